@@ -12,10 +12,16 @@ require (
 	{{- if include_database }}
 	gorm.io/gorm v1.25.5
 	gorm.io/driver/postgres v1.5.4
+	gorm.io/plugin/dbresolver v1.5.0
+	github.com/DATA-DOG/go-sqlmock v1.5.2
+	github.com/glebarez/sqlite v1.10.0
 	{{- endif }}
 	{{- if include_redis }}
 	github.com/redis/go-redis/v9 v9.3.0
+	github.com/alicebob/miniredis/v2 v2.31.1
 	{{- endif }}
+	golang.org/x/crypto v0.9.0
+	golang.org/x/sync v0.5.0
 	golang.org/x/time v0.5.0
 	github.com/google/uuid v1.4.0
 )
@@ -38,7 +44,6 @@ require (
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	github.com/ugorji/go/codec v1.2.11 // indirect
 	golang.org/x/arch v0.3.0 // indirect
-	golang.org/x/crypto v0.9.0 // indirect
 	golang.org/x/net v0.10.0 // indirect
 	golang.org/x/sys v0.8.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
@@ -2,40 +2,97 @@ package app
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/handlers"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/httpclient"
 	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/metrics"
 	"{{ module_name }}/internal/middleware"
-	"{{ module_name }}/internal/handlers"
+	"{{ module_name }}/internal/query"
+	"{{ module_name }}/internal/response"
+	"{{ module_name }}/internal/webhooks"
 	{{- if include_database }}
 	"{{ module_name }}/internal/database"
 	{{- endif }}
 	{{- if include_redis }}
 	"{{ module_name }}/internal/redis"
 	{{- endif }}
+	{{- if include_auth }}
+	"{{ module_name }}/internal/password"
+	{{- endif }}
 )
 
 type App struct {
-	config    *config.Config
-	logger    logger.Logger
-	Router    *gin.Engine
+	config     *config.Config
+	runtime    *config.Runtime
+	logger     logger.Logger
+	captures   *middleware.CaptureBuffer
+	health     *health.Registry
+	http       *http.Client
+	healthHTTP *http.Client
+	background *background
+	info       AppInfo
+
+	Router   *gin.Engine
+	Webhooks *webhooks.Dispatcher
+
+	// warmedUp is set once warm-up has completed
+	warmedUp atomic.Bool
 	{{- if include_database }}
+
 	dbManager *database.DatabaseManager
+	// migrationErr holds the error of a failed startup migration or
+	// schema check, reported by the migrations health check
+	migrationErr atomic.Pointer[error]
 	{{- endif }}
 	{{- if include_redis }}
-	redis     *redis.Client
+
+	redis *redis.Client
 	{{- endif }}
+	{{- if include_auth }}
+
+	passwords *password.Policy
+	{{- endif }}
+}
+
+// NewApp initializes the application and its dependencies. The whole
+// initialization sequence is bounded by ctx, which every dependency connects
+// with, so startup fails rather than blocking indefinitely. Nothing is left
+// running on failure: connections opened so far are closed, and an app that
+// finished initializing only after ctx expired is shut down.
+func NewApp(ctx context.Context, cfg *config.Config, log logger.Logger) (*App, error) {
+	app, err := newApp(ctx, cfg, log)
+	if err == nil && ctx.Err() != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		defer cancel()
+		_ = app.Shutdown(shutdownCtx)
+		err = ctx.Err()
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("startup did not complete: %w", err)
+		}
+		return nil, err
+	}
+	return app, nil
 }
 
-func NewApp(cfg *config.Config, log logger.Logger) (*App, error) {
+func newApp(ctx context.Context, cfg *config.Config, log logger.Logger) (*App, error) {
 	app := &App{
-		config: cfg,
-		logger: log,
+		config:     cfg,
+		runtime:    config.NewRuntime(cfg),
+		logger:     log,
+		background: newBackground(),
 	}
 
 	// Set Gin mode
@@ -43,12 +100,31 @@ func NewApp(cfg *config.Config, log logger.Logger) (*App, error) {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Configure response serialization
+	response.Configure(response.Options{
+		SnakeCase:  cfg.JSONSnakeCase,
+		OmitEmpty:  cfg.JSONOmitEmpty,
+		TimeFormat: cfg.JSONTimeFormat,
+	})
+
+	// Configure list endpoint pagination
+	query.ConfigurePagination(query.PaginationOptions{
+		DefaultPageSize: cfg.PaginationDefaultPageSize,
+		MaxPageSize:     cfg.PaginationMaxPageSize,
+		DefaultSort:     cfg.PaginationDefaultSort,
+		MaxOffset:       cfg.PaginationMaxOffset,
+	})
+
 	// Initialize router
-	app.Router = gin.New()
+	router, err := newRouter(cfg)
+	if err != nil {
+		return nil, err
+	}
+	app.Router = router
 
 	{{- if include_database }}
 	// Initialize database using Marty framework patterns
-	dbManager, err := database.GetInstance(cfg.ServiceName, cfg, log)
+	dbManager, err := database.GetInstance(ctx, cfg.ServiceName, cfg, log)
 	if err != nil {
 		return nil, err
 	}
@@ -57,98 +133,404 @@ func NewApp(cfg *config.Config, log logger.Logger) (*App, error) {
 
 	{{- if include_redis }}
 	// Initialize Redis
-	redis, err := redis.NewClient(cfg, log)
+	redisClient, err := redis.NewClient(ctx, cfg, log)
 	if err != nil {
+		{{- if include_database }}
+		if closeErr := app.dbManager.Close(); closeErr != nil {
+			log.WithError(closeErr).Warn("Failed to close database after startup failure")
+		}
+		{{- endif }}
 		return nil, err
 	}
-	app.redis = redis
+	app.redis = redisClient
+	{{- endif }}
+
+	// Initialize webhook delivery
+	app.Webhooks = webhooks.NewDispatcher(webhooks.Options{
+		MaxAttempts:    cfg.WebhookMaxAttempts,
+		InitialBackoff: cfg.WebhookInitialBackoff,
+		MaxBackoff:     cfg.WebhookMaxBackoff,
+		Timeout:        cfg.WebhookTimeout,
+		MaxConcurrency: cfg.WebhookMaxConcurrency,
+	}, webhooks.NewMemoryStore(0), log)
+
+	// Initialize outbound HTTP clients
+	clientOptions := httpClientOptions(cfg)
+	app.http = httpclient.New(clientOptions)
+	app.healthHTTP = healthHTTPClient(clientOptions)
+	{{- if include_auth }}
+
+	// Password policy for new passwords, applied by Register. The breach
+	// check uses a plain client rather than httpclient.New, so neither the
+	// service token nor the request ID, tenant and trace headers are sent
+	// to the third-party API.
+	policy := password.Policy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+		OnBreachCheckError: func(err error) {
+			log.WithError(err).Warn("Password breach check failed, accepting password")
+		},
+	}
+	if cfg.PasswordBreachCheck {
+		policy.Breaches = &password.HIBP{
+			Client:  &http.Client{Timeout: cfg.PasswordBreachCheckTimeout},
+			URL:     cfg.PasswordBreachCheckURL,
+			Timeout: cfg.PasswordBreachCheckTimeout,
+		}
+	}
+	app.passwords = password.NewPolicy(policy)
 	{{- endif }}
 
+	// Register health checks
+	app.setupHealthChecks()
+
 	// Setup middleware
 	app.setupMiddleware()
 
 	// Setup routes
 	app.setupRoutes()
 
+	// Report collectors that failed to register, now that there's a logger
+	if err := metrics.RegistrationErrors(); err != nil {
+		log.WithError(err).Warn("Some metrics will not be exported")
+	}
+
+	{{- if include_database }}
+
+	// Migrate in the background; /health reports unhealthy and the startup
+	// probe fails until done
+	app.startMigrations()
+	{{- endif }}
+
+	// Warm up in the background; /health reports unhealthy and the startup
+	// probe fails until done
+	if cfg.WarmUpEnabled {
+		app.startWarmUp()
+	}
+
+	// Raise the log level to debug on SIGUSR1, restore it on SIGUSR2
+	app.Go("log-level-signals", app.watchLogLevelSignals)
+
+	// Record what was initialized, served at /admin/info and /admin/config
+	app.finishInfo()
+
 	return app, nil
 }
 
+func (a *App) setupHealthChecks() {
+	a.health = health.NewRegistry(health.Options{
+		CacheTTL:     a.config.HealthCacheTTL,
+		CheckTimeout: a.config.HealthCheckTimeout,
+	})
+
+	{{- if include_database }}
+	// Database check
+	a.health.Register("database", true, a.dbManager.HealthCheck)
+
+	// Not ready until the schema is migrated or confirmed current
+	a.health.Register("migrations", true, a.checkMigrations)
+	{{- endif }}
+
+	{{- if include_redis }}
+	// Redis check
+	a.health.Register("redis", true, a.redis.HealthCheck)
+
+	// Memory pressure only degrades the service: Redis is still serving
+	a.health.Register("redis_memory", false, a.redis.MemoryCheck(a.config.RedisMemoryWarnRatio))
+	{{- endif }}
+
+	// Not ready until connection pools and caches are warmed up
+	if a.config.WarmUpEnabled {
+		a.health.Register("warmup", true, a.checkWarmUp)
+	}
+
+	// Downstream HTTP dependencies
+	for _, dep := range a.config.HealthHTTPDependencies {
+		a.health.Register(dep.Name, dep.Critical, health.HTTPCheck(a.healthHTTP, dep.Method, dep.URL))
+		a.logger.Infof("Registered health check for %s (critical=%t)", dep.Name, dep.Critical)
+	}
+}
+
+{{- if include_database }}
+
+// startMigrations migrates the schema, or with DATABASE_AUTO_MIGRATE off
+// checks it exists, as a background task. A failure isn't fatal: the
+// replica stays unready, reporting the error, and retries on its next start.
+func (a *App) startMigrations() {
+	migrated := a.health.StartupTask("migrations")
+	a.Go("migrations", func(ctx context.Context) error {
+		var err error
+		if !a.config.DatabaseAutoMigrate {
+			err = a.dbManager.VerifySchema(ctx, database.Models()...)
+		} else {
+			err = a.dbManager.AutoMigrateContext(ctx, database.Models()...)
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				a.logger.WithError(err).Error("Schema not ready, reporting unhealthy")
+				a.migrationErr.Store(&err)
+				migrated(err)
+			}
+			return nil
+		}
+		migrated(nil)
+		return nil
+	})
+}
+
+// checkMigrations fails until the schema is migrated or confirmed current,
+// with the error of a failed migration once there is one
+func (a *App) checkMigrations(ctx context.Context) (health.CheckResult, error) {
+	if !a.dbManager.Migrated() {
+		if err := a.migrationErr.Load(); err != nil {
+			return health.CheckResult{}, *err
+		}
+		return health.CheckResult{}, fmt.Errorf("migrations pending")
+	}
+	return health.CheckResult{}, nil
+}
+{{- endif }}
+
+// httpClientOptions returns the options of the outbound HTTP clients,
+// authenticated as this service when a token endpoint is configured
+func httpClientOptions(cfg *config.Config) httpclient.Options {
+	opts := httpclient.Options{
+		Timeout:       cfg.HTTPClientTimeout,
+		TenantHeader:  cfg.TenantHeader,
+		MaxRetries:    cfg.HTTPClientMaxRetries,
+		MaxRetryAfter: cfg.HTTPClientMaxRetryAfter,
+	}
+	if cfg.ServiceTokenURL != "" {
+		opts.TokenAudience = cfg.ServiceTokenAudience
+		opts.TokenSource = httpclient.ClientCredentials(
+			httpclient.New(httpclient.Options{Timeout: cfg.HTTPClientTimeout}),
+			httpclient.ClientCredentialsConfig{
+				TokenURL:     cfg.ServiceTokenURL,
+				ClientID:     cfg.ServiceClientID,
+				ClientSecret: cfg.ServiceClientSecret,
+				Scopes:       cfg.ServiceTokenScopes,
+			},
+			cfg.ServiceTokenRefreshBefore,
+		)
+	}
+	return opts
+}
+
+// healthHTTPClient creates the client probing HTTP dependencies, sharing the
+// outbound client's service token but without its retries: a dependency
+// answering 429 or 503 is reported at once, rather than after waiting out
+// its Retry-After past HEALTH_CHECK_TIMEOUT
+func healthHTTPClient(opts httpclient.Options) *http.Client {
+	opts.MaxRetries = 0
+	return httpclient.New(opts)
+}
+
+// newRouter creates the gin engine with the configured routing options
+func newRouter(cfg *config.Config) (*gin.Engine, error) {
+	router := gin.New()
+	router.RedirectTrailingSlash = cfg.RedirectTrailingSlash
+	router.RedirectFixedPath = cfg.RedirectFixedPath
+	router.HandleMethodNotAllowed = cfg.HandleMethodNotAllowed
+
+	// Trust the client IP header set by the platform in front of the service
+	switch strings.ToLower(cfg.TrustedPlatform) {
+	case "":
+	case "cloudflare":
+		router.TrustedPlatform = gin.PlatformCloudflare
+	case "google", "appengine":
+		router.TrustedPlatform = gin.PlatformGoogleAppEngine
+	default:
+		// Any other value is taken as the header name
+		router.TrustedPlatform = cfg.TrustedPlatform
+	}
+
+	// Only honour X-Forwarded-For from configured proxies, so clients can't
+	// spoof their IP (and with it IP-keyed rate limits)
+	proxies := make([]string, len(cfg.TrustedProxies))
+	for i, prefix := range cfg.TrustedProxies {
+		proxies[i] = prefix.String()
+	}
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return nil, fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+
+	return router, nil
+}
+
 func (a *App) setupMiddleware() {
 	// Recovery middleware
-	a.Router.Use(gin.Recovery())
+	a.use("recovery", gin.Recovery())
+
+	// Request ID middleware, first so even requests rejected by later
+	// middleware carry the ID in their error response
+	a.use("request_id", middleware.RequestID())
 
 	// Logger middleware
-	a.Router.Use(middleware.Logger(a.logger))
+	if a.config.EnableRequestLogging {
+		a.use("logger", middleware.Logger(a.logger, a.config.AccessLogFormat, a.config.AccessLogHeaders))
+	}
+
+	// Warn about slow requests; routes can override the threshold with
+	// middleware.SlowRequestThreshold
+	a.use("slow_requests", middleware.SlowRequests(a.config.SlowRequestThreshold, a.logger))
+
+	// Load shedding and in-flight tracking
+	a.use("concurrency_limit", middleware.ConcurrencyLimit(a.config.MaxConcurrentRequests, a.config.ConcurrencyQueueTimeout))
 
 	// CORS middleware
-	a.Router.Use(middleware.CORS(a.config.CORSOrigins))
+	if a.config.EnableCORS {
+		a.use("cors", middleware.CORS(a.runtime))
+	}
 
-	// Rate limiter middleware
-	a.Router.Use(middleware.RateLimit(a.config.RateLimit))
+	// Rate limiter middleware, per client IP, which may be disabled when an
+	// upstream gateway already limits traffic
+	if a.config.EnableRateLimit {
+		a.use("rate_limit", middleware.RateLimit(a.runtime, a.logger, middleware.KeyByIP))
+	}
 
 	// Security headers middleware
-	a.Router.Use(middleware.Security())
+	if a.config.EnableSecurityHeaders {
+		a.use("security_headers", middleware.Security(middleware.SecurityOptions{
+			HSTS: middleware.HSTSOptions{
+				Enabled:             a.config.HSTSEnabled,
+				MaxAge:              a.config.HSTSMaxAge,
+				IncludeSubDomains:   a.config.HSTSIncludeSubDomains,
+				Preload:             a.config.HSTSPreload,
+				TrustForwardedProto: a.config.HSTSTrustForwardedProto,
+			},
+			ContentSecurityPolicy: a.config.ContentSecurityPolicy,
+			PermissionsPolicy:     a.config.PermissionsPolicy,
+		}))
+	}
+
+	// Trace context middleware, forwarded on outbound calls
+	a.use("trace_context", middleware.TraceContext())
+
+	// Tenant middleware
+	a.use("tenant", middleware.Tenant(a.config.TenantHeader))
+
+	// Request-tagged logger for logger.FromContext, used for SQL logs
+	a.use("request_logger", middleware.RequestLogger(a.logger))
+
+	// Request body size limit, overridable per route
+	a.use("max_body_size", middleware.MaxBodySize(int64(a.config.MaxBodySize)))
 
-	// Request ID middleware
-	a.Router.Use(middleware.RequestID())
+	// Transparently decompress gzip-encoded request bodies
+	a.use("decompress", middleware.DecompressRequest(int64(a.config.MaxDecompressedBodySize)))
+
+	// Request capture middleware, off unless a sample rate is configured.
+	// It comes after the body limits and decompression, so it records the
+	// body handlers see and can't read past the limits.
+	if a.config.CaptureSampleRate > 0 {
+		a.captures = middleware.NewCaptureBuffer(a.config.CaptureBufferSize)
+		a.use("capture", middleware.Capture(a.config.CaptureSampleRate, a.captures))
+	}
+
+	// Request-scoped lookup cache
+	a.use("request_cache", middleware.RequestCache())
+	{{- if include_database }}
+
+	// Request-bound database access via database.For(c)
+	a.use("database", database.Bind(a.dbManager))
+	{{- endif }}
+	{{- if include_redis }}
+
+	// Request-bound Redis access via redis.For(c)
+	a.use("redis", redis.Bind(a.redis))
+	{{- endif }}
+
+	{{- if include_database }}
+
+	// Per-request SQL debug logging, gated by the admin token
+	if a.config.AdminToken != "" {
+		a.use("debug_sql", middleware.DebugSQL(a.config.AdminToken))
+	}
+	{{- endif }}
 
 	// Prometheus metrics middleware
-	a.Router.Use(middleware.Metrics())
+	if a.config.EnableMetricsRecording {
+		a.use("metrics", middleware.Metrics(a.config.MetricsTenants))
+	}
 }
 
 func (a *App) setupRoutes() {
 	// Health check
-	a.Router.GET(a.config.HealthPath, handlers.HealthCheck(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}{{- if include_redis }}, a.redis{{- endif }}))
+	a.Router.GET(a.config.HealthPath, handlers.HealthCheck(a.config, a.logger, a.health))
+	a.Router.GET(a.config.HealthPath+"/startup", handlers.StartupCheck(a.health))
+	a.Router.GET(a.config.HealthPath+"/live", handlers.LivenessCheck(a.health))
 
 	// Metrics endpoint
 	a.Router.GET(a.config.MetricsPath, gin.WrapH(promhttp.Handler()))
 
-	// API routes
-	api := a.Router.Group("/api/v1")
-	{
-		{{- if include_auth }}
-		// Auth routes
-		auth := api.Group("/auth")
+	// Admin routes, only served when an admin token is configured
+	if a.config.AdminToken != "" {
+		admin := a.Router.Group("/admin")
+		admin.Use(middleware.AdminAuth(a.config.AdminToken))
 		{
-			auth.POST("/login", handlers.Login(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}))
-			auth.POST("/register", handlers.Register(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}))
-			auth.POST("/refresh", handlers.RefreshToken(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}))
+			admin.GET("/config", handlers.GetConfig(a.config, func() interface{} { return a.Info() }))
+			admin.GET("/info", a.getInfo)
+			admin.GET("/settings", handlers.GetRuntimeSettings(a.runtime))
+			admin.PUT("/settings", handlers.UpdateRuntimeSettings(a.runtime, a.logger))
+			if a.captures != nil {
+				admin.GET("/captures", handlers.GetCaptures(a.captures))
+			}
 		}
+	}
 
-		// Protected routes
-		protected := api.Group("/")
-		protected.Use(middleware.AuthMiddleware(a.config.JWTSecret))
-		{
-			protected.GET("/profile", handlers.GetProfile(a.logger{{- if include_database }}, a.dbManager{{- endif }}))
-		}
-		{{- endif }}
+	// Versioned API routes
+	a.setupAPIRoutes()
 
-		// Example routes
-		api.GET("/", handlers.Root(a.logger))
-		api.GET("/ping", handlers.Ping(a.logger))
-	}
+	// Streaming routes, outside the API's request budget
+	a.setupStreamRoutes()
+
+	// JSON error envelopes for unknown routes and wrong methods
+	a.Router.NoRoute(handlers.NotFound())
+	a.Router.NoMethod(handlers.MethodNotAllowed(a.Router.Routes))
 }
 
+// Shutdown stops background tasks and closes dependency connections. It
+// doesn't wait for requests; servers use Drain, which calls it once
+// in-flight requests have completed. It returns the errors of the steps that
+// failed.
 func (a *App) Shutdown(ctx context.Context) error {
 	a.logger.Info("Shutting down application...")
+	timer := newShutdownTimer(a.logger)
+	defer timer.finish()
+
+	// Fail health checks so load balancers stop routing new traffic here
+	a.health.SetDraining(true)
+
+	a.release(ctx, timer)
+	return timer.err()
+}
+
+// release stops background tasks and closes dependency connections, the
+// final shutdown steps
+func (a *App) release(ctx context.Context, timer *shutdownTimer) {
+	// Stop background tasks
+	timer.step("background_tasks", func() error {
+		return a.stopBackground(ctx)
+	})
+
+	// Wait for in-flight webhook deliveries
+	timer.step("webhooks", func() error {
+		return a.Webhooks.Close(ctx)
+	})
 
 	{{- if include_database }}
 	// Close database connection
 	if a.dbManager != nil {
-		if err := a.dbManager.Close(); err != nil {
-			a.logger.Errorf("Error closing database: %v", err)
-		}
+		timer.step("database", a.dbManager.Close)
 	}
 	{{- endif }}
 
 	{{- if include_redis }}
 	// Close Redis connection
 	if a.redis != nil {
-		if err := a.redis.Close(); err != nil {
-			a.logger.Errorf("Error closing Redis: %v", err)
-		}
+		timer.step("redis", a.redis.Close)
 	}
 	{{- endif }}
-
-	return nil
 }
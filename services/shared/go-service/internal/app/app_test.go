@@ -0,0 +1,408 @@
+package app
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/middleware"
+	"{{ module_name }}/internal/reqctx"
+	{{- if include_database }}
+
+	"context"
+	"database/sql/driver"
+	"io"
+	"net"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	{{- if include_redis }}
+	"github.com/alicebob/miniredis/v2"
+	{{- endif }}
+	"gorm.io/driver/postgres"
+
+	"{{ module_name }}/internal/database"
+	"{{ module_name }}/internal/handlers"
+	"{{ module_name }}/internal/health"
+	{{- if include_redis }}
+	"{{ module_name }}/internal/redis"
+	{{- endif }}
+	{{- endif }}
+)
+
+// syncBuffer is a bytes.Buffer safe for the logger and the test to share
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf.Reset()
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// observed is what a handler saw of its request's state
+type observed struct {
+	keys      map[string]interface{}
+	tenant    string
+	requestID string
+	userID    string
+}
+
+// TestRequestStateDoesNotLeak sends requests one after another through the
+// full middleware chain, so gin hands the second the first's pooled context,
+// and checks nothing set for the first is visible to the second
+func TestRequestStateDoesNotLeak(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ENABLE_RATE_LIMIT", "false")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	var logs syncBuffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&logs) })
+
+	router, err := newRouter(cfg)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	a := &App{config: cfg, runtime: config.NewRuntime(cfg), logger: log, Router: router}
+	a.setupMiddleware()
+
+	var seen observed
+	probe := func(c *gin.Context) {
+		ctx := c.Request.Context()
+		seen = observed{
+			keys:      make(map[string]interface{}, len(c.Keys)),
+			tenant:    reqctx.TenantID(ctx),
+			requestID: reqctx.RequestID(ctx),
+			userID:    c.GetString("user_id"),
+		}
+		for k, v := range c.Keys {
+			seen.keys[k] = v
+		}
+		c.Set("scratch", c.Request.URL.Path)
+
+		if requestLog, ok := logger.FromContext(ctx); ok {
+			requestLog.Info("probe")
+		}
+		c.Status(http.StatusNoContent)
+	}
+	a.Router.GET("/private", middleware.AuthMiddleware(middleware.AuthOptions{Secret: cfg.JWTSecret}), probe)
+	a.Router.GET("/public", probe)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "alice"}).SignedString([]byte(cfg.JWTSecret))
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/private", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(cfg.TenantHeader, "acme")
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, req)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("first request got %d: %s", w.Code, w.Body)
+		}
+		if seen.userID != "alice" || seen.tenant != "acme" {
+			t.Fatalf("first request saw user %q, tenant %q; want alice, acme", seen.userID, seen.tenant)
+		}
+		firstRequestID := seen.requestID
+
+		logs.Reset()
+		w = httptest.NewRecorder()
+		a.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public", nil))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("second request got %d: %s", w.Code, w.Body)
+		}
+
+		for _, key := range []string{"scratch", "user_id", "email", "tenant_id"} {
+			if v, ok := seen.keys[key]; ok {
+				t.Errorf("second request sees c.Keys[%q] = %v from the first", key, v)
+			}
+		}
+		if seen.userID != "" {
+			t.Errorf("second request sees user %q", seen.userID)
+		}
+		if seen.tenant != "" {
+			t.Errorf("second request sees tenant %q", seen.tenant)
+		}
+		if seen.requestID == "" || seen.requestID == firstRequestID {
+			t.Errorf("second request ID = %q, want a new one (first was %q)", seen.requestID, firstRequestID)
+		}
+
+		probeLog := logs.String()
+		if !strings.Contains(probeLog, seen.requestID) {
+			t.Errorf("request logger isn't tagged with the second request's ID: %s", probeLog)
+		}
+		if strings.Contains(probeLog, firstRequestID) || strings.Contains(probeLog, "acme") {
+			t.Errorf("request logger carries the first request's fields: %s", probeLog)
+		}
+	}
+}
+{{- if include_database }}
+
+// newSchemaApp returns an app checking its schema against a sqlmock
+// database, serving /health with only the migrations check
+func newSchemaApp(t *testing.T) (*App, sqlmock.Sqlmock) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.DatabaseAutoMigrate = false
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+
+	m, err := database.NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, log)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	a := &App{config: cfg, logger: log, dbManager: m, background: newBackground(), Router: gin.New()}
+	a.health = health.NewRegistry(health.Options{CheckTimeout: time.Second})
+	a.health.Register("migrations", true, a.checkMigrations)
+	a.Router.GET(cfg.HealthPath, handlers.HealthCheck(cfg, log, a.health))
+	t.Cleanup(func() { _ = a.stopBackground(context.Background()) })
+	return a, mock
+}
+
+// getHealth serves GET /health on a
+func getHealth(a *App) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	a.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.config.HealthPath, nil))
+	return w
+}
+
+// waitForStartup waits until no startup task is pending
+func waitForStartup(t *testing.T, a *App) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(a.health.PendingStartup()) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("startup tasks still pending: %v", a.health.PendingStartup())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMigrationsGateReadiness(t *testing.T) {
+	a, mock := newSchemaApp(t)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM information_schema.tables`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	if w := getHealth(a); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "migrations pending") {
+		t.Errorf("before migrating: %d %s, want 503 with migrations pending", w.Code, w.Body)
+	}
+
+	a.startMigrations()
+	waitForStartup(t, a)
+
+	if w := getHealth(a); w.Code != http.StatusOK {
+		t.Errorf("after migrating: %d %s, want 200", w.Code, w.Body)
+	}
+	if err := a.health.StartupErr(); err != nil {
+		t.Errorf("StartupErr = %v, want nil", err)
+	}
+}
+
+func TestFailedMigrationReported(t *testing.T) {
+	a, mock := newSchemaApp(t)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM information_schema.tables`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	a.startMigrations()
+	waitForStartup(t, a)
+
+	const want = "does not exist"
+	if w := getHealth(a); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), want) {
+		t.Errorf("health = %d %s, want 503 reporting the migration error", w.Code, w.Body)
+	}
+	if failed := a.health.FailedStartup(); len(failed) != 1 || failed[0] != "migrations" {
+		t.Errorf("FailedStartup = %v, want [migrations]", failed)
+	}
+
+	// The self-test stops waiting and reports why
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.SelfTest(ctx); err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("SelfTest = %v, want the migration error", err)
+	}
+}
+
+// hangingListener accepts connections and never answers, like a dependency
+// too slow to come up
+func hangingListener(t *testing.T) net.Addr {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			// Hold the connection open, unanswered, until the client gives up
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(io.Discard, conn)
+			}()
+		}
+	}()
+	return listener.Addr()
+}
+
+func TestStartupStopsAtBudget(t *testing.T) {
+	host, port, err := net.SplitHostPort(hangingListener(t).String())
+	if err != nil {
+		t.Fatalf("split address: %v", err)
+	}
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DATABASE_HOST", host)
+	t.Setenv("DATABASE_PORT", port)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	const budget = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	start := time.Now()
+	a, err := NewApp(ctx, cfg, log)
+	elapsed := time.Since(start)
+
+	if err == nil {
+		_ = a.Shutdown(context.Background())
+		t.Fatal("NewApp succeeded against a hanging database")
+	}
+	if !strings.Contains(err.Error(), "startup did not complete") {
+		t.Errorf("NewApp = %v, want a startup budget error", err)
+	}
+	if elapsed > budget+time.Second {
+		t.Errorf("NewApp returned after %v, want about the %v budget", elapsed, budget)
+	}
+}
+
+func TestWarmUpGatesReadiness(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.WarmUpEnabled = true
+	cfg.WarmUpConnections = 1
+	cfg.WarmUpTimeout = 5 * time.Second
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	conn, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	mock.ExpectPing()
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+	m, err := database.NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, log)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	// Warming up the pool takes a while
+	const delay = 300 * time.Millisecond
+	mock.ExpectPing().WillDelayFor(delay)
+
+	a := &App{config: cfg, logger: log, dbManager: m, background: newBackground(), Router: gin.New()}
+	{{- if include_redis }}
+	server := miniredis.RunT(t)
+	cfg.RedisURL = ""
+	cfg.RedisClusterAddrs = nil
+	cfg.RedisHost = server.Host()
+	cfg.RedisPort = server.Port()
+	a.redis, err = redis.NewClient(context.Background(), cfg, log)
+	if err != nil {
+		t.Fatalf("redis.NewClient: %v", err)
+	}
+	t.Cleanup(func() { a.redis.Close() })
+	{{- endif }}
+	a.health = health.NewRegistry(health.Options{CheckTimeout: time.Second})
+	a.health.Register("warmup", true, a.checkWarmUp)
+	a.Router.GET(cfg.HealthPath, handlers.HealthCheck(cfg, log, a.health))
+	a.Router.GET(cfg.HealthPath+"/startup", handlers.StartupCheck(a.health))
+	t.Cleanup(func() { _ = a.stopBackground(context.Background()) })
+
+	getStartup := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		a.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, cfg.HealthPath+"/startup", nil))
+		return w
+	}
+
+	start := time.Now()
+	a.startWarmUp()
+
+	if w := getHealth(a); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "warm-up pending") {
+		t.Errorf("during warm-up: health = %d %s, want 503 with warm-up pending", w.Code, w.Body)
+	}
+	if w := getStartup(); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "warmup") {
+		t.Errorf("during warm-up: startup = %d %s, want 503 pending warmup", w.Code, w.Body)
+	}
+
+	waitForStartup(t, a)
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("ready after %v, before warm-up could finish", elapsed)
+	}
+
+	if w := getHealth(a); w.Code != http.StatusOK {
+		t.Errorf("after warm-up: health = %d %s, want 200", w.Code, w.Body)
+	}
+	if w := getStartup(); w.Code != http.StatusOK {
+		t.Errorf("after warm-up: startup = %d %s, want 200", w.Code, w.Body)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+{{- endif }}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/safego"
+)
+
+// background owns every long-running goroutine of the app (schedulers,
+// workers, subscribers, monitors) so they can be stopped together.
+type background struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	group  *errgroup.Group
+
+	fatalOnce sync.Once
+	fatal     chan error
+}
+
+func newBackground() *background {
+	ctx, cancel := context.WithCancel(context.Background())
+	group, ctx := errgroup.WithContext(ctx)
+
+	return &background{
+		ctx:    ctx,
+		cancel: cancel,
+		group:  group,
+		fatal:  make(chan error, 1),
+	}
+}
+
+// Go starts a long-running background task. The task's context is cancelled
+// on shutdown, after which it should return promptly. A task returning an
+// error or panicking before shutdown is fatal: every other task is cancelled
+// and the error is delivered on Fatal so the service shuts down.
+func (a *App) Go(name string, task func(ctx context.Context) error) {
+	a.background.group.Go(func() error {
+		a.logger.Debugf("Background task %s started", name)
+
+		// A panicking task is recovered and treated as a failed one
+		err := safego.Run(a.logger, "background task "+name, func() error {
+			return task(a.background.ctx)
+		})
+		if err != nil && !errors.Is(err, context.Canceled) {
+			err = fmt.Errorf("background task %s failed: %w", name, err)
+			a.background.fatalOnce.Do(func() {
+				a.background.fatal <- err
+			})
+			return err
+		}
+
+		a.logger.Debugf("Background task %s stopped", name)
+		return nil
+	})
+}
+
+// Fatal delivers the first error returned by a background task
+func (a *App) Fatal() <-chan error {
+	return a.background.fatal
+}
+
+// stopBackground cancels all background tasks and waits for them to return
+func (a *App) stopBackground(ctx context.Context) error {
+	a.background.cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- a.background.group.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("background tasks did not stop: %w", ctx.Err())
+	}
+}
+
+// Heartbeat registers a critical goroutine with the liveness watchdog. If it
+// goes LIVENESS_TIMEOUT without calling Beat, /health/live fails and the
+// orchestrator restarts the process. Stop it when the goroutine exits.
+func (a *App) Heartbeat(name string) *health.Heartbeat {
+	return a.health.Heartbeat(name, a.config.LivenessTimeout)
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/logger"
+)
+
+// newBackgroundApp returns an app with only background task support
+func newBackgroundApp() *App {
+	return &App{
+		logger:     logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) }),
+		background: newBackground(),
+	}
+}
+
+func TestStopBackground(t *testing.T) {
+	a := newBackgroundApp()
+
+	var stopped atomic.Int32
+	for _, name := range []string{"scheduler", "worker", "subscriber"} {
+		a.Go(name, func(ctx context.Context) error {
+			<-ctx.Done()
+			// Tasks may take a moment to wind down once cancelled
+			time.Sleep(50 * time.Millisecond)
+			stopped.Add(1)
+			return ctx.Err()
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := a.stopBackground(ctx); err != nil {
+		t.Fatalf("stopBackground: %v", err)
+	}
+	if got := stopped.Load(); got != 3 {
+		t.Errorf("%d tasks stopped when stopBackground returned, want 3", got)
+	}
+}
+
+func TestStopBackgroundTimesOut(t *testing.T) {
+	a := newBackgroundApp()
+
+	release := make(chan struct{})
+	defer close(release)
+	a.Go("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.stopBackground(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("stopBackground = %v, want a deadline error for the stuck task", err)
+	}
+}
+
+func TestBackgroundTaskFailure(t *testing.T) {
+	a := newBackgroundApp()
+
+	cancelled := make(chan struct{})
+	a.Go("sibling", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	})
+	broken := errors.New("broken")
+	a.Go("failing", func(ctx context.Context) error { return broken })
+
+	select {
+	case err := <-a.Fatal():
+		if !errors.Is(err, broken) {
+			t.Errorf("Fatal delivered %v, want the failing task's error", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("failed task wasn't reported on Fatal")
+	}
+
+	// The other tasks are cancelled so the service can shut down
+	select {
+	case <-cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sibling task wasn't cancelled after the failure")
+	}
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/response"
+)
+
+// AppInfo reports what NewApp initialized, for tests and diagnostics
+type AppInfo struct {
+	Service     string    `json:"service"`
+	Environment string    `json:"environment"`
+	StartedAt   time.Time `json:"started_at"`
+
+	DatabaseConnected bool `json:"database_connected"`
+	RedisConnected    bool `json:"redis_connected"`
+
+	// Routes is the number of registered routes
+	Routes int `json:"routes"`
+	// Middleware lists the global middleware in the order they run
+	Middleware   []string `json:"middleware"`
+	HealthChecks []string `json:"health_checks"`
+}
+
+// Info returns the boot report built by NewApp
+func (a *App) Info() AppInfo {
+	info := a.info
+	info.Middleware = append([]string(nil), a.info.Middleware...)
+	info.HealthChecks = append([]string(nil), a.info.HealthChecks...)
+	return info
+}
+
+// use registers global middleware, recording it in the boot report
+func (a *App) use(name string, handler gin.HandlerFunc) {
+	a.info.Middleware = append(a.info.Middleware, name)
+	a.Router.Use(handler)
+}
+
+// finishInfo completes the boot report once routes and checks are set up
+func (a *App) finishInfo() {
+	a.info.Service = a.config.ServiceName
+	a.info.Environment = a.config.Environment
+	a.info.StartedAt = time.Now()
+	{{- if include_database }}
+	a.info.DatabaseConnected = a.dbManager != nil
+	{{- endif }}
+	{{- if include_redis }}
+	a.info.RedisConnected = a.redis != nil
+	{{- endif }}
+	a.info.Routes = len(a.Router.Routes())
+	for _, check := range a.health.Checks() {
+		a.info.HealthChecks = append(a.info.HealthChecks, check.Name)
+	}
+}
+
+// getInfo serves the boot report
+func (a *App) getInfo(c *gin.Context) {
+	response.JSON(c, http.StatusOK, a.Info())
+}
@@ -0,0 +1,316 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+	{{- if include_database }}
+
+	"database/sql/driver"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+
+	"{{ module_name }}/internal/database"
+	{{- endif }}
+	{{- if include_redis }}
+
+	"github.com/alicebob/miniredis/v2"
+
+	"{{ module_name }}/internal/redis"
+	{{- endif }}
+)
+
+// newMiddlewareApp returns an app with only the global middleware set up,
+// configured by configure, and the buffer its logs are written to
+func newMiddlewareApp(t *testing.T, configure func(*config.Config)) (*App, *syncBuffer) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	configure(cfg)
+
+	var logs syncBuffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&logs) })
+
+	router, err := newRouter(cfg)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	a := &App{config: cfg, runtime: config.NewRuntime(cfg), logger: log, Router: router}
+	a.setupMiddleware()
+	return a, &logs
+}
+
+// requestsRecorded reports whether http_requests_total has a series for path
+func requestsRecorded(t *testing.T, path string) bool {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "http_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "path" && label.GetValue() == path {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func TestMiddlewareToggles(t *testing.T) {
+	tests := []struct {
+		name    string
+		disable func(*config.Config)
+		applied func(t *testing.T, w *httptest.ResponseRecorder, logs *syncBuffer, path string) bool
+	}{
+		{
+			name:    "cors",
+			disable: func(cfg *config.Config) { cfg.EnableCORS = false },
+			applied: func(t *testing.T, w *httptest.ResponseRecorder, _ *syncBuffer, _ string) bool {
+				return w.Header().Get("Access-Control-Allow-Origin") != ""
+			},
+		},
+		{
+			name:    "rate_limit",
+			disable: func(cfg *config.Config) { cfg.EnableRateLimit = false },
+			applied: func(t *testing.T, w *httptest.ResponseRecorder, _ *syncBuffer, _ string) bool {
+				return w.Header().Get("RateLimit-Limit") != ""
+			},
+		},
+		{
+			name:    "security_headers",
+			disable: func(cfg *config.Config) { cfg.EnableSecurityHeaders = false },
+			applied: func(t *testing.T, w *httptest.ResponseRecorder, _ *syncBuffer, _ string) bool {
+				return w.Header().Get("X-Frame-Options") != ""
+			},
+		},
+		{
+			name:    "logger",
+			disable: func(cfg *config.Config) { cfg.EnableRequestLogging = false },
+			applied: func(t *testing.T, _ *httptest.ResponseRecorder, logs *syncBuffer, path string) bool {
+				return strings.Contains(logs.String(), path)
+			},
+		},
+		{
+			name:    "metrics",
+			disable: func(cfg *config.Config) { cfg.EnableMetricsRecording = false },
+			applied: func(t *testing.T, _ *httptest.ResponseRecorder, _ *syncBuffer, path string) bool {
+				return requestsRecorded(t, path)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, enabled := range []bool{true, false} {
+			name := tt.name + "/disabled"
+			if enabled {
+				name = tt.name + "/enabled"
+			}
+			t.Run(name, func(t *testing.T) {
+				a, logs := newMiddlewareApp(t, func(cfg *config.Config) {
+					cfg.EnableCORS = true
+					cfg.CORSOrigins = []string{"*"}
+					cfg.EnableRateLimit = true
+					cfg.EnableSecurityHeaders = true
+					cfg.EnableRequestLogging = true
+					cfg.EnableMetricsRecording = true
+					if !enabled {
+						tt.disable(cfg)
+					}
+				})
+				// A path of its own, so the metrics series is this subtest's
+				path := "/toggle/" + name
+				a.Router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Origin", "https://app.example.com")
+				w := httptest.NewRecorder()
+				a.Router.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Fatalf("GET %s = %d, want 200", path, w.Code)
+				}
+
+				if got := tt.applied(t, w, logs, path); got != enabled {
+					t.Errorf("%s applied = %v, want %v", tt.name, got, enabled)
+				}
+				if got := slices.Contains(a.Info().Middleware, tt.name); got != enabled {
+					t.Errorf("middleware %v lists %s = %v, want %v", a.Info().Middleware, tt.name, got, enabled)
+				}
+			})
+		}
+	}
+}
+
+// newBootApp sets up an app the way newApp does once its dependencies are
+// connected, with a sqlmock database and a miniredis server when asked for,
+// and returns it with its boot report complete
+func newBootApp(t *testing.T, withDatabase, withRedis bool, configure func(*config.Config)) *App {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.AdminToken = "admin-token"
+	if configure != nil {
+		configure(cfg)
+	}
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	router, err := newRouter(cfg)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	a := &App{config: cfg, runtime: config.NewRuntime(cfg), logger: log, background: newBackground(), Router: router}
+	t.Cleanup(func() { _ = a.stopBackground(context.Background()) })
+	{{- if include_database }}
+
+	if withDatabase {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+		mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+		mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+
+		a.dbManager, err = database.NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, log)
+		if err != nil {
+			t.Fatalf("NewManager: %v", err)
+		}
+	}
+	{{- endif }}
+	{{- if include_redis }}
+
+	if withRedis {
+		server := miniredis.RunT(t)
+		cfg.RedisURL = ""
+		cfg.RedisClusterAddrs = nil
+		cfg.RedisHost = server.Host()
+		cfg.RedisPort = server.Port()
+
+		a.redis, err = redis.NewClient(context.Background(), cfg, log)
+		if err != nil {
+			t.Fatalf("redis.NewClient: %v", err)
+		}
+		t.Cleanup(func() { a.redis.Close() })
+	}
+	{{- endif }}
+
+	a.setupHealthChecks()
+	a.setupMiddleware()
+	a.setupRoutes()
+	a.finishInfo()
+	return a
+}
+
+func TestBootReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		database  bool
+		redis     bool
+		configure func(*config.Config)
+		// middleware expected in, or left out of, the report
+		middleware map[string]bool
+	}{
+		{
+			name:       "no dependencies",
+			middleware: map[string]bool{"recovery": true, "metrics": true},
+		},
+		{{- if include_database }}
+		{
+			name:       "database",
+			database:   true,
+			middleware: map[string]bool{"database": true, "debug_sql": true},
+		},
+		{{- endif }}
+		{{- if include_redis }}
+		{
+			name:       "redis",
+			redis:      true,
+			middleware: map[string]bool{"redis": true},
+		},
+		{{- endif }}
+		{
+			name: "middleware disabled",
+			configure: func(cfg *config.Config) {
+				cfg.EnableCORS = false
+				cfg.EnableMetricsRecording = false
+			},
+			middleware: map[string]bool{"recovery": true, "cors": false, "metrics": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newBootApp(t, tt.database, tt.redis, tt.configure)
+			info := a.Info()
+
+			if info.DatabaseConnected != tt.database || info.RedisConnected != tt.redis {
+				t.Errorf("database, redis connected = %v, %v, want %v, %v", info.DatabaseConnected, info.RedisConnected, tt.database, tt.redis)
+			}
+			if want := len(a.Router.Routes()); info.Routes != want {
+				t.Errorf("Routes = %d, want %d", info.Routes, want)
+			}
+			for name, want := range tt.middleware {
+				if got := slices.Contains(info.Middleware, name); got != want {
+					t.Errorf("middleware %v lists %s = %v, want %v", info.Middleware, name, got, want)
+				}
+			}
+
+			// /admin/config carries the same report
+			req := httptest.NewRequest(http.MethodGet, "/admin/config", nil)
+			req.Header.Set("X-Admin-Token", "admin-token")
+			w := httptest.NewRecorder()
+			a.Router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET /admin/config = %d: %s", w.Code, w.Body)
+			}
+			var body struct {
+				AppInfo AppInfo
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			served := body.AppInfo
+			if served.DatabaseConnected != tt.database || served.RedisConnected != tt.redis ||
+				served.Routes != info.Routes || !slices.Equal(served.Middleware, info.Middleware) {
+				t.Errorf("/admin/config AppInfo = %+v, want %+v", served, info)
+			}
+		})
+	}
+}
+
+func TestBootReportCountsRoutes(t *testing.T) {
+	withAdmin := newBootApp(t, false, false, nil).Info()
+	withoutAdmin := newBootApp(t, false, false, func(cfg *config.Config) { cfg.AdminToken = "" }).Info()
+
+	// /admin/config, /admin/info and GET and PUT /admin/settings
+	if got := withAdmin.Routes - withoutAdmin.Routes; got != 4 {
+		t.Errorf("admin routes counted = %d, want 4", got)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchLogLevelSignals switches the log level to debug on SIGUSR1 and back to
+// the configured LOG_LEVEL on SIGUSR2, for live debugging without a restart.
+func (a *App) watchLogLevelSignals(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case sig := <-signals:
+			a.handleLogLevelSignal(sig)
+		}
+	}
+}
+
+func (a *App) handleLogLevelSignal(sig os.Signal) {
+	level := a.config.LogLevel
+	if sig == syscall.SIGUSR1 {
+		level = "debug"
+	}
+
+	previous := a.logger.Level()
+	if err := a.logger.SetLevel(level); err != nil {
+		a.logger.WithError(err).Warnf("Ignoring %s: invalid log level %q", sig, level)
+		return
+	}
+	// Logged at warn so the change is visible whatever the new level
+	a.logger.Warnf("Log level changed from %s to %s on %s", previous, a.logger.Level(), sig)
+}
@@ -0,0 +1,73 @@
+//go:build !windows
+
+package app
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+)
+
+func TestLogLevelSignals(t *testing.T) {
+	// Keep the signals from reaching their default action, which would
+	// kill the test binary, until the watcher has registered for them
+	guard := make(chan os.Signal, 8)
+	signal.Notify(guard, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(guard)
+
+	var logs syncBuffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&logs) })
+	a := &App{config: &config.Config{LogLevel: "info"}, logger: log, background: newBackground()}
+	a.Go("log-level-signals", a.watchLogLevelSignals)
+	t.Cleanup(func() { _ = a.stopBackground(context.Background()) })
+
+	// signalUntil resends sig until the log level is want, since the first
+	// may arrive before the watcher is listening
+	signalUntil := func(sig syscall.Signal, want string) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for log.Level() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("log level = %s after %s, want %s", log.Level(), sig, want)
+			}
+			if err := syscall.Kill(os.Getpid(), sig); err != nil {
+				t.Fatalf("kill: %v", err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	signalUntil(syscall.SIGUSR1, "debug")
+	if !strings.Contains(logs.String(), "Log level changed from info to debug") {
+		t.Errorf("logs = %s, want the change to debug logged", logs.String())
+	}
+
+	signalUntil(syscall.SIGUSR2, "info")
+	if !strings.Contains(logs.String(), "Log level changed from debug to info") {
+		t.Errorf("logs = %s, want the change back to info logged", logs.String())
+	}
+}
+
+func TestLogLevelSignalInvalidLevel(t *testing.T) {
+	var logs syncBuffer
+	log := logger.NewLogger("debug", func(l *logrus.Logger) { l.SetOutput(&logs) })
+	a := &App{config: &config.Config{LogLevel: "verbose"}, logger: log}
+
+	a.handleLogLevelSignal(syscall.SIGUSR2)
+
+	if log.Level() != "debug" {
+		t.Errorf("log level = %s, want debug kept", log.Level())
+	}
+	if !strings.Contains(logs.String(), `invalid log level \"verbose\"`) {
+		t.Errorf("logs = %s, want the invalid level reported", logs.String())
+	}
+}
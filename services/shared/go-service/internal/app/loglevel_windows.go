@@ -0,0 +1,10 @@
+//go:build windows
+
+package app
+
+import "context"
+
+// watchLogLevelSignals does nothing: Windows has no SIGUSR1 or SIGUSR2
+func (a *App) watchLogLevelSignals(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,126 @@
+package app
+
+import (
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/handlers"
+	"{{ module_name }}/internal/middleware"
+	{{- if include_auth }}
+	{{- if include_database }}
+	"{{ module_name }}/internal/database"
+	{{- endif }}
+	{{- endif }}
+)
+
+// apiVersion describes one served API version
+type apiVersion struct {
+	// register adds the version's routes to its /api/<version> group
+	register func(api *gin.RouterGroup)
+
+	// deprecation, when its Sunset is set, marks every route of the version
+	// as deprecated
+	deprecation middleware.DeprecationOptions
+}
+
+// apiVersions returns every API version served. Versions are served side by
+// side, so routes can be migrated to a new version one at a time while
+// clients move over.
+func (a *App) apiVersions() map[string]apiVersion {
+	return map[string]apiVersion{
+		"v1": {
+			register: a.registerV1Routes,
+			deprecation: middleware.DeprecationOptions{
+				Since:  a.config.APIV1Deprecated,
+				Sunset: a.config.APIV1Sunset,
+				Link:   a.config.APIV1DeprecationLink,
+			},
+		},
+		"v2": {register: a.registerV2Routes},
+	}
+}
+
+// setupAPIRoutes mounts each API version under /api/<version>
+func (a *App) setupAPIRoutes() {
+	versions := a.apiVersions()
+
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		version := versions[name]
+
+		api := a.Router.Group("/api/" + name)
+		api.Use(middleware.RequestTimeout(a.config.RequestTimeout))
+		if !version.deprecation.Sunset.IsZero() {
+			api.Use(middleware.Deprecation(version.deprecation, a.logger))
+		}
+		version.register(api)
+	}
+}
+
+// setupStreamRoutes mounts the streaming (SSE, WebSocket) routes under
+// /streams. They have no request budget, which would close them at the
+// deadline, and share a cap of MAX_OPEN_STREAMS connections.
+func (a *App) setupStreamRoutes() {
+	streams := a.Router.Group("/streams")
+	streams.Use(middleware.StreamLimit(a.config.MaxOpenStreams))
+	a.registerStreamRoutes(streams)
+}
+
+// registerStreamRoutes registers streaming routes. Add SSE and WebSocket
+// handlers here rather than under /api.
+func (a *App) registerStreamRoutes(streams *gin.RouterGroup) {
+	// streams.GET("/events", handlers.Events())
+}
+
+func (a *App) registerV1Routes(api *gin.RouterGroup) {
+	{{- if include_auth }}
+	// Auth routes
+	auth := api.Group("/auth")
+	{
+		auth.POST("/login", handlers.Login(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}))
+		auth.POST("/register", handlers.Register(a.config, a.logger, a.passwords{{- if include_database }}, a.dbManager{{- endif }}))
+		auth.POST("/refresh", handlers.RefreshToken(a.config, a.logger{{- if include_database }}, a.dbManager{{- endif }}))
+	}
+
+	// Protected routes
+	protected := api.Group("/")
+	protected.Use(middleware.AuthMiddleware(middleware.AuthOptions{
+		Secret:          a.config.JWTSecret,
+		PreviousSecrets: a.config.JWTPreviousSecrets,
+		Leeway:          a.config.JWTLeeway,
+		Gateway: middleware.GatewayOptions{
+			TrustHeaders:   a.config.AuthTrustGatewayHeaders,
+			TrustedProxies: a.config.AuthTrustedProxies,
+			UserIDHeader:   a.config.AuthUserIDHeader,
+			EmailHeader:    a.config.AuthEmailHeader,
+		},
+	}))
+	{{- if include_database }}
+	// Reject tokens of deleted or deactivated users, and load the user once
+	// for handlers calling database.CurrentUser. Users authenticated by
+	// gateway headers must be in the users table too.
+	protected.Use(database.LoadUser(a.dbManager))
+	{{- endif }}
+	{
+		protected.GET("/profile", handlers.GetProfile(a.logger))
+	}
+	{{- endif }}
+
+	// Example routes
+	api.GET("/", handlers.Root(a.logger))
+	api.GET("/ping", handlers.Ping(a.logger))
+}
+
+// registerV2Routes registers v2 routes. Add routes here as they are migrated
+// from v1 or when their contract changes incompatibly.
+func (a *App) registerV2Routes(api *gin.RouterGroup) {
+	// Example routes
+	api.GET("/", handlers.Root(a.logger))
+	api.GET("/ping", handlers.Ping(a.logger))
+}
@@ -0,0 +1,122 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+)
+
+// TestAPIVersionsServedSideBySide checks both API versions resolve, and only
+// v1 carries the deprecation headers once its sunset is configured
+func TestAPIVersionsServedSideBySide(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("API_V1_SUNSET", "2026-07-01")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	router, err := newRouter(cfg)
+	if err != nil {
+		t.Fatalf("newRouter: %v", err)
+	}
+	a := &App{config: cfg, runtime: config.NewRuntime(cfg), logger: log, Router: router}
+	a.setupAPIRoutes()
+
+	tests := []struct {
+		path       string
+		deprecated bool
+	}{
+		{"/api/v1/ping", true},
+		{"/api/v1/", true},
+		{"/api/v2/ping", false},
+		{"/api/v2/", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			a.Router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != http.StatusOK {
+				t.Fatalf("GET %s = %d, want 200: %s", tt.path, w.Code, w.Body)
+			}
+			if got := w.Header().Get("Sunset") != ""; got != tt.deprecated {
+				t.Errorf("GET %s sent Sunset %q, want deprecated = %v", tt.path, w.Header().Get("Sunset"), tt.deprecated)
+			}
+		})
+	}
+}
+
+func TestRouterOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		configure    func(cfg *config.Config)
+		method, path string
+		wantCode     int
+		wantLocation string
+	}{
+		{
+			name:         "trailing slash redirected",
+			configure:    func(cfg *config.Config) { cfg.RedirectTrailingSlash = true },
+			method:       http.MethodGet,
+			path:         "/items/",
+			wantCode:     http.StatusMovedPermanently,
+			wantLocation: "/items",
+		},
+		{
+			name:      "trailing slash not redirected",
+			configure: func(cfg *config.Config) { cfg.RedirectTrailingSlash = false },
+			method:    http.MethodGet,
+			path:      "/items/",
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "wrong method answered 405",
+			configure: func(cfg *config.Config) { cfg.HandleMethodNotAllowed = true },
+			method:    http.MethodPost,
+			path:      "/items",
+			wantCode:  http.StatusMethodNotAllowed,
+		},
+		{
+			name:      "wrong method answered 404",
+			configure: func(cfg *config.Config) { cfg.HandleMethodNotAllowed = false },
+			method:    http.MethodPost,
+			path:      "/items",
+			wantCode:  http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatalf("config.Load: %v", err)
+			}
+			tt.configure(cfg)
+
+			router, err := newRouter(cfg)
+			if err != nil {
+				t.Fatalf("newRouter: %v", err)
+			}
+			router.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s %s = %d, want %d", tt.method, tt.path, w.Code, tt.wantCode)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
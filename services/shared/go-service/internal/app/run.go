@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+)
+
+// Run is the service's entrypoint, shared by main.go and cmd/server so the
+// two can't drift. It parses the command line, serves until a signal or a
+// fatal background task error, drains, and returns the process exit code.
+func Run() int {
+	selfTest := flag.Bool("selftest", false, "boot the app, run health checks once and exit without serving traffic")
+	validateConfig := flag.Bool("validate-config", false, "load and validate the configuration, report and exit without serving traffic")
+	checkDeps := flag.Bool("check-deps", false, "with --validate-config, also check that dependencies are reachable")
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	// Flags override the environment, which overrides .env
+	if err := config.ApplyFlags(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to apply flags: %v\n", err)
+		return 1
+	}
+
+	if *validateConfig {
+		return runValidateConfig(os.Stdout, *checkDeps)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to load configuration: %v\n", err)
+		return 1
+	}
+
+	log, asyncOutput := newLogger(cfg)
+	defer flushLogs(asyncOutput)
+
+	// Create application, bounding dependency initialization by STARTUP_TIMEOUT
+	startupCtx, cancelStartup := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	application, err := NewApp(startupCtx, cfg, log)
+	cancelStartup()
+	if err != nil {
+		log.Errorf("Failed to create application within STARTUP_TIMEOUT (%s): %v", cfg.StartupTimeout, err)
+		return 1
+	}
+
+	if *selfTest {
+		return runSelfTest(application, log)
+	}
+
+	return serve(application, cfg, log)
+}
+
+// newLogger creates the service logger and, with LOG_ASYNC, the buffered
+// output that must be flushed before exit
+func newLogger(cfg *config.Config) (logger.Logger, *logger.AsyncWriter) {
+	var options []logger.Option
+	if cfg.SyslogEnabled {
+		options = append(options, logger.WithSyslog(logger.SyslogOptions{
+			Network:  cfg.SyslogNetwork,
+			Address:  cfg.SyslogAddress,
+			Facility: cfg.SyslogFacility,
+			Tag:      cfg.ServiceName,
+		}))
+	}
+
+	var asyncOutput *logger.AsyncWriter
+	if cfg.LogAsync {
+		asyncOutput = logger.NewAsyncWriter(os.Stdout, cfg.LogBufferSize)
+		options = append(options, logger.WithAsyncOutput(asyncOutput))
+	}
+	return logger.NewLogger(cfg.LogLevel, options...), asyncOutput
+}
+
+// serve runs the HTTP server until SIGINT, SIGTERM or a fatal background
+// task error, then drains it within SHUTDOWN_TIMEOUT
+func serve(application *App, cfg *config.Config, log logger.Logger) int {
+	server := &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      application.Router,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 30 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Infof("Starting {{ service_name }} on port %s", cfg.Port)
+		log.Infof("Environment: %s", cfg.Environment)
+		log.Infof("Log Level: %s", cfg.LogLevel)
+
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serveErr <- err
+		}
+	}()
+
+	// Wait for interrupt signal, a server failure or a fatal background task error
+	code := 0
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+	select {
+	case sig := <-quit:
+		log.Infof("Received signal %s", sig)
+	case err := <-serveErr:
+		log.Errorf("Server failed: %v", err)
+		code = 1
+	case err := <-application.Fatal():
+		log.Errorf("Shutting down after background task failure: %v", err)
+		code = 1
+	}
+
+	// Drain within SHUTDOWN_TIMEOUT; a second signal cuts it short
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-quit:
+			log.Warn("Received second signal, shutting down immediately")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := application.Drain(ctx, server); err != nil {
+		log.Errorf("Server drain error: %v", err)
+		code = 1
+	}
+
+	log.Info("Server shutdown complete")
+	return code
+}
+
+// flushLogs writes out log entries still buffered by asynchronous logging
+func flushLogs(output *logger.AsyncWriter) {
+	if output == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := output.Close(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush logs: %v\n", err)
+	}
+}
+
+// runSelfTest executes the application self-test and returns the process
+// exit code.
+func runSelfTest(application *App, log logger.Logger) int {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	code := 0
+	if err := application.SelfTest(ctx); err != nil {
+		log.Errorf("Self-test failed: %v", err)
+		code = 1
+	} else {
+		log.Info("Self-test passed")
+	}
+
+	if err := application.Shutdown(ctx); err != nil {
+		log.Errorf("Application shutdown error: %v", err)
+	}
+
+	return code
+}
+
+// runValidateConfig loads and validates the configuration and, with
+// checkDeps, connects to each dependency, writing a report to out. It
+// returns the process exit code: 1 if the configuration is invalid or a
+// critical dependency is unreachable, 0 otherwise.
+func runValidateConfig(out io.Writer, checkDeps bool) int {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(out, "Configuration invalid: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(out, "Configuration valid for %s (%s)\n", cfg.ServiceName, cfg.Environment)
+
+	if !checkDeps {
+		return 0
+	}
+
+	// Only errors are logged, so connection logs don't clutter the report
+	quiet := logger.NewLogger("error")
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.StartupTimeout)
+	defer cancel()
+
+	code := 0
+	for _, dep := range CheckDependencies(ctx, cfg, quiet) {
+		switch {
+		case dep.Err == nil:
+			fmt.Fprintf(out, "  ok       %s\n", dep.Name)
+		case dep.Critical:
+			fmt.Fprintf(out, "  FAILED   %s: %v\n", dep.Name, dep.Err)
+			code = 1
+		default:
+			fmt.Fprintf(out, "  WARNING  %s (non-critical): %v\n", dep.Name, dep.Err)
+		}
+	}
+
+	if code != 0 {
+		fmt.Fprintln(out, "Critical dependencies unreachable")
+	}
+	return code
+}
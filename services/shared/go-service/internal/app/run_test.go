@@ -0,0 +1,129 @@
+package app
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	{{- if include_redis }}
+
+	"github.com/alicebob/miniredis/v2"
+	{{- endif }}
+)
+
+// closedAddr returns an address nothing listens on
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+func TestRunValidateConfig(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(up.Close)
+	down := "http://" + closedAddr(t)
+
+	tests := []struct {
+		name      string
+		env       map[string]string
+		checkDeps bool
+		wantCode  int
+		want      []string
+		// notWant are lines the report must not contain
+		notWant []string
+	}{
+		{
+			name:     "valid",
+			wantCode: 0,
+			want:     []string{"Configuration valid for"},
+		},
+		{
+			name:     "invalid",
+			env:      map[string]string{"RATE_LIMIT": "-5"},
+			wantCode: 1,
+			want:     []string{"Configuration invalid: RATE_LIMIT must not be negative"},
+		},
+		{
+			name:      "invalid with check-deps",
+			env:       map[string]string{"RATE_LIMIT": "-5"},
+			checkDeps: true,
+			wantCode:  1,
+			want:      []string{"Configuration invalid"},
+			// Dependencies aren't checked against an invalid configuration
+			notWant: []string{"  ok ", "  FAILED ", "  WARNING "},
+		},
+		{
+			name:      "non-critical dependency down",
+			env:       map[string]string{"HEALTH_HTTP_DEPENDENCIES": "orders=" + up.URL + ",audit=" + down + ";optional"},
+			checkDeps: true,
+			{{- if include_database }}
+			// No database is reachable in tests, which fails the check
+			wantCode: 1,
+			want:     []string{"  FAILED   database:", "  ok       orders", "  WARNING  audit (non-critical):", "Critical dependencies unreachable"},
+			{{- else }}
+			wantCode: 0,
+			want:     []string{"  ok       orders", "  WARNING  audit (non-critical):"},
+			notWant:  []string{"Critical dependencies unreachable"},
+			{{- endif }}
+		},
+		{
+			name:      "critical dependency down",
+			env:       map[string]string{"HEALTH_HTTP_DEPENDENCIES": "orders=" + down},
+			checkDeps: true,
+			wantCode:  1,
+			want:      []string{"  FAILED   orders:", "Critical dependencies unreachable"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("STARTUP_TIMEOUT", "500ms")
+			{{- if include_database }}
+			host, port, _ := net.SplitHostPort(closedAddr(t))
+			t.Setenv("DATABASE_URL", "")
+			t.Setenv("DATABASE_HOST", host)
+			t.Setenv("DATABASE_PORT", port)
+			{{- endif }}
+			{{- if include_redis }}
+			server := miniredis.RunT(t)
+			t.Setenv("REDIS_URL", "")
+			t.Setenv("REDIS_CLUSTER_ADDRS", "")
+			t.Setenv("REDIS_HOST", server.Host())
+			t.Setenv("REDIS_PORT", server.Port())
+			{{- endif }}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			var out bytes.Buffer
+			code := runValidateConfig(&out, tt.checkDeps)
+			report := out.String()
+
+			if code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d:\n%s", code, tt.wantCode, report)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(report, want) {
+					t.Errorf("report missing %q:\n%s", want, report)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(report, notWant) {
+					t.Errorf("report contains %q:\n%s", notWant, report)
+				}
+			}
+			{{- if include_redis }}
+			if tt.checkDeps && !strings.HasPrefix(report, "Configuration invalid") && !strings.Contains(report, "  ok       redis") {
+				t.Errorf("report missing a reachable redis:\n%s", report)
+			}
+			{{- endif }}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SelfTest runs every dependency health check once and verifies that the
+// core routes are registered, without serving any traffic. It is used by the
+// --selftest flag for CI and deployment gating.
+func (a *App) SelfTest(ctx context.Context) error {
+	if err := a.runSelfTest(ctx); err != nil {
+		return err
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("self-test did not complete: %w", ctx.Err())
+	}
+	return nil
+}
+
+func (a *App) runSelfTest(ctx context.Context) error {
+	// Migrations and warm-up run in the background, and their checks fail
+	// until they are done
+	for pending := a.health.PendingStartup(); len(pending) > 0; pending = a.health.PendingStartup() {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("startup tasks still pending: %s", strings.Join(pending, ", "))
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	if err := a.health.StartupErr(); err != nil {
+		return fmt.Errorf("startup failed: %w", err)
+	}
+
+	var errs []error
+
+	// Run every registered health check
+	for _, check := range a.health.Checks() {
+		if _, err := check.Run(ctx); err != nil {
+			if !check.Critical {
+				a.logger.Warnf("Non-critical check %s failed: %v", check.Name, err)
+				continue
+			}
+			errs = append(errs, fmt.Errorf("%s check failed: %w", check.Name, err))
+		}
+	}
+
+	// Verify routes are registered
+	registered := make(map[string]bool)
+	for _, route := range a.Router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	expected := []string{
+		http.MethodGet + " " + a.config.HealthPath,
+		http.MethodGet + " " + a.config.MetricsPath,
+		http.MethodGet + " /api/v1/ping",
+	}
+	for _, route := range expected {
+		if !registered[route] {
+			errs = append(errs, fmt.Errorf("route not registered: %s", route))
+		}
+	}
+
+	return errors.Join(errs...)
+}
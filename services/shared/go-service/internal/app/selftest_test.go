@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/logger"
+)
+
+// newSelfTestApp returns an app with the core routes registered and the
+// given dependency checks
+func newSelfTestApp(t *testing.T, checks map[string]error) *App {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	a := &App{
+		config: cfg,
+		logger: logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) }),
+		health: health.NewRegistry(health.Options{CheckTimeout: time.Second}),
+		Router: gin.New(),
+	}
+	for name, err := range checks {
+		err := err
+		a.health.Register(name, true, func(context.Context) (health.CheckResult, error) {
+			return health.CheckResult{}, err
+		})
+	}
+
+	ok := func(c *gin.Context) {}
+	a.Router.GET(cfg.HealthPath, ok)
+	a.Router.GET(cfg.MetricsPath, ok)
+	a.Router.GET("/api/v1/ping", ok)
+	return a
+}
+
+func TestSelfTest(t *testing.T) {
+	refused := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		checks  map[string]error
+		wantErr string
+	}{
+		{"healthy dependencies", map[string]error{"database": nil, "redis": nil}, ""},
+		{"broken dependency", map[string]error{"database": nil, "redis": refused}, "redis check failed: connection refused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSelfTestApp(t, tt.checks)
+			err := a.SelfTest(context.Background())
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("SelfTest = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("SelfTest = %v, want %q", err, tt.wantErr)
+			}
+			if !errors.Is(err, refused) {
+				t.Errorf("SelfTest = %v, want it to wrap the check's error", err)
+			}
+		})
+	}
+}
+
+func TestSelfTestMissingRoute(t *testing.T) {
+	a := newSelfTestApp(t, nil)
+	a.Router = gin.New()
+
+	err := a.SelfTest(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "route not registered: GET /api/v1/ping") {
+		t.Errorf("SelfTest = %v, want the missing route reported", err)
+	}
+}
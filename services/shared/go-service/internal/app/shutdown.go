@@ -0,0 +1,150 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/middleware"
+)
+
+// Shutdown timing is exported as it progresses: steps completing while
+// requests are still served, such as shutdown_delay, can be scraped before
+// the server stops
+var (
+	shutdownDuration = metrics.Register(nil, prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "shutdown_duration_seconds",
+		Help: "The time spent shutting down, updated as each step completes",
+	}))
+
+	shutdownStepDuration = metrics.Register(nil, prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "shutdown_step_duration_seconds",
+			Help: "The time each shutdown step took",
+		},
+		[]string{"step"},
+	))
+)
+
+// Drain shuts the service down on deploy without dropping requests, within
+// ctx (SHUTDOWN_TIMEOUT):
+//
+//  1. Readiness flips: /health reports 503 draining, so load balancers stop
+//     routing new requests here. Keep-alives are disabled so clients open
+//     their next connection to another instance.
+//  2. Requests are still served for SHUTDOWN_DELAY, the time load balancers
+//     take to notice, so requests already routed here don't fail.
+//  3. The server stops accepting connections and waits for in-flight
+//     requests to complete. Connections still open when ctx expires, such as
+//     streams, are closed.
+//  4. Background tasks, webhook deliveries and dependency connections are
+//     shut down (see Shutdown), after the last request that could use them.
+//
+// It returns the errors of the steps that failed, including in-flight
+// requests that didn't complete in time.
+func (a *App) Drain(ctx context.Context, server *http.Server) error {
+	a.logger.Info("Draining server...")
+	timer := newShutdownTimer(a.logger)
+	defer timer.finish()
+
+	a.health.SetDraining(true)
+	server.SetKeepAlivesEnabled(false)
+
+	timer.step("shutdown_delay", func() error {
+		delay := time.NewTimer(a.config.ShutdownDelay)
+		defer delay.Stop()
+		select {
+		case <-delay.C:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	timer.step("http_server", func() error {
+		a.logger.Infof("Waiting for %d in-flight requests", middleware.RequestsInFlight())
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+			return fmt.Errorf("%d requests still in flight: %w", middleware.RequestsInFlight(), err)
+		}
+		return nil
+	})
+
+	a.release(ctx, timer)
+	return timer.err()
+}
+
+// shutdownTimer logs and exports how long each shutdown step took and logs
+// whether it failed or ran out of time, for post-mortems of slow or unclean
+// shutdowns
+type shutdownTimer struct {
+	log    logger.Logger
+	start  time.Time
+	failed []string
+	timed  []string
+	errs   []error
+}
+
+func newShutdownTimer(log logger.Logger) *shutdownTimer {
+	return &shutdownTimer{log: log, start: time.Now()}
+}
+
+// step runs fn and logs its duration and outcome, judged by fn's error
+// alone: a step that succeeded just before the deadline didn't time out
+func (t *shutdownTimer) step(name string, fn func() error) {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+
+	shutdownStepDuration.WithLabelValues(name).Set(elapsed.Seconds())
+	shutdownDuration.Set(time.Since(t.start).Seconds())
+
+	log := t.log.WithFields(map[string]interface{}{
+		"step":        name,
+		"duration_ms": elapsed.Milliseconds(),
+	})
+
+	if err != nil {
+		t.errs = append(t.errs, fmt.Errorf("%s: %w", name, err))
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		t.timed = append(t.timed, name)
+		log.WithError(err).Errorf("Shutdown step %s timed out after %s", name, elapsed)
+	case err != nil:
+		t.failed = append(t.failed, name)
+		log.WithError(err).Errorf("Shutdown step %s failed after %s", name, elapsed)
+	default:
+		log.Infof("Shutdown step %s completed in %s", name, elapsed)
+	}
+}
+
+// err returns the errors of the failed and timed out steps joined, or nil
+func (t *shutdownTimer) err() error {
+	return errors.Join(t.errs...)
+}
+
+// finish logs the summary with the total shutdown duration and records the
+// total in shutdown_duration_seconds
+func (t *shutdownTimer) finish() {
+	elapsed := time.Since(t.start)
+	shutdownDuration.Set(elapsed.Seconds())
+
+	log := t.log.WithFields(map[string]interface{}{
+		"duration_ms": elapsed.Milliseconds(),
+		"failed":      t.failed,
+		"timed_out":   t.timed,
+	})
+	if len(t.failed) > 0 || len(t.timed) > 0 {
+		log.Warnf("Application shutdown finished with errors in %s", elapsed)
+		return
+	}
+	log.Infof("Application shutdown completed in %s", elapsed)
+}
@@ -0,0 +1,303 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/handlers"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/webhooks"
+)
+
+// newDrainTestApp returns an app serving /health and a /slow route that
+// blocks until release is closed, signalling started when it's entered
+func newDrainTestApp(t *testing.T, delay time.Duration, started chan<- struct{}, release <-chan struct{}) *App {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.ShutdownDelay = delay
+
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+	a := &App{
+		config:     cfg,
+		logger:     log,
+		Router:     gin.New(),
+		health:     health.NewRegistry(health.Options{}),
+		background: newBackground(),
+		Webhooks:   webhooks.NewDispatcher(webhooks.Options{}, webhooks.NewMemoryStore(0), log),
+	}
+	a.Router.GET(cfg.HealthPath, handlers.HealthCheck(cfg, log, a.health))
+	a.Router.GET("/slow", func(c *gin.Context) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			c.String(http.StatusOK, "done")
+		case <-c.Request.Context().Done():
+		}
+	})
+	return a
+}
+
+// getOnNewConn sends a GET on a new connection and returns the status and body
+func getOnNewConn(url string) (int, string, error) {
+	client := &http.Client{
+		Transport: &http.Transport{DisableKeepAlives: true},
+		Timeout:   5 * time.Second,
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+// waitRefused waits until the listener at addr refuses connections
+func waitRefused(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		conn, err := net.DialTimeout("tcp", addr, 100*time.Millisecond)
+		if err != nil {
+			return
+		}
+		conn.Close()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("server still accepting connections while draining")
+}
+
+// drainResponse is the outcome of a request sent with getOnNewConn
+type drainResponse struct {
+	status int
+	body   string
+	err    error
+}
+
+func TestDrain(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	a := newDrainTestApp(t, 200*time.Millisecond, started, release)
+
+	srv := httptest.NewServer(a.Router)
+	defer srv.Close()
+	healthURL := srv.URL + a.config.HealthPath
+
+	if status, body, err := getOnNewConn(healthURL); err != nil || status != http.StatusOK {
+		t.Fatalf("health before draining = %d, %v: %s", status, err, body)
+	}
+
+	inFlight := make(chan drainResponse, 1)
+	go func() {
+		status, body, err := getOnNewConn(srv.URL + "/slow")
+		inFlight <- drainResponse{status, body, err}
+	}()
+	<-started
+
+	const budget = 2 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	start := time.Now()
+	drained := make(chan error, 1)
+	go func() {
+		drained <- a.Drain(ctx, srv.Config)
+	}()
+
+	// Readiness fails while requests are still served during SHUTDOWN_DELAY
+	deadline := time.Now().Add(time.Second)
+	for {
+		status, body, err := getOnNewConn(healthURL)
+		if err != nil {
+			t.Fatalf("health during SHUTDOWN_DELAY: %v", err)
+		}
+		if status == http.StatusServiceUnavailable {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health while draining = %d: %s, want 503", status, body)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Once the delay is over, new connections are refused but the request
+	// in flight is waited for
+	waitRefused(t, srv.Listener.Addr().String())
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v before the in-flight request completed", err)
+	default:
+	}
+
+	close(release)
+	if r := <-inFlight; r.err != nil || r.status != http.StatusOK || r.body != "done" {
+		t.Errorf("in-flight request = %d %q, %v; want 200 done", r.status, r.body, r.err)
+	}
+
+	select {
+	case err := <-drained:
+		if err != nil {
+			t.Errorf("Drain: %v", err)
+		}
+	case <-time.After(budget):
+		t.Fatal("Drain did not return after the in-flight request completed")
+	}
+	if elapsed := time.Since(start); elapsed > budget {
+		t.Errorf("Drain took %s, beyond the %s budget", elapsed, budget)
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	a := newDrainTestApp(t, 0, started, release)
+
+	srv := httptest.NewServer(a.Router)
+	defer srv.Close()
+
+	inFlight := make(chan drainResponse, 1)
+	go func() {
+		status, body, err := getOnNewConn(srv.URL + "/slow")
+		inFlight <- drainResponse{status, body, err}
+	}()
+	<-started
+
+	const budget = 200 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	start := time.Now()
+	if err := a.Drain(ctx, srv.Config); err == nil {
+		t.Error("Drain succeeded with a request still in flight")
+	}
+	if elapsed := time.Since(start); elapsed > budget+500*time.Millisecond {
+		t.Errorf("Drain took %s, beyond the %s budget", elapsed, budget)
+	}
+
+	// The hung request's connection is closed rather than left open
+	select {
+	case r := <-inFlight:
+		if r.err == nil {
+			t.Errorf("hung request completed with %d after the budget", r.status)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("hung request's connection was left open")
+	}
+}
+
+// shutdownLogs decodes the JSON log entries written to buf
+func shutdownLogs(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestShutdownTimer(t *testing.T) {
+	var buf bytes.Buffer
+	timer := newShutdownTimer(logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&buf) }))
+
+	failed := errors.New("connection reset")
+	timer.step("fast", func() error { return nil })
+	timer.step("slow", func() error {
+		time.Sleep(20 * time.Millisecond)
+		return context.DeadlineExceeded
+	})
+	timer.step("broken", func() error { return failed })
+	timer.finish()
+
+	err := timer.err()
+	if !errors.Is(err, failed) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the failed and timed out steps' errors joined", err)
+	}
+	if !strings.Contains(err.Error(), "broken: connection reset") {
+		t.Errorf("err = %q, want the step's name", err)
+	}
+
+	entries := shutdownLogs(t, &buf)
+	if len(entries) != 4 {
+		t.Fatalf("logged %d entries, want one per step and a summary: %v", len(entries), entries)
+	}
+	for i, want := range []struct{ step, level, msg string }{
+		{"fast", "info", "Shutdown step fast completed"},
+		{"slow", "error", "Shutdown step slow timed out"},
+		{"broken", "error", "Shutdown step broken failed"},
+	} {
+		entry := entries[i]
+		if entry["step"] != want.step || entry["level"] != want.level {
+			t.Errorf("entry %d = %v, want a %s entry for %s", i, entry, want.level, want.step)
+		}
+		if msg, _ := entry["msg"].(string); !strings.HasPrefix(msg, want.msg) {
+			t.Errorf("entry %d msg = %q, want %q", i, msg, want.msg)
+		}
+		if _, ok := entry["duration_ms"].(float64); !ok {
+			t.Errorf("entry %d has no duration_ms: %v", i, entry)
+		}
+	}
+	if ms := entries[1]["duration_ms"].(float64); ms < 20 {
+		t.Errorf("slow step duration_ms = %v, want at least 20", ms)
+	}
+
+	slow := testutil.ToFloat64(shutdownStepDuration.WithLabelValues("slow"))
+	if slow < 0.02 {
+		t.Errorf("shutdown_step_duration_seconds{step=slow} = %v, want at least 0.02", slow)
+	}
+	if total := testutil.ToFloat64(shutdownDuration); total < slow {
+		t.Errorf("shutdown_duration_seconds = %v, want at least the slow step's %v", total, slow)
+	}
+
+	summary := entries[3]
+	if summary["level"] != "warning" || fmt.Sprint(summary["failed"]) != "[broken]" || fmt.Sprint(summary["timed_out"]) != "[slow]" {
+		t.Errorf("summary = %v, want a warning naming the failed and timed out steps", summary)
+	}
+}
+
+func TestShutdownReturnsStepErrors(t *testing.T) {
+	a := newDrainTestApp(t, 0, nil, nil)
+	var buf bytes.Buffer
+	a.logger = logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&buf) })
+
+	broken := errors.New("broken")
+	a.Go("worker", func(ctx context.Context) error { return broken })
+	<-a.Fatal()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := a.Shutdown(ctx)
+	if !errors.Is(err, broken) || !strings.Contains(err.Error(), "background_tasks") {
+		t.Errorf("Shutdown = %v, want the background_tasks step's error", err)
+	}
+
+	summary := shutdownLogs(t, &buf)
+	if last := summary[len(summary)-1]; fmt.Sprint(last["failed"]) != "[background_tasks]" {
+		t.Errorf("summary = %v, want background_tasks reported as failed", last)
+	}
+}
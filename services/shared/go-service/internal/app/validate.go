@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/logger"
+	{{- if include_database }}
+	"{{ module_name }}/internal/database"
+	{{- endif }}
+	{{- if include_redis }}
+	"{{ module_name }}/internal/redis"
+	{{- endif }}
+)
+
+// DependencyStatus is the outcome of connecting to one dependency
+type DependencyStatus struct {
+	Name     string
+	Critical bool
+	// Err is nil when the dependency was reachable
+	Err error
+}
+
+// CheckDependencies connects to every dependency cfg configures, the way
+// NewApp would, and releases it again. It doesn't migrate the schema, start
+// background tasks or serve traffic, so it can vet a configuration before it
+// is deployed (--validate-config --check-deps). Connecting is bounded by ctx.
+func CheckDependencies(ctx context.Context, cfg *config.Config, log logger.Logger) []DependencyStatus {
+	var statuses []DependencyStatus
+
+	{{- if include_database }}
+
+	dbManager, err := database.GetInstance(ctx, cfg.ServiceName, cfg, log)
+	if err == nil {
+		_ = dbManager.Close()
+	}
+	statuses = append(statuses, DependencyStatus{Name: "database", Critical: true, Err: err})
+	{{- endif }}
+
+	{{- if include_redis }}
+
+	redisClient, err := redis.NewClient(ctx, cfg, log)
+	if err == nil {
+		_ = redisClient.Close()
+	}
+	statuses = append(statuses, DependencyStatus{Name: "redis", Critical: true, Err: err})
+	{{- endif }}
+
+	client := healthHTTPClient(httpClientOptions(cfg))
+	for _, dep := range cfg.HealthHTTPDependencies {
+		checkCtx, cancel := context.WithTimeout(ctx, cfg.HealthCheckTimeout)
+		_, err := health.HTTPCheck(client, dep.Method, dep.URL)(checkCtx)
+		cancel()
+		statuses = append(statuses, DependencyStatus{Name: dep.Name, Critical: dep.Critical, Err: err})
+	}
+
+	return statuses
+}
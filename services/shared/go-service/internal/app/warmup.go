@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"{{ module_name }}/internal/health"
+)
+
+// warmUpStep primes a dependency, so the first requests after startup aren't
+// slowed down by empty connection pools or cold caches
+type warmUpStep struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// warmUpSteps lists the steps run after dependencies connect and before the
+// service reports ready. Add steps here that load hot cache keys, e.g.
+// reference data read on most requests. Steps run concurrently with
+// migrations, so a step reading tables should check dbManager.Migrated().
+func (a *App) warmUpSteps() []warmUpStep {
+	var steps []warmUpStep
+
+	{{- if include_database }}
+	steps = append(steps, warmUpStep{name: "database_pool", run: func(ctx context.Context) error {
+		return a.dbManager.WarmUp(ctx, a.config.WarmUpConnections)
+	}})
+	{{- endif }}
+
+	{{- if include_redis }}
+	steps = append(steps, warmUpStep{name: "redis_pool", run: func(ctx context.Context) error {
+		return a.redis.WarmUp(ctx, a.config.WarmUpConnections)
+	}})
+	{{- endif }}
+
+	return steps
+}
+
+// warmUp runs every warm-up step in order within WARMUP_TIMEOUT. Warm-up only
+// speeds up the first requests, so a failed step is logged and the service
+// becomes ready regardless. It returns false if ctx was cancelled first.
+func (a *App) warmUp(ctx context.Context) bool {
+	stepCtx, cancel := context.WithTimeout(ctx, a.config.WarmUpTimeout)
+	defer cancel()
+
+	start := time.Now()
+	for _, step := range a.warmUpSteps() {
+		stepStart := time.Now()
+		if err := step.run(stepCtx); err != nil {
+			a.logger.WithError(err).Warnf("Warm-up step %s failed after %s", step.name, time.Since(stepStart))
+			continue
+		}
+		a.logger.Debugf("Warm-up step %s completed in %s", step.name, time.Since(stepStart))
+	}
+
+	// Shut down before warm-up finished
+	if ctx.Err() != nil {
+		return false
+	}
+	a.logger.Infof("Warm-up completed in %s", time.Since(start))
+	return true
+}
+
+// startWarmUp runs the warm-up steps as a background task. The startup task
+// and checkWarmUp keep the replica unready until they are done.
+func (a *App) startWarmUp() {
+	warmed := a.health.StartupTask("warmup")
+	a.Go("warmup", func(ctx context.Context) error {
+		if a.warmUp(ctx) {
+			a.warmedUp.Store(true)
+			warmed(nil)
+		}
+		return nil
+	})
+}
+
+// checkWarmUp fails until warm-up has completed
+func (a *App) checkWarmUp(ctx context.Context) (health.CheckResult, error) {
+	if !a.warmedUp.Load() {
+		return health.CheckResult{}, fmt.Errorf("warm-up pending")
+	}
+	return health.CheckResult{}, nil
+}
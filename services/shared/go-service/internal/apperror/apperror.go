@@ -0,0 +1,203 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// Code is a machine-readable error classification returned to clients
+type Code string
+
+const (
+	CodeInvalidArgument  Code = "invalid_argument"
+	CodeUnauthenticated  Code = "unauthenticated"
+	CodeForbidden        Code = "forbidden"
+	CodeNotFound         Code = "not_found"
+	CodeMethodNotAllowed Code = "method_not_allowed"
+	CodeConflict         Code = "conflict"
+	CodePayloadTooLarge  Code = "payload_too_large"
+	CodeUnsupportedMedia Code = "unsupported_media_type"
+	CodeRateLimited      Code = "rate_limited"
+	CodeUnavailable      Code = "unavailable"
+	CodeInternal         Code = "internal"
+)
+
+// Error is an application error carrying the HTTP status and client-safe
+// message to respond with, plus where it occurred. Errors created by New and
+// its helpers describe what went wrong; Wrap adds the operation and request
+// ID at each layer the error passes through.
+type Error struct {
+	Code      Code
+	Status    int
+	Message   string
+	Details   interface{}
+	Op        string
+	RequestID string
+	Err       error
+
+	wrapped bool
+}
+
+// New creates an error with the given status, code and client-safe message
+func New(status int, code Code, message string) *Error {
+	return &Error{Code: code, Status: status, Message: message}
+}
+
+func InvalidArgument(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidArgument, message)
+}
+
+func Unauthenticated(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthenticated, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func MethodNotAllowed(message string) *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func PayloadTooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
+func UnsupportedMediaType(message string) *Error {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+func Unavailable(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+// WithDetails returns a copy of e with client-facing details attached
+func (e *Error) WithDetails(details interface{}) *Error {
+	cp := *e
+	cp.Details = details
+	return &cp
+}
+
+// WithCause returns a copy of e recording the underlying cause. The cause is
+// logged but never sent to clients.
+func (e *Error) WithCause(err error) *Error {
+	cp := *e
+	cp.Err = err
+	return &cp
+}
+
+// Wrap annotates err with the operation it passed through and the request
+// ID from ctx. The status, code and message of the nearest *Error in the
+// chain are carried up; any other error is treated as internal.
+func Wrap(ctx context.Context, op string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	wrapped := &Error{
+		Code:      CodeInternal,
+		Status:    http.StatusInternalServerError,
+		Message:   "Internal server error",
+		Op:        op,
+		RequestID: reqctx.RequestID(ctx),
+		Err:       err,
+		wrapped:   true,
+	}
+
+	var inner *Error
+	if errors.As(err, &inner) {
+		wrapped.Code = inner.Code
+		wrapped.Status = inner.Status
+		wrapped.Message = inner.Message
+		wrapped.Details = inner.Details
+		if wrapped.RequestID == "" {
+			wrapped.RequestID = inner.RequestID
+		}
+	}
+
+	return wrapped
+}
+
+// From returns err as an *Error, converting unknown errors to internal ones
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	return Internal("Internal server error").WithCause(err)
+}
+
+func (e *Error) Error() string {
+	if e.wrapped {
+		return e.Op + ": " + e.Err.Error()
+	}
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Format implements fmt.Formatter. %+v prints the full chain with the
+// operation, code and request ID recorded at each layer.
+func (e *Error) Format(s fmt.State, verb rune) {
+	switch {
+	case verb == 'v' && s.Flag('+'):
+		e.writeChain(s)
+	case verb == 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	default:
+		io.WriteString(s, e.Error())
+	}
+}
+
+func (e *Error) writeChain(w io.Writer) {
+	var err error = e
+	for depth := 0; err != nil; depth++ {
+		if depth > 0 {
+			io.WriteString(w, "\n\tcaused by: ")
+		}
+
+		appErr, ok := err.(*Error)
+		if !ok {
+			io.WriteString(w, err.Error())
+			return
+		}
+
+		if appErr.wrapped {
+			fmt.Fprintf(w, "%s [code=%s status=%d", appErr.Op, appErr.Code, appErr.Status)
+		} else {
+			fmt.Fprintf(w, "%s [code=%s status=%d", appErr.Message, appErr.Code, appErr.Status)
+		}
+		if appErr.RequestID != "" {
+			fmt.Fprintf(w, " request_id=%s", appErr.RequestID)
+		}
+		io.WriteString(w, "]")
+
+		err = appErr.Err
+	}
+}
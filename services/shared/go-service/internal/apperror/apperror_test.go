@@ -0,0 +1,84 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+func TestWrapChain(t *testing.T) {
+	errNoRows := errors.New("record not found")
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+
+	base := NotFound("User not found").WithCause(errNoRows)
+	err := Wrap(ctx, "handlers.GetUser", fmt.Errorf("lookup: %w", Wrap(ctx, "database.GetUserByID", base)))
+
+	if !errors.Is(err, errNoRows) {
+		t.Error("errors.Is doesn't find the cause through the chain")
+	}
+	if !errors.Is(err, base) {
+		t.Error("errors.Is doesn't find the original *Error through the chain")
+	}
+
+	var appErr *Error
+	if !errors.As(err, &appErr) {
+		t.Fatal("errors.As doesn't find an *Error")
+	}
+	if appErr.Op != "handlers.GetUser" || appErr.RequestID != "req-1" {
+		t.Errorf("outer error op = %q, request ID = %q; want handlers.GetUser, req-1", appErr.Op, appErr.RequestID)
+	}
+	if appErr.Status != http.StatusNotFound || appErr.Code != CodeNotFound || appErr.Message != "User not found" {
+		t.Errorf("outer error = %d %s %q, want the original 404 carried up", appErr.Status, appErr.Code, appErr.Message)
+	}
+
+	want := "handlers.GetUser: lookup: database.GetUserByID: User not found: record not found"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+
+	chain := fmt.Sprintf("%+v", err)
+	for _, part := range []string{"handlers.GetUser [code=not_found status=404 request_id=req-1]", "database.GetUserByID", "record not found"} {
+		if !strings.Contains(chain, part) {
+			t.Errorf("%%+v = %q, want it to contain %q", chain, part)
+		}
+	}
+}
+
+func TestWrapRequestID(t *testing.T) {
+	inner := Wrap(reqctx.WithRequestID(context.Background(), "req-1"), "inner", Conflict("Email taken"))
+
+	// A layer without the request ID in its context keeps the inner one
+	var appErr *Error
+	if !errors.As(Wrap(context.Background(), "outer", inner), &appErr) || appErr.RequestID != "req-1" {
+		t.Errorf("request ID = %q, want req-1 from the inner layer", appErr.RequestID)
+	}
+
+	if Wrap(context.Background(), "op", nil) != nil {
+		t.Error("Wrap(nil) isn't nil")
+	}
+}
+
+func TestFrom(t *testing.T) {
+	cause := errors.New("connection reset")
+
+	appErr := From(fmt.Errorf("query: %w", cause))
+	if appErr.Status != http.StatusInternalServerError || appErr.Code != CodeInternal {
+		t.Errorf("From = %d %s, want an internal error", appErr.Status, appErr.Code)
+	}
+	if strings.Contains(appErr.Message, "connection reset") {
+		t.Errorf("message %q leaks the cause", appErr.Message)
+	}
+	if !errors.Is(appErr, cause) {
+		t.Error("From dropped the cause")
+	}
+
+	forbidden := Forbidden("No access")
+	if From(fmt.Errorf("check: %w", forbidden)) != forbidden {
+		t.Error("From doesn't return the *Error in the chain")
+	}
+}
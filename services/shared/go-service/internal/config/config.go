@@ -1,51 +1,300 @@
 package config
 
 import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// HTTPDependency is a downstream HTTP service probed by the health check
+type HTTPDependency struct {
+	Name string
+	URL  string
+	// Method is GET or HEAD
+	Method   string
+	Critical bool
+}
+
+// syslogFacilities are the facility names accepted in SYSLOG_FACILITY
+var syslogFacilities = map[string]bool{
+	"kern": true, "user": true, "mail": true, "daemon": true, "auth": true,
+	"syslog": true, "lpr": true, "news": true, "uucp": true, "cron": true,
+	"authpriv": true, "ftp": true, "local0": true, "local1": true,
+	"local2": true, "local3": true, "local4": true, "local5": true,
+	"local6": true, "local7": true,
+}
+
 type Config struct {
 	Environment string
 	Port        string
 	LogLevel    string
 	ServiceName string
 
+	// StartupTimeout bounds dependency initialization
+	StartupTimeout time.Duration
+
+	// RequestTimeout is the overall time budget of an API request
+	RequestTimeout time.Duration
+
+	// Warm-up of connection pools and caches before the service reports ready
+	WarmUpEnabled     bool
+	WarmUpConnections int
+	WarmUpTimeout     time.Duration
+
+	// ShutdownDelay keeps serving after readiness flips at shutdown, until
+	// load balancers stop routing here; ShutdownTimeout bounds the shutdown
+	ShutdownDelay   time.Duration
+	ShutdownTimeout time.Duration
+
+	// Syslog output, in addition to stdout
+	SyslogEnabled  bool
+	SyslogNetwork  string
+	SyslogAddress  string
+	SyslogFacility string
+
+	// Asynchronous stdout logging with a bounded buffer
+	LogAsync      bool
+	LogBufferSize int
+
 	{{- if include_database }}
 	// Database configuration
-	DatabaseURL      string
-	DatabaseHost     string
-	DatabasePort     string
-	DatabaseUser     string
-	DatabasePassword string
-	DatabaseName     string
-	DatabaseSSLMode  string
+	DatabaseURL       string `sensitive:"true"`
+	DatabaseHost      string
+	DatabasePort      string
+	DatabaseUser      string
+	DatabasePassword  string `sensitive:"true"`
+	DatabaseName      string
+	DatabaseSSLMode   string
+	DatabaseBatchSize int
+
+	// DatabaseQueryTimeout bounds each statement of a request's queries
+	DatabaseQueryTimeout time.Duration
+
+	// Retries of transactions aborted by a serialization failure or deadlock
+	DatabaseTxMaxRetries   int
+	DatabaseTxRetryBackoff time.Duration
+
+	// Table naming
+	DatabaseTablePrefix    string
+	DatabaseSingularTables bool
+
+	// DatabaseAutoMigrate migrates the schema at startup; otherwise the
+	// schema is only checked to exist. Off by default so a service pointed
+	// at a shared database doesn't alter tables it doesn't own.
+	DatabaseAutoMigrate bool
+
+	// DatabaseMigrationGracePeriod is how long a migration in progress at
+	// shutdown may run before it is rolled back
+	DatabaseMigrationGracePeriod time.Duration
+
+	// DatabaseRequiredExtensions must be installed for the service to start
+	DatabaseRequiredExtensions []string
+
+	// Read replicas, each optionally prefixed with its region ("region=dsn"),
+	// and which of them this instance reads from
+	DatabaseReplicaURLs    []string `sensitive:"true"`
+	DatabaseRegion         string
+	DatabaseReadPreference string
 	{{- endif }}
 
 	{{- if include_redis }}
 	// Redis configuration
-	RedisURL      string
+	RedisURL      string `sensitive:"true"`
 	RedisHost     string
 	RedisPort     string
-	RedisPassword string
+	RedisUsername string
+	RedisPassword string `sensitive:"true"`
 	RedisDB       int
+
+	// RedisClusterAddrs switches to a Redis Cluster client seeded with these
+	// nodes; RedisHost, RedisPort, RedisURL and RedisDB are then ignored
+	RedisClusterAddrs []string
+	// RedisClusterReplicaReads serves cluster reads from the lowest-latency
+	// node, replicas included, instead of the slot's master. Replicas lag,
+	// so reads may not see a write just made.
+	RedisClusterReplicaReads bool
+
+	RedisCompressionThreshold int
+	RedisKeyPrefix            string
+	RedisTTLJitter            float64
+
+	// Per-operation timeouts, so a slow Redis can't hang a request
+	RedisDialTimeout  time.Duration
+	RedisReadTimeout  time.Duration
+	RedisWriteTimeout time.Duration
+
+	// RedisOperationTimeout bounds a whole command, retries included
+	RedisOperationTimeout time.Duration
+
+	// NonceTTL is how long an issued replay-protection nonce stays valid
+	NonceTTL time.Duration
+
+	// RedisStateKeyPrefix namespaces non-evictable state (sessions, locks,
+	// nonces) apart from evictable cache entries
+	RedisStateKeyPrefix string
+
+	// RedisMemoryWarnRatio of maxmemory in use degrades the health check
+	RedisMemoryWarnRatio float64
 	{{- endif }}
 
 	{{- if include_auth }}
 	// JWT configuration
-	JWTSecret     string
+	JWTSecret     string `sensitive:"true"`
 	JWTExpiresIn  string
+
+	// JWTPreviousSecrets still verify tokens during a secret rotation
+	JWTPreviousSecrets []string `sensitive:"true"`
+
+	// JWTLeeway tolerates clock skew between the issuer and this service
+	// when checking exp, nbf and iat
+	JWTLeeway time.Duration
+
+	// Identity forwarded by an API gateway that already verified the JWT
+	AuthTrustGatewayHeaders bool
+	AuthTrustedProxies      []netip.Prefix
+	AuthUserIDHeader        string
+	AuthEmailHeader         string
+
+	// Password policy for new passwords
+	PasswordMinLength          int
+	PasswordRequireUpper       bool
+	PasswordRequireLower       bool
+	PasswordRequireDigit       bool
+	PasswordRequireSymbol      bool
+	PasswordBreachCheck        bool
+	PasswordBreachCheckURL     string `sensitive:"url"`
+	PasswordBreachCheckTimeout time.Duration
 	{{- endif }}
 
+	// API lifecycle
+	APIV1Sunset          time.Time
+	APIV1Deprecated      time.Time
+	APIV1DeprecationLink string
+
+	// Response serialization
+	JSONSnakeCase bool
+	JSONOmitEmpty bool
+	// JSONTimeFormat is rfc3339, unix, unix_ms or a Go time layout
+	JSONTimeFormat string
+
+	// Request binding
+	StrictJSONBinding bool
+
+	// MaxBodySize caps request bodies as sent, in bytes; routes can
+	// override it with middleware.RouteMaxBodySize
+	MaxBodySize int
+
+	// MaxDecompressedBodySize caps gzip-encoded request bodies once
+	// decompressed, in bytes
+	MaxDecompressedBodySize int
+
+	// Pagination policy for list endpoints
+	PaginationDefaultPageSize int
+	PaginationMaxPageSize     int
+	PaginationDefaultSort     string
+	PaginationMaxOffset       int
+
+	// HTTP router
+	RedirectTrailingSlash  bool
+	RedirectFixedPath      bool
+	HandleMethodNotAllowed bool
+	TrustedPlatform        string
+
+	// TrustedProxies may set X-Forwarded-For/X-Real-IP; from any other peer
+	// those headers are ignored and the client IP is the peer address
+	TrustedProxies []netip.Prefix
+
 	// Security
 	CORSOrigins []string
 	RateLimit   int
+	AdminToken  string `sensitive:"true"`
+
+	// Middleware toggles
+	EnableRequestLogging   bool
+	EnableCORS             bool
+	EnableRateLimit        bool
+	EnableSecurityHeaders  bool
+	EnableMetricsRecording bool
+
+	// AccessLogFormat is json, common or combined (Apache formats)
+	AccessLogFormat string
+
+	// AccessLogHeaders are request headers included in JSON access logs
+	AccessLogHeaders []string
+
+	// HSTS, opt-in and only sent over HTTPS
+	HSTSEnabled             bool
+	HSTSMaxAge              int
+	HSTSIncludeSubDomains   bool
+	HSTSPreload             bool
+	HSTSTrustForwardedProto bool
+
+	// Content-Security-Policy and Permissions-Policy headers; empty disables
+	ContentSecurityPolicy string
+	PermissionsPolicy     string
+
+	// Load shedding
+	MaxConcurrentRequests   int
+	ConcurrencyQueueTimeout time.Duration
+
+	// MaxOpenStreams caps concurrent streaming (SSE, WebSocket) connections
+	MaxOpenStreams int
+
+	// Multi-tenancy
+	TenantHeader string
+
+	// Webhooks
+	WebhookMaxAttempts    int
+	WebhookTimeout        time.Duration
+	WebhookInitialBackoff time.Duration
+	WebhookMaxBackoff     time.Duration
+	WebhookMaxConcurrency int
+
+	// Debugging
+	CaptureSampleRate float64
+	CaptureBufferSize int
+
+	// Outbound HTTP
+	HTTPClientTimeout       time.Duration
+	HTTPClientMaxRetries    int
+	HTTPClientMaxRetryAfter time.Duration
+
+	// Service-to-service tokens from an OAuth2 client credentials grant,
+	// attached to outbound calls when ServiceTokenURL is set
+	ServiceTokenURL           string `sensitive:"url"`
+	ServiceClientID           string
+	ServiceClientSecret       string `sensitive:"true"`
+	ServiceTokenScopes        []string
+	ServiceTokenRefreshBefore time.Duration
+	// ServiceTokenAudience lists the hosts the service token is sent to
+	ServiceTokenAudience []string
 
 	// Monitoring
 	MetricsPath string
 	HealthPath  string
+	// MetricsTenants are the tenants labeled on request metrics; any other
+	// tenant is counted as "other"
+	MetricsTenants []string
+
+	// SlowRequestThreshold is how long a request may take before it is
+	// logged at warn level (0 disables)
+	SlowRequestThreshold time.Duration
+
+	// Health checks
+	HealthCheckTimeout     time.Duration
+	HealthCacheTTL         time.Duration
+	HealthHTTPDependencies []HTTPDependency `sensitive:"url"`
+
+	// LivenessTimeout is how long a critical goroutine may go without a
+	// heartbeat before the liveness probe fails
+	LivenessTimeout time.Duration
 }
 
 func Load() (*Config, error) {
@@ -58,39 +307,507 @@ func Load() (*Config, error) {
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
 		ServiceName: getEnv("SERVICE_NAME", "{{ service_name }}"),
 
+		StartupTimeout: getEnvAsDuration("STARTUP_TIMEOUT", 60*time.Second),
+
+		RequestTimeout: getEnvAsDuration("REQUEST_TIMEOUT", 30*time.Second),
+
+		WarmUpEnabled:     getEnvAsBool("WARMUP_ENABLED", true),
+		WarmUpConnections: getEnvAsInt("WARMUP_CONNECTIONS", 5),
+		WarmUpTimeout:     getEnvAsDuration("WARMUP_TIMEOUT", 30*time.Second),
+
+		ShutdownDelay:   getEnvAsDuration("SHUTDOWN_DELAY", 5*time.Second),
+		ShutdownTimeout: getEnvAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		SyslogEnabled:  getEnvAsBool("SYSLOG_ENABLED", false),
+		SyslogNetwork:  getEnv("SYSLOG_NETWORK", ""),
+		SyslogAddress:  getEnv("SYSLOG_ADDRESS", ""),
+		SyslogFacility: getEnv("SYSLOG_FACILITY", "local0"),
+
+		LogAsync:      getEnvAsBool("LOG_ASYNC", false),
+		LogBufferSize: getEnvAsInt("LOG_BUFFER_SIZE", 1024),
+
 		{{- if include_database }}
-		DatabaseURL:      getEnv("DATABASE_URL", ""),
-		DatabaseHost:     getEnv("DATABASE_HOST", "localhost"),
-		DatabasePort:     getEnv("DATABASE_PORT", "5432"),
-		DatabaseUser:     getEnv("DATABASE_USER", "postgres"),
-		DatabasePassword: getEnv("DATABASE_PASSWORD", "password"),
-		DatabaseName:     getEnv("DATABASE_NAME", ""),
-		DatabaseSSLMode:  getEnv("DATABASE_SSL_MODE", "disable"),
+		DatabaseURL:       getEnv("DATABASE_URL", ""),
+		DatabaseHost:      getEnv("DATABASE_HOST", "localhost"),
+		DatabasePort:      getEnv("DATABASE_PORT", "5432"),
+		DatabaseUser:      getEnv("DATABASE_USER", "postgres"),
+		DatabasePassword:  getEnv("DATABASE_PASSWORD", "password"),
+		DatabaseName:      getEnv("DATABASE_NAME", ""),
+		DatabaseSSLMode:   getEnv("DATABASE_SSL_MODE", "disable"),
+		DatabaseBatchSize: getEnvAsInt("DATABASE_BATCH_SIZE", 500),
+
+		DatabaseQueryTimeout: getEnvAsDuration("DATABASE_QUERY_TIMEOUT", 5*time.Second),
+
+		DatabaseTxMaxRetries:   getEnvAsInt("DATABASE_TX_MAX_RETRIES", 3),
+		DatabaseTxRetryBackoff: getEnvAsDuration("DATABASE_TX_RETRY_BACKOFF", 50*time.Millisecond),
+
+		DatabaseTablePrefix:    getEnv("DATABASE_TABLE_PREFIX", ""),
+		DatabaseSingularTables: getEnvAsBool("DATABASE_SINGULAR_TABLES", false),
+
+		DatabaseAutoMigrate: getEnvAsBool("DATABASE_AUTO_MIGRATE", false),
+
+		DatabaseMigrationGracePeriod: getEnvAsDuration("DATABASE_MIGRATION_GRACE_PERIOD", 20*time.Second),
+
+		DatabaseRequiredExtensions: getEnvAsList("DATABASE_REQUIRED_EXTENSIONS", nil),
+
+		DatabaseReplicaURLs:    getEnvAsList("DATABASE_REPLICA_URLS", nil),
+		DatabaseRegion:         getEnv("DATABASE_REGION", ""),
+		DatabaseReadPreference: getEnv("DATABASE_READ_PREFERENCE", "nearest"),
 		{{- endif }}
 
 		{{- if include_redis }}
 		RedisURL:      getEnv("REDIS_URL", ""),
 		RedisHost:     getEnv("REDIS_HOST", "localhost"),
 		RedisPort:     getEnv("REDIS_PORT", "6379"),
+		RedisUsername: getEnv("REDIS_USERNAME", ""),
 		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 		RedisDB:       getEnvAsInt("REDIS_DB", 0),
+
+		RedisClusterAddrs:        getEnvAsList("REDIS_CLUSTER_ADDRS", nil),
+		RedisClusterReplicaReads: getEnvAsBool("REDIS_CLUSTER_REPLICA_READS", false),
+
+		RedisCompressionThreshold: getEnvAsInt("REDIS_COMPRESSION_THRESHOLD", 0),
+		RedisKeyPrefix:            getEnv("REDIS_KEY_PREFIX", ""),
+		RedisTTLJitter:            getEnvAsFloat("REDIS_TTL_JITTER", 0),
+
+		RedisDialTimeout:  getEnvAsDuration("REDIS_DIAL_TIMEOUT", 5*time.Second),
+		RedisReadTimeout:  getEnvAsDuration("REDIS_READ_TIMEOUT", 3*time.Second),
+		RedisWriteTimeout: getEnvAsDuration("REDIS_WRITE_TIMEOUT", 3*time.Second),
+
+		RedisOperationTimeout: getEnvAsDuration("REDIS_OPERATION_TIMEOUT", 5*time.Second),
+
+		NonceTTL: getEnvAsDuration("NONCE_TTL", 5*time.Minute),
+
+		RedisStateKeyPrefix: getEnv("REDIS_STATE_KEY_PREFIX", "state:"),
+
+		RedisMemoryWarnRatio: getEnvAsFloat("REDIS_MEMORY_WARN_RATIO", 0.9),
 		{{- endif }}
 
 		{{- if include_auth }}
 		JWTSecret:    getEnv("JWT_SECRET", "your-secret-key"),
 		JWTExpiresIn: getEnv("JWT_EXPIRES_IN", "24h"),
+
+		JWTPreviousSecrets: getEnvAsList("JWT_PREVIOUS_SECRETS", nil),
+		JWTLeeway:          getEnvAsDuration("JWT_LEEWAY", 30*time.Second),
+
+		AuthTrustGatewayHeaders: getEnvAsBool("AUTH_TRUST_GATEWAY_HEADERS", false),
+		AuthUserIDHeader:        getEnv("AUTH_USER_ID_HEADER", "X-User-Id"),
+		AuthEmailHeader:         getEnv("AUTH_EMAIL_HEADER", "X-User-Email"),
+
+		PasswordMinLength:          getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:       getEnvAsBool("PASSWORD_REQUIRE_UPPER", false),
+		PasswordRequireLower:       getEnvAsBool("PASSWORD_REQUIRE_LOWER", false),
+		PasswordRequireDigit:       getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol:      getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+		PasswordBreachCheck:        getEnvAsBool("PASSWORD_BREACH_CHECK", false),
+		PasswordBreachCheckURL:     getEnv("PASSWORD_BREACH_CHECK_URL", "https://api.pwnedpasswords.com/range/"),
+		PasswordBreachCheckTimeout: getEnvAsDuration("PASSWORD_BREACH_CHECK_TIMEOUT", 2*time.Second),
 		{{- endif }}
 
-		CORSOrigins: []string{getEnv("CORS_ORIGINS", "*")},
+		JSONSnakeCase: getEnvAsBool("JSON_SNAKE_CASE", false),
+		JSONOmitEmpty: getEnvAsBool("JSON_OMIT_EMPTY", false),
+
+		JSONTimeFormat: getEnv("JSON_TIME_FORMAT", "rfc3339"),
+
+		StrictJSONBinding: getEnvAsBool("STRICT_JSON_BINDING", false),
+
+		MaxBodySize:             getEnvAsInt("MAX_BODY_SIZE", 10<<20),
+		MaxDecompressedBodySize: getEnvAsInt("MAX_DECOMPRESSED_BODY_SIZE", 10<<20),
+
+		PaginationDefaultPageSize: getEnvAsInt("PAGINATION_DEFAULT_PAGE_SIZE", 20),
+		PaginationMaxPageSize:     getEnvAsInt("PAGINATION_MAX_PAGE_SIZE", 100),
+		PaginationDefaultSort:     getEnv("PAGINATION_DEFAULT_SORT", ""),
+		PaginationMaxOffset:       getEnvAsInt("PAGINATION_MAX_OFFSET", 10000),
+
+		RedirectTrailingSlash:  getEnvAsBool("REDIRECT_TRAILING_SLASH", true),
+		RedirectFixedPath:      getEnvAsBool("REDIRECT_FIXED_PATH", false),
+		HandleMethodNotAllowed: getEnvAsBool("HANDLE_METHOD_NOT_ALLOWED", false),
+		TrustedPlatform:        getEnv("TRUSTED_PLATFORM", ""),
+
+		CORSOrigins: getEnvAsList("CORS_ORIGINS", []string{"*"}),
 		RateLimit:   getEnvAsInt("RATE_LIMIT", 100),
+		AdminToken:  getEnv("ADMIN_TOKEN", ""),
+
+		EnableRequestLogging:   getEnvAsBool("ENABLE_REQUEST_LOGGING", true),
+		EnableCORS:             getEnvAsBool("ENABLE_CORS", true),
+		EnableRateLimit:        getEnvAsBool("ENABLE_RATE_LIMIT", true),
+		EnableSecurityHeaders:  getEnvAsBool("ENABLE_SECURITY_HEADERS", true),
+		EnableMetricsRecording: getEnvAsBool("ENABLE_METRICS_RECORDING", true),
+
+		AccessLogFormat:  getEnv("ACCESS_LOG_FORMAT", "json"),
+		AccessLogHeaders: getEnvAsList("ACCESS_LOG_HEADERS", []string{"X-Forwarded-For", "Referer"}),
+
+		HSTSEnabled:             getEnvAsBool("HSTS_ENABLED", false),
+		HSTSMaxAge:              getEnvAsInt("HSTS_MAX_AGE", 31536000),
+		HSTSIncludeSubDomains:   getEnvAsBool("HSTS_INCLUDE_SUBDOMAINS", true),
+		HSTSPreload:             getEnvAsBool("HSTS_PRELOAD", false),
+		HSTSTrustForwardedProto: getEnvAsBool("HSTS_TRUST_FORWARDED_PROTO", false),
+
+		ContentSecurityPolicy: getEnvAllowEmpty("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+		PermissionsPolicy:     getEnvAllowEmpty("PERMISSIONS_POLICY", "camera=(), microphone=(), geolocation=(), payment=(), usb=()"),
+
+		MaxConcurrentRequests:   getEnvAsInt("MAX_CONCURRENT_REQUESTS", 0),
+		ConcurrencyQueueTimeout: getEnvAsDuration("CONCURRENCY_QUEUE_TIMEOUT", 100*time.Millisecond),
+
+		MaxOpenStreams: getEnvAsInt("MAX_OPEN_STREAMS", 1000),
+
+		TenantHeader: getEnv("TENANT_HEADER", "X-Tenant-ID"),
 
-		MetricsPath: getEnv("METRICS_PATH", "/metrics"),
-		HealthPath:  getEnv("HEALTH_PATH", "/health"),
+		WebhookMaxAttempts:    getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookTimeout:        getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookInitialBackoff: getEnvAsDuration("WEBHOOK_INITIAL_BACKOFF", time.Second),
+		WebhookMaxBackoff:     getEnvAsDuration("WEBHOOK_MAX_BACKOFF", time.Minute),
+		WebhookMaxConcurrency: getEnvAsInt("WEBHOOK_MAX_CONCURRENCY", 4),
+
+		CaptureSampleRate: getEnvAsFloat("CAPTURE_SAMPLE_RATE", 0),
+		CaptureBufferSize: getEnvAsInt("CAPTURE_BUFFER_SIZE", 100),
+
+		HTTPClientTimeout:       getEnvAsDuration("HTTP_CLIENT_TIMEOUT", 10*time.Second),
+		HTTPClientMaxRetries:    getEnvAsInt("HTTP_CLIENT_MAX_RETRIES", 2),
+		HTTPClientMaxRetryAfter: getEnvAsDuration("HTTP_CLIENT_MAX_RETRY_AFTER", 10*time.Second),
+
+		ServiceTokenURL:           getEnv("SERVICE_TOKEN_URL", ""),
+		ServiceClientID:           getEnv("SERVICE_CLIENT_ID", ""),
+		ServiceClientSecret:       getEnv("SERVICE_CLIENT_SECRET", ""),
+		ServiceTokenScopes:        getEnvAsList("SERVICE_TOKEN_SCOPES", nil),
+		ServiceTokenRefreshBefore: getEnvAsDuration("SERVICE_TOKEN_REFRESH_BEFORE", time.Minute),
+		ServiceTokenAudience:      getEnvAsList("SERVICE_TOKEN_AUDIENCE", nil),
+
+		MetricsPath:    getEnv("METRICS_PATH", "/metrics"),
+		HealthPath:     getEnv("HEALTH_PATH", "/health"),
+		MetricsTenants: getEnvAsList("METRICS_TENANTS", nil),
+
+		SlowRequestThreshold: getEnvAsDuration("SLOW_REQUEST_THRESHOLD", time.Second),
+
+		HealthCheckTimeout: getEnvAsDuration("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		HealthCacheTTL:     getEnvAsDuration("HEALTH_CACHE_TTL", 2*time.Second),
+
+		LivenessTimeout: getEnvAsDuration("LIVENESS_TIMEOUT", time.Minute),
+	}
+
+	dependencies, err := parseHTTPDependencies(getEnv("HEALTH_HTTP_DEPENDENCIES", ""))
+	if err != nil {
+		return nil, err
+	}
+	cfg.HealthHTTPDependencies = dependencies
+
+	trustedProxies, err := parsePrefixes(getEnv("TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	cfg.TrustedProxies = trustedProxies
+
+	{{- if include_auth }}
+
+	proxies, err := parsePrefixes(getEnv("AUTH_TRUSTED_PROXIES", ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid AUTH_TRUSTED_PROXIES: %w", err)
+	}
+	cfg.AuthTrustedProxies = proxies
+	{{- endif }}
+
+	origins, err := normalizeOrigins(cfg.CORSOrigins)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CORS_ORIGINS: %w", err)
+	}
+	cfg.CORSOrigins = origins
+
+	if sunset := getEnv("API_V1_SUNSET", ""); sunset != "" {
+		date, err := time.Parse(time.DateOnly, sunset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API_V1_SUNSET %q, expected YYYY-MM-DD: %w", sunset, err)
+		}
+		cfg.APIV1Sunset = date
+	}
+	if deprecated := getEnv("API_V1_DEPRECATED", ""); deprecated != "" {
+		date, err := time.Parse(time.DateOnly, deprecated)
+		if err != nil {
+			return nil, fmt.Errorf("invalid API_V1_DEPRECATED %q, expected YYYY-MM-DD: %w", deprecated, err)
+		}
+		cfg.APIV1Deprecated = date
+	}
+	cfg.APIV1DeprecationLink = getEnv("API_V1_DEPRECATION_LINK", "")
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
 	return cfg, nil
 }
 
+// Validate checks for settings that would misbehave at runtime
+func (c *Config) Validate() error {
+	if c.RateLimit < 0 {
+		return fmt.Errorf("RATE_LIMIT must not be negative, got %d (use 0 to disable rate limiting)", c.RateLimit)
+	}
+
+	if c.StartupTimeout <= 0 {
+		return fmt.Errorf("STARTUP_TIMEOUT must be positive, got %s", c.StartupTimeout)
+	}
+
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must not be negative, got %s (use 0 to disable)", c.RequestTimeout)
+	}
+
+	if c.WarmUpEnabled {
+		if c.WarmUpConnections < 0 {
+			return fmt.Errorf("WARMUP_CONNECTIONS must not be negative, got %d", c.WarmUpConnections)
+		}
+		if c.WarmUpTimeout <= 0 {
+			return fmt.Errorf("WARMUP_TIMEOUT must be positive, got %s", c.WarmUpTimeout)
+		}
+	}
+
+	if !c.APIV1Deprecated.IsZero() && !c.APIV1Sunset.IsZero() && c.APIV1Deprecated.After(c.APIV1Sunset) {
+		return fmt.Errorf("API_V1_DEPRECATED must not be after API_V1_SUNSET, got %s and %s",
+			c.APIV1Deprecated.Format(time.DateOnly), c.APIV1Sunset.Format(time.DateOnly))
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be positive, got %s", c.ShutdownTimeout)
+	}
+
+	// Otherwise no time would be left to finish in-flight requests
+	if c.ShutdownDelay < 0 || c.ShutdownDelay >= c.ShutdownTimeout {
+		return fmt.Errorf("SHUTDOWN_DELAY must be in [0, SHUTDOWN_TIMEOUT), got %s", c.ShutdownDelay)
+	}
+
+	if c.LivenessTimeout <= 0 {
+		return fmt.Errorf("LIVENESS_TIMEOUT must be positive, got %s", c.LivenessTimeout)
+	}
+
+	if c.SyslogEnabled {
+		if c.SyslogNetwork != "" && c.SyslogNetwork != "udp" && c.SyslogNetwork != "tcp" {
+			return fmt.Errorf("SYSLOG_NETWORK must be udp, tcp or empty for the local daemon, got %q", c.SyslogNetwork)
+		}
+		if c.SyslogNetwork != "" && c.SyslogAddress == "" {
+			return fmt.Errorf("SYSLOG_ADDRESS is required when SYSLOG_NETWORK is set")
+		}
+		if !syslogFacilities[strings.ToLower(c.SyslogFacility)] {
+			return fmt.Errorf("SYSLOG_FACILITY must be a syslog facility such as local0 or daemon, got %q", c.SyslogFacility)
+		}
+	}
+
+	if c.LogAsync && c.LogBufferSize <= 0 {
+		return fmt.Errorf("LOG_BUFFER_SIZE must be positive, got %d", c.LogBufferSize)
+	}
+
+	{{- if include_database }}
+
+	if c.DatabaseTxMaxRetries < 0 {
+		return fmt.Errorf("DATABASE_TX_MAX_RETRIES must not be negative, got %d (use 0 to disable retries)", c.DatabaseTxMaxRetries)
+	}
+
+	if c.DatabaseMigrationGracePeriod < 0 {
+		return fmt.Errorf("DATABASE_MIGRATION_GRACE_PERIOD must not be negative, got %s", c.DatabaseMigrationGracePeriod)
+	}
+
+	if c.DatabaseReadPreference != "nearest" && c.DatabaseReadPreference != "primary-preferred" {
+		return fmt.Errorf("DATABASE_READ_PREFERENCE must be nearest or primary-preferred, got %q", c.DatabaseReadPreference)
+	}
+	{{- endif }}
+
+	{{- if include_redis }}
+
+	// Cluster mode has no numbered databases
+	if len(c.RedisClusterAddrs) > 0 && c.RedisDB != 0 {
+		return fmt.Errorf("REDIS_DB must be 0 with REDIS_CLUSTER_ADDRS, got %d", c.RedisDB)
+	}
+
+	if c.RedisMemoryWarnRatio <= 0 || c.RedisMemoryWarnRatio > 1 {
+		return fmt.Errorf("REDIS_MEMORY_WARN_RATIO must be in (0, 1], got %g", c.RedisMemoryWarnRatio)
+	}
+
+	if c.RedisTTLJitter < 0 || c.RedisTTLJitter >= 1 {
+		return fmt.Errorf("REDIS_TTL_JITTER must be in [0, 1), got %g", c.RedisTTLJitter)
+	}
+
+	if c.RedisDialTimeout <= 0 || c.RedisReadTimeout <= 0 || c.RedisWriteTimeout <= 0 {
+		return fmt.Errorf("REDIS_DIAL_TIMEOUT, REDIS_READ_TIMEOUT and REDIS_WRITE_TIMEOUT must be positive")
+	}
+
+	if c.NonceTTL <= 0 {
+		return fmt.Errorf("NONCE_TTL must be positive, got %s", c.NonceTTL)
+	}
+	{{- endif }}
+
+	if c.HSTSEnabled && c.HSTSMaxAge < 0 {
+		return fmt.Errorf("HSTS_MAX_AGE must not be negative, got %d", c.HSTSMaxAge)
+	}
+
+	// Browsers' preload lists only accept long-lived, subdomain-wide policies
+	if c.HSTSEnabled && c.HSTSPreload && (!c.HSTSIncludeSubDomains || c.HSTSMaxAge < 31536000) {
+		return fmt.Errorf("HSTS_PRELOAD requires HSTS_INCLUDE_SUBDOMAINS and HSTS_MAX_AGE of at least 31536000")
+	}
+
+	{{- if include_auth }}
+
+	if err := c.validateJWTSecrets(); err != nil {
+		return err
+	}
+
+	if c.JWTLeeway < 0 {
+		return fmt.Errorf("JWT_LEEWAY must not be negative, got %s (use 0 for no leeway)", c.JWTLeeway)
+	}
+
+	// Gateway headers are spoofable unless restricted to known proxies
+	if c.AuthTrustGatewayHeaders && len(c.AuthTrustedProxies) == 0 {
+		return fmt.Errorf("AUTH_TRUST_GATEWAY_HEADERS requires AUTH_TRUSTED_PROXIES")
+	}
+
+	// Passwords longer than 128 characters are rejected at binding
+	if c.PasswordMinLength < 1 || c.PasswordMinLength > 128 {
+		return fmt.Errorf("PASSWORD_MIN_LENGTH must be between 1 and 128, got %d", c.PasswordMinLength)
+	}
+
+	if c.PasswordBreachCheck && c.PasswordBreachCheckTimeout <= 0 {
+		return fmt.Errorf("PASSWORD_BREACH_CHECK_TIMEOUT must be positive, got %s", c.PasswordBreachCheckTimeout)
+	}
+	{{- endif }}
+
+	if c.MaxConcurrentRequests < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_REQUESTS must not be negative, got %d (use 0 for no limit)", c.MaxConcurrentRequests)
+	}
+
+	if c.MaxOpenStreams < 0 {
+		return fmt.Errorf("MAX_OPEN_STREAMS must not be negative, got %d (use 0 for no limit)", c.MaxOpenStreams)
+	}
+
+	switch c.AccessLogFormat {
+	case "json", "common", "combined":
+	default:
+		return fmt.Errorf("ACCESS_LOG_FORMAT must be json, common or combined, got %q", c.AccessLogFormat)
+	}
+
+	// Anything but the named formats is a Go time layout, which must contain
+	// at least one layout element to be meaningful
+	switch c.JSONTimeFormat {
+	case "rfc3339", "unix", "unix_ms":
+	default:
+		if time.Unix(0, 0).UTC().Format(c.JSONTimeFormat) == c.JSONTimeFormat {
+			return fmt.Errorf("JSON_TIME_FORMAT must be rfc3339, unix, unix_ms or a Go time layout, got %q", c.JSONTimeFormat)
+		}
+	}
+
+	if c.HTTPClientMaxRetries < 0 {
+		return fmt.Errorf("HTTP_CLIENT_MAX_RETRIES must not be negative, got %d (use 0 to disable)", c.HTTPClientMaxRetries)
+	}
+
+	if c.HTTPClientMaxRetryAfter <= 0 {
+		return fmt.Errorf("HTTP_CLIENT_MAX_RETRY_AFTER must be positive, got %s", c.HTTPClientMaxRetryAfter)
+	}
+
+	if c.ServiceTokenURL != "" && (c.ServiceClientID == "" || c.ServiceClientSecret == "") {
+		return fmt.Errorf("SERVICE_TOKEN_URL requires SERVICE_CLIENT_ID and SERVICE_CLIENT_SECRET")
+	}
+
+	if c.ServiceTokenURL != "" && len(c.ServiceTokenAudience) == 0 {
+		return fmt.Errorf("SERVICE_TOKEN_URL requires SERVICE_TOKEN_AUDIENCE, the hosts the token is sent to")
+	}
+
+	if c.ServiceTokenRefreshBefore < 0 {
+		return fmt.Errorf("SERVICE_TOKEN_REFRESH_BEFORE must not be negative, got %s", c.ServiceTokenRefreshBefore)
+	}
+
+	if c.SlowRequestThreshold < 0 {
+		return fmt.Errorf("SLOW_REQUEST_THRESHOLD must not be negative, got %s (use 0 to disable)", c.SlowRequestThreshold)
+	}
+
+	if c.MaxBodySize <= 0 {
+		return fmt.Errorf("MAX_BODY_SIZE must be positive, got %d", c.MaxBodySize)
+	}
+
+	if c.MaxDecompressedBodySize <= 0 {
+		return fmt.Errorf("MAX_DECOMPRESSED_BODY_SIZE must be positive, got %d", c.MaxDecompressedBodySize)
+	}
+
+	if c.PaginationDefaultPageSize < 1 || c.PaginationMaxPageSize < 1 {
+		return fmt.Errorf("PAGINATION_DEFAULT_PAGE_SIZE and PAGINATION_MAX_PAGE_SIZE must be positive, got %d and %d",
+			c.PaginationDefaultPageSize, c.PaginationMaxPageSize)
+	}
+
+	if c.PaginationDefaultPageSize > c.PaginationMaxPageSize {
+		return fmt.Errorf("PAGINATION_DEFAULT_PAGE_SIZE (%d) must not exceed PAGINATION_MAX_PAGE_SIZE (%d)",
+			c.PaginationDefaultPageSize, c.PaginationMaxPageSize)
+	}
+
+	if c.PaginationMaxOffset < 0 {
+		return fmt.Errorf("PAGINATION_MAX_OFFSET must not be negative, got %d (use 0 to disable)", c.PaginationMaxOffset)
+	}
+
+	return nil
+}
+
+{{- if include_auth }}
+
+// minJWTSecretLength is the shortest HS256 secret accepted in production;
+// RFC 7518 requires a key at least as long as the 256-bit hash output
+const minJWTSecretLength = 32
+
+// validateJWTSecrets rejects blank secrets, with which HS256 tokens are
+// trivially forgeable, and in production secrets shorter than 32 bytes.
+// Previous secrets still verify tokens, so they are held to the same rules.
+func (c *Config) validateJWTSecrets() error {
+	if strings.TrimSpace(c.JWTSecret) == "" {
+		return fmt.Errorf("JWT_SECRET must not be empty or whitespace")
+	}
+	if c.Environment != "production" {
+		return nil
+	}
+
+	if len(c.JWTSecret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d bytes in production, got %d", minJWTSecretLength, len(c.JWTSecret))
+	}
+	for i, secret := range c.JWTPreviousSecrets {
+		if len(secret) < minJWTSecretLength {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS entry %d must be at least %d bytes in production, got %d", i+1, minJWTSecretLength, len(secret))
+		}
+	}
+	return nil
+}
+{{- endif }}
+
+// parseHTTPDependencies parses a comma-separated list of name=url entries,
+// each optionally followed by ";"-separated options: "optional" makes the
+// dependency non-critical, and "head" or "get" sets the probe's method
+// (GET by default).
+func parseHTTPDependencies(value string) ([]HTTPDependency, error) {
+	var dependencies []HTTPDependency
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ";")
+		name, url, ok := strings.Cut(parts[0], "=")
+		if !ok || name == "" || url == "" {
+			return nil, fmt.Errorf("invalid HEALTH_HTTP_DEPENDENCIES entry %q, expected name=url", entry)
+		}
+
+		dependency := HTTPDependency{
+			Name:     strings.TrimSpace(name),
+			URL:      strings.TrimSpace(url),
+			Method:   http.MethodGet,
+			Critical: true,
+		}
+		for _, option := range parts[1:] {
+			switch strings.ToLower(strings.TrimSpace(option)) {
+			case "optional":
+				dependency.Critical = false
+			case "get":
+				dependency.Method = http.MethodGet
+			case "head":
+				dependency.Method = http.MethodHead
+			default:
+				return nil, fmt.Errorf("invalid HEALTH_HTTP_DEPENDENCIES option %q for %s, expected optional, get or head", option, dependency.Name)
+			}
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -98,6 +815,88 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvAsList parses a comma-separated variable, trimming spaces and
+// dropping empty entries. A variable that is set but empty yields an empty
+// list rather than the default.
+func getEnvAsList(name string, defaultValue []string) []string {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return defaultValue
+	}
+	return compactList(strings.Split(value, ","))
+}
+
+// normalizeOrigins validates CORS origins and rewrites them in the form
+// browsers send in the Origin header: lowercase scheme://host[:port] with no
+// trailing slash. "*" is kept as is.
+func normalizeOrigins(origins []string) ([]string, error) {
+	normalized := make([]string, 0, len(origins))
+	for _, origin := range compactList(origins) {
+		if origin == "*" {
+			normalized = append(normalized, origin)
+			continue
+		}
+
+		u, err := url.Parse(strings.TrimSuffix(origin, "/"))
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			return nil, fmt.Errorf("%q is not an origin, expected scheme://host[:port]", origin)
+		}
+		normalized = append(normalized, strings.ToLower(u.Scheme+"://"+u.Host))
+	}
+	return normalized, nil
+}
+
+// parsePrefixes parses a comma-separated list of CIDRs or single IPs, IPv4
+// or IPv6. IPv4-mapped IPv6 entries (::ffff:10.0.0.1) are stored as plain
+// IPv4 so they match however the peer address is written.
+func parsePrefixes(value string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range compactList(strings.Split(value, ",")) {
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, err
+			}
+			if addr.Zone() != "" {
+				return nil, fmt.Errorf("%s: zoned addresses are not supported", entry)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// compactList trims spaces from every item and drops empty ones
+func compactList(items []string) []string {
+	compacted := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			compacted = append(compacted, item)
+		}
+	}
+	return compacted
+}
+
+// getEnvAllowEmpty is like getEnv, except that a variable set to an empty
+// value yields "" rather than the default
+func getEnvAllowEmpty(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsInt(name string, defaultValue int) int {
 	valueStr := getEnv(name, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
@@ -105,3 +904,27 @@ func getEnvAsInt(name string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsFloat(name string, defaultValue float64) float64 {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsBool(name string, defaultValue bool) bool {
+	valueStr := getEnv(name, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsDuration(name string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(name, "")
+	if value, err := time.ParseDuration(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
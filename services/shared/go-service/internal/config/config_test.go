@@ -0,0 +1,253 @@
+package config
+
+import (
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int
+		wantErr string
+	}{
+		{"positive", "100", 100, ""},
+		{"zero disables rate limiting", "0", 0, ""},
+		{"negative", "-5", 0, "RATE_LIMIT must not be negative, got -5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RATE_LIMIT", tt.value)
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Load = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if cfg.RateLimit != tt.want {
+				t.Errorf("RateLimit = %d, want %d", cfg.RateLimit, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		origins []string
+		want    []string
+		wantErr bool
+	}{
+		{"empty list denies every origin", nil, []string{}, false},
+		{"blank entries dropped", []string{"", "  "}, []string{}, false},
+		{"whitespace trimmed", []string{"  https://app.example.com  "}, []string{"https://app.example.com"}, false},
+		{"lowercased without trailing slash", []string{"HTTPS://App.Example.com:8443/"}, []string{"https://app.example.com:8443"}, false},
+		{"wildcard kept", []string{" * "}, []string{"*"}, false},
+		{"path rejected", []string{"https://app.example.com/login"}, nil, true},
+		{"missing scheme rejected", []string{"app.example.com"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeOrigins(tt.origins)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("normalizeOrigins(%q) = %q, want an error", tt.origins, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeOrigins(%q): %v", tt.origins, err)
+			}
+			if got == nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeOrigins(%q) = %#v, want %#v", tt.origins, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadCORSOrigins(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"comma-separated", "https://a.example.com,https://b.example.com", []string{"https://a.example.com", "https://b.example.com"}},
+		{"spaces after commas", "https://a.example.com, https://b.example.com/", []string{"https://a.example.com", "https://b.example.com"}},
+		{"trailing comma", "https://a.example.com,", []string{"https://a.example.com"}},
+		{"empty denies every origin", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CORS_ORIGINS", tt.value)
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.CORSOrigins, tt.want) {
+				t.Errorf("CORSOrigins = %#v, want %#v", cfg.CORSOrigins, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadSecurityPolicies(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.ContentSecurityPolicy != "default-src 'none'; frame-ancestors 'none'" {
+		t.Errorf("default ContentSecurityPolicy = %q", cfg.ContentSecurityPolicy)
+	}
+	if cfg.PermissionsPolicy != "camera=(), microphone=(), geolocation=(), payment=(), usb=()" {
+		t.Errorf("default PermissionsPolicy = %q", cfg.PermissionsPolicy)
+	}
+
+	// Set but empty turns the header off rather than restoring the default
+	t.Setenv("CONTENT_SECURITY_POLICY", "")
+	t.Setenv("PERMISSIONS_POLICY", "")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.ContentSecurityPolicy != "" || cfg.PermissionsPolicy != "" {
+		t.Errorf("policies = %q, %q, want both empty", cfg.ContentSecurityPolicy, cfg.PermissionsPolicy)
+	}
+}
+
+func TestParsePrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		want     []string
+		contains []string
+		excludes []string
+		wantErr  bool
+	}{
+		{
+			name:     "IPv4 address and CIDR",
+			value:    "10.0.0.1, 192.168.0.0/16",
+			want:     []string{"10.0.0.1/32", "192.168.0.0/16"},
+			contains: []string{"10.0.0.1", "192.168.44.7", "::ffff:192.168.44.7"},
+			excludes: []string{"10.0.0.2", "192.169.0.1"},
+		},
+		{
+			name:     "IPv6 address and CIDR",
+			value:    "2001:db8::1,fd00::/8",
+			want:     []string{"2001:db8::1/128", "fd00::/8"},
+			contains: []string{"2001:db8::1", "fd12:3456::1"},
+			excludes: []string{"2001:db8::2", "fe80::1"},
+		},
+		{
+			name:     "host bits masked",
+			value:    "10.1.2.3/8",
+			want:     []string{"10.0.0.0/8"},
+			contains: []string{"10.200.0.1"},
+		},
+		{
+			name:     "IPv4-mapped entries stored as IPv4",
+			value:    "::ffff:10.0.0.1,::ffff:172.16.0.0/108",
+			want:     []string{"10.0.0.1/32", "172.16.0.0/12"},
+			contains: []string{"10.0.0.1", "172.31.255.255"},
+		},
+		{name: "empty", value: " , ", want: []string{}},
+		{name: "zoned address", value: "fe80::1%eth0", wantErr: true},
+		{name: "malformed CIDR", value: "10.0.0.0/33", wantErr: true},
+		{name: "not an address", value: "proxy.internal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefixes, err := parsePrefixes(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePrefixes(%q) = %v, want an error", tt.value, prefixes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePrefixes(%q): %v", tt.value, err)
+			}
+
+			got := make([]string, len(prefixes))
+			for i, prefix := range prefixes {
+				got[i] = prefix.String()
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePrefixes(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+
+			// Peers are matched the way GatewayOptions.trusts does, unmapped
+			member := func(ip string) bool {
+				addr := netip.MustParseAddr(ip).Unmap()
+				for _, prefix := range prefixes {
+					if prefix.Contains(addr) {
+						return true
+					}
+				}
+				return false
+			}
+			for _, ip := range tt.contains {
+				if !member(ip) {
+					t.Errorf("%s not matched by %v", ip, got)
+				}
+			}
+			for _, ip := range tt.excludes {
+				if member(ip) {
+					t.Errorf("%s matched by %v", ip, got)
+				}
+			}
+		})
+	}
+}
+{{- if include_auth }}
+
+func TestValidateJWTSecrets(t *testing.T) {
+	strong := strings.Repeat("k", minJWTSecretLength)
+	short := strings.Repeat("k", minJWTSecretLength-1)
+
+	tests := []struct {
+		name        string
+		environment string
+		secret      string
+		previous    []string
+		wantErr     string
+	}{
+		{"empty", "development", "", nil, "JWT_SECRET must not be empty or whitespace"},
+		{"whitespace", "development", " \t\n", nil, "JWT_SECRET must not be empty or whitespace"},
+		{"whitespace in production", "production", strings.Repeat(" ", minJWTSecretLength), nil, "JWT_SECRET must not be empty or whitespace"},
+		{"short outside production", "development", "dev-secret", nil, ""},
+		{"short in production", "production", short, nil, "JWT_SECRET must be at least 32 bytes in production, got 31"},
+		{"short previous secret in production", "production", strong, []string{strong, short}, "JWT_PREVIOUS_SECRETS entry 2 must be at least 32 bytes"},
+		{"acceptable in production", "production", strong, []string{strong}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{Environment: tt.environment, JWTSecret: tt.secret, JWTPreviousSecrets: tt.previous}
+
+			err := cfg.validateJWTSecrets()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateJWTSecrets: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateJWTSecrets = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+{{- endif }}
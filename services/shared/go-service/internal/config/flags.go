@@ -0,0 +1,71 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// configFlag is a command-line flag overriding an environment variable
+type configFlag struct {
+	name  string
+	env   string
+	usage string
+	// duration flags are parsed as time.Duration, so typos are rejected
+	// instead of Load silently falling back to the default
+	duration bool
+}
+
+// configFlags are the settings that can be given on the command line
+var configFlags = []configFlag{
+	{name: "port", env: "PORT", usage: "port to listen on"},
+	{name: "environment", env: "ENVIRONMENT", usage: "deployment environment"},
+	{name: "service-name", env: "SERVICE_NAME", usage: "service name"},
+	{name: "log-level", env: "LOG_LEVEL", usage: "log level (debug/info/warn/error)"},
+	{name: "request-timeout", env: "REQUEST_TIMEOUT", usage: "overall time budget of an API request", duration: true},
+	{name: "shutdown-timeout", env: "SHUTDOWN_TIMEOUT", usage: "budget for the whole shutdown", duration: true},
+	{{- if include_database }}
+	{name: "database-url", env: "DATABASE_URL", usage: "database URL; prefer the environment for credentials, flags are visible in the process list"},
+	{{- endif }}
+	{{- if include_redis }}
+	{name: "redis-url", env: "REDIS_URL", usage: "Redis URL; prefer the environment for credentials, flags are visible in the process list"},
+	{{- endif }}
+}
+
+// RegisterFlags defines a flag on fs for each setting in configFlags. Call
+// ApplyFlags once fs has been parsed.
+func RegisterFlags(fs *flag.FlagSet) {
+	for _, f := range configFlags {
+		usage := fmt.Sprintf("%s (overrides %s)", f.usage, f.env)
+		if f.duration {
+			fs.Duration(f.name, 0, usage)
+			continue
+		}
+		fs.String(f.name, "", usage)
+	}
+}
+
+// ApplyFlags makes the flags set on the command line override their
+// environment variables for Load, so settings take precedence in the order
+// flags, environment, .env file, defaults. Flags that weren't set leave the
+// environment untouched.
+func ApplyFlags(fs *flag.FlagSet) error {
+	envByFlag := make(map[string]string, len(configFlags))
+	for _, f := range configFlags {
+		envByFlag[f.name] = f.env
+	}
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		env, ok := envByFlag[f.Name]
+		if !ok || err != nil {
+			return
+		}
+
+		// Duration flags print as time.ParseDuration accepts, e.g. "1m30s"
+		if setErr := os.Setenv(env, f.Value.String()); setErr != nil {
+			err = fmt.Errorf("failed to apply --%s: %w", f.Name, setErr)
+		}
+	})
+	return err
+}
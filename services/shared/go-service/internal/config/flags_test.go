@@ -0,0 +1,55 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestApplyFlags(t *testing.T) {
+	// Registered with t.Setenv so ApplyFlags' changes are undone
+	t.Setenv("PORT", "8080")
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("SHUTDOWN_TIMEOUT", "30s")
+	t.Setenv("REQUEST_TIMEOUT", "10s")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Bool("selftest", false, "not a config flag")
+	RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"--port", "9090", "--shutdown-timeout", "1m30s", "--selftest"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if err := ApplyFlags(fs); err != nil {
+		t.Fatalf("ApplyFlags: %v", err)
+	}
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Port != "9090" {
+		t.Errorf("Port = %q, want the flag's 9090", cfg.Port)
+	}
+	if cfg.ShutdownTimeout != 90*time.Second {
+		t.Errorf("ShutdownTimeout = %s, want the flag's 1m30s", cfg.ShutdownTimeout)
+	}
+	if cfg.LogLevel != "warn" {
+		t.Errorf("LogLevel = %q, want the environment's warn without a flag", cfg.LogLevel)
+	}
+	if cfg.RequestTimeout != 10*time.Second {
+		t.Errorf("RequestTimeout = %s, want the environment's 10s without a flag", cfg.RequestTimeout)
+	}
+}
+
+func TestRegisterFlagsRejectsInvalidDuration(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	RegisterFlags(fs)
+
+	if err := fs.Parse([]string{"--shutdown-timeout", "30"}); err == nil {
+		t.Error("Parse accepted a duration without a unit")
+	}
+}
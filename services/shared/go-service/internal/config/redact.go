@@ -0,0 +1,77 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"time"
+)
+
+// redacted replaces the value of sensitive fields that are set
+const redacted = "[REDACTED]"
+
+// Redacted returns the effective configuration keyed by field name, for
+// debugging deployments. Fields tagged `sensitive:"true"` are replaced with
+// "[REDACTED]" when set, so whether a secret is configured stays visible
+// without exposing it. URLs in fields tagged `sensitive:"url"` are shown
+// without their userinfo and query, where credentials and tokens end up.
+func (c *Config) Redacted() map[string]interface{} {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	out := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		value := v.Field(i)
+
+		switch {
+		case field.Tag.Get("sensitive") == "true":
+			if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+				out[field.Name] = ""
+			} else {
+				out[field.Name] = redacted
+			}
+		case field.Tag.Get("sensitive") == "url":
+			out[field.Name] = redactURLs(value.Interface())
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			// Durations read better as "30s" than as nanoseconds
+			out[field.Name] = value.Interface().(time.Duration).String()
+		default:
+			out[field.Name] = value.Interface()
+		}
+	}
+	return out
+}
+
+// redactURLs redacts the URLs held by a field tagged `sensitive:"url"`
+func redactURLs(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		return redactURL(value)
+	case []HTTPDependency:
+		out := make([]HTTPDependency, len(value))
+		for i, dependency := range value {
+			dependency.URL = redactURL(dependency.URL)
+			out[i] = dependency
+		}
+		return out
+	default:
+		return redacted
+	}
+}
+
+// redactURL drops the userinfo, query and fragment of rawURL. A URL that
+// doesn't parse is redacted entirely.
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	return u.String()
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// RuntimeSettings is the subset of configuration that can be changed while
+// the service is running, e.g. during incident response.
+type RuntimeSettings struct {
+	RateLimit   int      `json:"rate_limit"`
+	CORSOrigins []string `json:"cors_origins"`
+}
+
+// Runtime holds the current RuntimeSettings. Reads and updates are atomic so
+// middleware picks up changes on the next request.
+type Runtime struct {
+	settings atomic.Pointer[RuntimeSettings]
+}
+
+// NewRuntime creates a holder seeded from the loaded configuration
+func NewRuntime(cfg *Config) *Runtime {
+	r := &Runtime{}
+	r.settings.Store(&RuntimeSettings{
+		RateLimit:   cfg.RateLimit,
+		CORSOrigins: append([]string(nil), cfg.CORSOrigins...),
+	})
+	return r
+}
+
+// Load returns a copy of the current settings
+func (r *Runtime) Load() RuntimeSettings {
+	s := *r.settings.Load()
+	s.CORSOrigins = append([]string(nil), s.CORSOrigins...)
+	return s
+}
+
+// Store validates and atomically replaces the current settings
+func (r *Runtime) Store(s RuntimeSettings) error {
+	if s.RateLimit < 0 {
+		return fmt.Errorf("rate_limit must not be negative, got %d (use 0 to disable rate limiting)", s.RateLimit)
+	}
+
+	origins, err := normalizeOrigins(s.CORSOrigins)
+	if err != nil {
+		return fmt.Errorf("invalid cors_origins: %w", err)
+	}
+	s.CORSOrigins = origins
+
+	r.settings.Store(&s)
+	return nil
+}
@@ -0,0 +1,115 @@
+package ctxcache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type contextKey struct{}
+
+// errLoadPanicked is the error waiters see when the shared load panicked
+var errLoadPanicked = errors.New("load panicked")
+
+// cache holds values for the lifetime of a single request
+type cache struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// New returns a copy of ctx carrying an empty request-scoped cache
+func New(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextKey{}, &cache{entries: make(map[string]*entry)})
+}
+
+func from(ctx context.Context) *cache {
+	c, _ := ctx.Value(contextKey{}).(*cache)
+	return c
+}
+
+// Get returns the value cached under key, if any
+func Get(ctx context.Context, key string) (interface{}, bool) {
+	c := from(ctx)
+	if c == nil {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	<-e.done
+	if e.err != nil {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set caches value under key for the rest of the request. It is a no-op when
+// ctx carries no cache.
+func Set(ctx context.Context, key string, value interface{}) {
+	c := from(ctx)
+	if c == nil {
+		return
+	}
+
+	e := &entry{done: make(chan struct{}), value: value}
+	close(e.done)
+
+	c.mu.Lock()
+	c.entries[key] = e
+	c.mu.Unlock()
+}
+
+// GetOrLoad returns the value cached under key, calling load on a miss.
+// Concurrent callers within the same request share a single load. Errors are
+// not cached, so a later call retries. Without a cache in ctx, load is always
+// called.
+func GetOrLoad[T any](ctx context.Context, key string, load func() (T, error)) (T, error) {
+	c := from(ctx)
+	if c == nil {
+		return load()
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		<-e.done
+		if e.err == nil {
+			if value, ok := e.value.(T); ok {
+				return value, nil
+			}
+		}
+		return load()
+	}
+	e := &entry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	// Released even if load panics, so waiters don't block forever; they
+	// see the error and load for themselves
+	e.err = errLoadPanicked
+	defer func() {
+		if e.err != nil {
+			c.mu.Lock()
+			if c.entries[key] == e {
+				delete(c.entries, key)
+			}
+			c.mu.Unlock()
+		}
+		close(e.done)
+	}()
+
+	value, err := load()
+	e.value, e.err = value, err
+	return value, err
+}
@@ -0,0 +1,61 @@
+package ctxcache
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetOrLoadSharesLoad(t *testing.T) {
+	ctx := New(context.Background())
+	var calls atomic.Int32
+
+	for i := 0; i < 3; i++ {
+		value, err := GetOrLoad(ctx, "user:1", func() (string, error) {
+			calls.Add(1)
+			return "alice", nil
+		})
+		if err != nil || value != "alice" {
+			t.Fatalf("GetOrLoad = %q, %v", value, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("load called %d times, want 1", n)
+	}
+}
+
+func TestGetOrLoadPanicReleasesWaiters(t *testing.T) {
+	ctx := New(context.Background())
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	go func() {
+		defer func() { _ = recover() }()
+		_, _ = GetOrLoad(ctx, "key", func() (int, error) {
+			close(started)
+			<-release
+			panic("load failed")
+		})
+	}()
+	<-started
+
+	result := make(chan int, 1)
+	go func() {
+		value, _ := GetOrLoad(ctx, "key", func() (int, error) { return 42, nil })
+		result <- value
+	}()
+
+	// Let the waiter block on the in-flight load before it panics
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	select {
+	case value := <-result:
+		if value != 42 {
+			t.Errorf("waiter got %d, want 42 from its own load", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("waiter blocked after the shared load panicked")
+	}
+}
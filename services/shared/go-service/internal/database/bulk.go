@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+const defaultBatchSize = 500
+
+// WithTransaction runs fn inside a transaction bound to ctx. The transaction
+// is committed when fn returns nil and rolled back otherwise.
+func (m *DatabaseManager) WithTransaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	if m.DB() == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	return m.Query(ctx).Transaction(fn)
+}
+
+// BulkInsert inserts records in batches using GORM's CreateInBatches, all
+// within a single transaction. The context is checked between batches, so
+// cancelling it mid-way rolls back every batch inserted so far. A batchSize
+// of zero or less falls back to DATABASE_BATCH_SIZE. It returns the number of
+// records inserted.
+func BulkInsert[T any](ctx context.Context, m *DatabaseManager, records []T, batchSize int) (int, error) {
+	if batchSize <= 0 {
+		batchSize = m.config.DatabaseBatchSize
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+
+	total := len(records)
+	inserted := 0
+
+	err := m.WithTransaction(ctx, func(tx *gorm.DB) error {
+		for start := 0; start < total; start += batchSize {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("bulk insert cancelled after %d of %d records: %w", inserted, total, err)
+			}
+
+			end := min(start+batchSize, total)
+			if err := tx.CreateInBatches(records[start:end], batchSize).Error; err != nil {
+				return fmt.Errorf("failed to insert batch at offset %d: %w", start, err)
+			}
+
+			inserted = end
+			m.logger.Debugf("Bulk insert progress: %d/%d records", inserted, total)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	m.logger.Infof("Bulk inserted %d records", inserted)
+	return inserted, nil
+}
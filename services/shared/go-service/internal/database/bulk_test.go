@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/config"
+)
+
+// bulkRecord has a client-side key, so batches insert without RETURNING
+type bulkRecord struct {
+	ID   string `gorm:"primaryKey"`
+	Name string
+}
+
+func newBulkRecords(n int) []bulkRecord {
+	records := make([]bulkRecord, n)
+	for i := range records {
+		records[i] = bulkRecord{ID: strconv.Itoa(i), Name: "record"}
+	}
+	return records
+}
+
+// newSQLiteManager returns a manager backed by a SQLite database of its own
+// holding the bulk_records table. The Postgres capability probes fail
+// against it, which only disables those features.
+func newSQLiteManager(t *testing.T, configure func(*config.Config)) *DatabaseManager {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "bulk.db")
+	m, err := NewManager(context.Background(), sqlite.Open(dsn), newTestConfig(t, configure), discardLogger())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	t.Cleanup(func() { m.Close() })
+
+	if err := m.DB().AutoMigrate(&bulkRecord{}); err != nil {
+		t.Fatalf("AutoMigrate: %v", err)
+	}
+	return m
+}
+
+// countBulkRecords returns the number of rows in bulk_records
+func countBulkRecords(t *testing.T, m *DatabaseManager) int64 {
+	t.Helper()
+	var n int64
+	if err := m.DB().Model(&bulkRecord{}).Count(&n).Error; err != nil {
+		t.Fatalf("Count: %v", err)
+	}
+	return n
+}
+
+func TestBulkInsert(t *testing.T) {
+	m := newSQLiteManager(t, func(cfg *config.Config) { cfg.DatabaseBatchSize = 500 })
+
+	inserted, err := BulkInsert(context.Background(), m, newBulkRecords(10000), 0)
+	if err != nil {
+		t.Fatalf("BulkInsert: %v", err)
+	}
+	if inserted != 10000 {
+		t.Errorf("inserted = %d, want 10000", inserted)
+	}
+	if n := countBulkRecords(t, m); n != 10000 {
+		t.Errorf("rows = %d, want 10000", n)
+	}
+
+	var last bulkRecord
+	if err := m.DB().First(&last, "id = ?", "9999").Error; err != nil {
+		t.Errorf("last record: %v", err)
+	}
+}
+
+func TestBulkInsertCancelledRollsBack(t *testing.T) {
+	m := newSQLiteManager(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel once the second batch is in
+	batches := 0
+	err := m.DB().Callback().Create().After("gorm:create").Register("test:cancel", func(*gorm.DB) {
+		if batches++; batches == 2 {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	inserted, err := BulkInsert(ctx, m, newBulkRecords(1000), 100)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if inserted != 0 {
+		t.Errorf("inserted = %d, want 0 after the rollback", inserted)
+	}
+	if batches != 2 {
+		t.Errorf("batches = %d, want 2 before the cancellation", batches)
+	}
+	if n := countBulkRecords(t, m); n != 0 {
+		t.Errorf("rows = %d, want 0 after the rollback", n)
+	}
+}
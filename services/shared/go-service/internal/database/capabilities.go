@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// Capabilities are the optional Postgres features found on the server at
+// startup. Managed providers and connection poolers don't always offer
+// everything a self-hosted server does, so features depending on them check
+// here and degrade instead of failing at first use.
+type Capabilities struct {
+	// AdvisoryLocks is false when session-level advisory locks are
+	// unavailable, e.g. the functions are revoked; migrations then run
+	// without the lock
+	AdvisoryLocks bool
+	// Extensions are the installed extensions, by name
+	Extensions map[string]bool
+}
+
+// HasExtension reports whether the named extension is installed
+func (c Capabilities) HasExtension(name string) bool {
+	return c.Extensions[strings.ToLower(name)]
+}
+
+// Capabilities returns the features detected when the manager was
+// initialized
+func (m *DatabaseManager) Capabilities() Capabilities {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.capabilities
+}
+
+// detectCapabilities probes the server for optional features. A failed
+// probe marks the feature unsupported, so it is only an error when a
+// feature listed in DATABASE_REQUIRED_EXTENSIONS is missing.
+func (m *DatabaseManager) detectCapabilities(ctx context.Context, sqlDB *sql.DB) (Capabilities, error) {
+	caps := Capabilities{Extensions: make(map[string]bool)}
+
+	if err := probeAdvisoryLocks(ctx, sqlDB, m.config.ServiceName); err != nil {
+		m.logger.WithError(err).Warn("Advisory locks unavailable, migrations will run without the migration lock")
+	} else {
+		caps.AdvisoryLocks = true
+	}
+
+	extensions, err := installedExtensions(ctx, sqlDB)
+	if err != nil {
+		m.logger.WithError(err).Warn("Failed to list installed database extensions")
+	}
+	for _, name := range extensions {
+		caps.Extensions[name] = true
+	}
+
+	var missing []string
+	for _, name := range m.config.DatabaseRequiredExtensions {
+		if !caps.HasExtension(name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return caps, fmt.Errorf("required database extensions not installed: %s", strings.Join(missing, ", "))
+	}
+
+	m.logger.WithFields(map[string]interface{}{
+		"advisory_locks": caps.AdvisoryLocks,
+		"extensions":     extensions,
+	}).Info("Database capabilities detected")
+	return caps, nil
+}
+
+// probeAdvisoryLocks takes and releases a session-level advisory lock on a
+// key of its own, which is what the migration lock needs
+func probeAdvisoryLocks(ctx context.Context, sqlDB *sql.DB, serviceName string) error {
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	h := fnv.New64a()
+	h.Write([]byte("capabilities:" + serviceName))
+	key := int64(h.Sum64())
+
+	// Another replica probing at the same moment makes this false, which
+	// still shows the functions work
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return err
+	}
+	if acquired {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installedExtensions lists the extensions installed in the database
+func installedExtensions(ctx context.Context, sqlDB *sql.DB) ([]string, error) {
+	rows, err := sqlDB.QueryContext(ctx, "SELECT extname FROM pg_extension")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	return names, rows.Err()
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+
+	"{{ module_name }}/internal/config"
+)
+
+func TestDetectCapabilities(t *testing.T) {
+	errDenied := errors.New("permission denied for function pg_try_advisory_lock")
+
+	tests := []struct {
+		name     string
+		required []string
+		// expect sets up the probes' answers
+		expect        func(mock sqlmock.Sqlmock)
+		wantLocks     bool
+		wantExtension map[string]bool
+		wantErr       string
+	}{
+		{
+			name:          "all features",
+			required:      []string{"PostGIS"},
+			expect:        func(mock sqlmock.Sqlmock) { expectCapabilityDetection(mock, "postgis", "pg_trgm") },
+			wantLocks:     true,
+			wantExtension: map[string]bool{"postgis": true, "pg_trgm": true},
+		},
+		{
+			name: "advisory locks unavailable",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnError(errDenied)
+				mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+			},
+			wantLocks: false,
+		},
+		{
+			name:          "optional extension missing",
+			expect:        func(mock sqlmock.Sqlmock) { expectCapabilityDetection(mock, "postgis") },
+			wantLocks:     true,
+			wantExtension: map[string]bool{"postgis": true, "pg_trgm": false},
+		},
+		{
+			name:     "required extension missing",
+			required: []string{"postgis", "pg_trgm", "citext"},
+			expect:   func(mock sqlmock.Sqlmock) { expectCapabilityDetection(mock, "pg_trgm") },
+			wantErr:  "required database extensions not installed: postgis, citext",
+		},
+		{
+			name:     "extensions not listed",
+			required: []string{"postgis"},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+				mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+				mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnError(errors.New("permission denied for table pg_extension"))
+			},
+			wantErr: "required database extensions not installed: postgis",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			t.Cleanup(func() { conn.Close() })
+			tt.expect(mock)
+
+			cfg := newTestConfig(t, func(cfg *config.Config) { cfg.DatabaseRequiredExtensions = tt.required })
+			m, err := NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, discardLogger())
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewManager = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewManager: %v", err)
+			}
+
+			caps := m.Capabilities()
+			if caps.AdvisoryLocks != tt.wantLocks {
+				t.Errorf("AdvisoryLocks = %v, want %v", caps.AdvisoryLocks, tt.wantLocks)
+			}
+			for name, want := range tt.wantExtension {
+				if got := caps.HasExtension(name); got != want {
+					t.Errorf("HasExtension(%q) = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrationsRunWithoutAdvisoryLocks checks migrations still run, without
+// the migration lock, when the server doesn't offer advisory locks
+func TestMigrationsRunWithoutAdvisoryLocks(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnError(errors.New("permission denied"))
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+	m, err := NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), newTestConfig(t, nil), discardLogger())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	// No pg_try_advisory_lock or pg_advisory_unlock around the migration
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TABLE "migrate_firsts"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := m.AutoMigrateContext(context.Background(), &migrateFirst{}); err != nil {
+		t.Fatalf("AutoMigrateContext: %v", err)
+	}
+	if !m.Migrated() {
+		t.Error("not Migrated after migrating without the lock")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
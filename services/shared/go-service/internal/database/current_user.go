@@ -0,0 +1,63 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/response"
+)
+
+// ErrUserDeactivated is returned for users whose account was deactivated
+var ErrUserDeactivated = errors.New("user deactivated")
+
+// LoadUser middleware rejects requests whose authenticated user no longer
+// exists or was deactivated with 401, so a still-valid token stops working
+// as soon as the account does. It must run after the auth middleware and
+// lets anonymous requests through. The user is cached for the rest of the
+// request, so CurrentUser in handlers doesn't query again.
+func LoadUser(m *DatabaseManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("user_id") == "" {
+			c.Next()
+			return
+		}
+
+		if _, err := currentUser(c, m); err != nil {
+			if errors.Is(err, ErrNotFound) || errors.Is(err, ErrUserDeactivated) {
+				response.Error(c, apperror.Unauthenticated("User not found or deactivated"))
+			} else {
+				response.Error(c, apperror.Internal("Failed to load user").WithCause(err))
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// CurrentUser returns the authenticated user, loading it from the database
+// on first access within a request and from the request cache after that.
+// It returns ErrNotFound for anonymous requests and ErrUserDeactivated for
+// deactivated accounts. It panics if Bind hasn't run.
+func CurrentUser(c *gin.Context) (*User, error) {
+	return currentUser(c, c.MustGet(ginKey).(*DatabaseManager))
+}
+
+func currentUser(c *gin.Context, m *DatabaseManager) (*User, error) {
+	userID := c.GetString("user_id")
+	if userID == "" {
+		return nil, ErrNotFound
+	}
+
+	user, err := m.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active() {
+		return nil, fmt.Errorf("user %s: %w", userID, ErrUserDeactivated)
+	}
+	return user, nil
+}
@@ -1,14 +1,19 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 
 	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
 	applogger "{{ module_name }}/internal/logger"
 )
 
@@ -18,34 +23,49 @@ type DatabaseManager struct {
 	logger applogger.Logger
 	config *config.Config
 	mu     sync.RWMutex
+
+	// migrated gates readiness until the schema is known to be current
+	migrated atomic.Bool
+
+	// capabilities are the optional server features detected at startup
+	capabilities Capabilities
 }
 
 var (
-	instance *DatabaseManager
-	once     sync.Once
+	instance   *DatabaseManager
+	instanceMu sync.Mutex
 )
 
-// GetInstance returns singleton database manager for service
-func GetInstance(serviceName string, cfg *config.Config, log applogger.Logger) (*DatabaseManager, error) {
-	var err error
+// Connection pool limits
+const (
+	maxIdleConns = 10
+	maxOpenConns = 100
+)
 
-	once.Do(func() {
-		instance = &DatabaseManager{
-			logger: log,
-			config: cfg,
-		}
-		err = instance.initialize()
-	})
+// GetInstance returns singleton database manager for service. Connecting is
+// bounded by ctx. A failed connection isn't kept, so the next call retries.
+func GetInstance(ctx context.Context, serviceName string, cfg *config.Config, log applogger.Logger) (*DatabaseManager, error) {
+	instanceMu.Lock()
+	defer instanceMu.Unlock()
 
-	if err != nil {
+	if instance != nil {
+		return instance, nil
+	}
+
+	m := &DatabaseManager{
+		logger: log,
+		config: cfg,
+	}
+	if err := m.initialize(ctx); err != nil {
 		return nil, err
 	}
+	instance = m
 
 	return instance, nil
 }
 
 // initialize sets up the database connection following Marty patterns
-func (m *DatabaseManager) initialize() error {
+func (m *DatabaseManager) initialize(ctx context.Context) error {
 	// Build service-specific database name following Marty conventions
 	serviceName := m.config.ServiceName
 	if serviceName == "" {
@@ -73,40 +93,94 @@ func (m *DatabaseManager) initialize() error {
 		)
 	}
 
-	// Configure GORM logger
-	var gormLogger logger.Interface
-	if m.config.LogLevel == "debug" {
-		gormLogger = logger.Default.LogMode(logger.Info)
-	} else {
-		gormLogger = logger.Default.LogMode(logger.Silent)
+	if err := m.open(ctx, postgres.Open(dsn)); err != nil {
+		return fmt.Errorf("failed to connect to database for service %s: %w", serviceName, err)
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: gormLogger,
+	m.logger.Info("Database manager initialized for service", "service", serviceName)
+	return nil
+}
+
+// NewManager creates a manager connected through dialector instead of to
+// the configured database, e.g. for a second database or, in tests, a
+// sqlmock connection wrapped in postgres.New. Unlike GetInstance it isn't a
+// singleton. Connecting is bounded by ctx.
+func NewManager(ctx context.Context, dialector gorm.Dialector, cfg *config.Config, log applogger.Logger) (*DatabaseManager, error) {
+	m := &DatabaseManager{
+		logger: log,
+		config: cfg,
+	}
+	if err := m.open(ctx, dialector); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// open connects through dialector, detects the server's capabilities and
+// registers the replicas and query timeout
+func (m *DatabaseManager) open(ctx context.Context, dialector gorm.Dialector) error {
+	// Configure GORM logger. SQL is logged under the request's fields, and
+	// requests flagged via reqctx.WithDebugSQL are logged verbosely
+	// regardless of the configured level.
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: newContextLogger(newServiceLogger(m.logger), m.logLevel()),
+		NamingStrategy: schema.NamingStrategy{
+			TablePrefix:   m.config.DatabaseTablePrefix,
+			SingularTable: m.config.DatabaseSingularTables,
+		},
+		// Connectivity is checked below, bounded by ctx
+		DisableAutomaticPing: true,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to connect to database for service %s: %w", serviceName, err)
+		return err
 	}
 
-	// Test connection
 	sqlDB, err := db.DB()
 	if err != nil {
 		return fmt.Errorf("failed to get database instance: %w", err)
 	}
 
-	if err := sqlDB.Ping(); err != nil {
+	// The pool holds a live connection once pinged, so it is closed rather
+	// than leaked when the manager can't be set up
+	if err := m.setUp(ctx, db, sqlDB); err != nil {
+		sqlDB.Close()
+		return err
+	}
+	return nil
+}
+
+// setUp checks the connection, configures the pool, detects the server's
+// capabilities and registers the replicas and query timeout
+func (m *DatabaseManager) setUp(ctx context.Context, db *gorm.DB, sqlDB *sql.DB) error {
+	// Test connection
+	if err := sqlDB.PingContext(ctx); err != nil {
 		return fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	// Configure connection pool
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	sqlDB.SetMaxIdleConns(maxIdleConns)
+	sqlDB.SetMaxOpenConns(maxOpenConns)
 
-	m.db = db
+	capabilities, err := m.detectCapabilities(ctx, sqlDB)
+	if err != nil {
+		return err
+	}
 
-	m.logger.Info("Database manager initialized for service", "service", serviceName)
+	if err := m.registerReplicas(db); err != nil {
+		return err
+	}
+
+	if err := registerQueryTimeout(db); err != nil {
+		return fmt.Errorf("failed to register query timeout: %w", err)
+	}
+
+	m.db = db
+	m.capabilities = capabilities
 	return nil
 }
+
+// DB returns the underlying connection. Queries issued on it aren't bound to
+// a request context; use Query(ctx) instead unless that is intended.
 func (m *DatabaseManager) DB() *gorm.DB {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -114,18 +188,21 @@ func (m *DatabaseManager) DB() *gorm.DB {
 }
 
 func (m *DatabaseManager) Ping() error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.PingContext(context.Background())
+}
 
-	if m.db == nil {
+// PingContext verifies the database connection, honouring ctx
+func (m *DatabaseManager) PingContext(ctx context.Context) error {
+	db := m.DB()
+	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	sqlDB, err := m.db.DB()
+	sqlDB, err := db.DB()
 	if err != nil {
 		return err
 	}
-	return sqlDB.Ping()
+	return sqlDB.PingContext(ctx)
 }
 
 func (m *DatabaseManager) Close() error {
@@ -150,42 +227,64 @@ func (m *DatabaseManager) Close() error {
 	return nil
 }
 
-// HealthCheck performs database health check following Marty patterns
-func (m *DatabaseManager) HealthCheck() (map[string]interface{}, error) {
-	if err := m.Ping(); err != nil {
-		return map[string]interface{}{
-			"status": "unhealthy",
-			"error":  err.Error(),
-		}, err
+// HealthCheck performs database health check following Marty patterns.
+// The ping is bounded by ctx, which the health registry limits to
+// HEALTH_CHECK_TIMEOUT, so a hung database reports a timeout instead of
+// blocking the health endpoint.
+func (m *DatabaseManager) HealthCheck(ctx context.Context) (health.CheckResult, error) {
+	err := m.PingContext(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("database ping timed out: %w", err)
+		return health.CheckResult{Details: map[string]interface{}{"reason": "timeout"}}, err
+	}
+	if err != nil {
+		return health.CheckResult{}, err
 	}
 
-	sqlDB, err := m.db.DB()
+	sqlDB, err := m.DB().DB()
 	if err != nil {
-		return map[string]interface{}{
-			"status": "unhealthy",
-			"error":  err.Error(),
-		}, err
+		return health.CheckResult{}, err
 	}
 
 	stats := sqlDB.Stats()
-	return map[string]interface{}{
-		"status":         "healthy",
+	return health.CheckResult{Details: map[string]interface{}{
 		"open_connections": stats.OpenConnections,
-		"in_use":         stats.InUse,
-		"idle":           stats.Idle,
-	}, nil
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+	}}, nil
 }
 
 // AutoMigrate runs database migrations
 func (m *DatabaseManager) AutoMigrate(models ...interface{}) error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
+	return m.AutoMigrateContext(context.Background(), models...)
+}
 
-	if m.db == nil {
+// AutoMigrateContext runs database migrations under an advisory lock, so when
+// several replicas start at once only one migrates while the others wait.
+// Waiting for the lock is bounded by ctx. Each model is migrated in its own
+// transaction; see migrateModels for how cancelling ctx is handled.
+func (m *DatabaseManager) AutoMigrateContext(ctx context.Context, models ...interface{}) error {
+	// The manager's lock isn't held while waiting for the migration lock,
+	// so Close isn't blocked behind a replica that is waiting
+	db := m.DB()
+	if db == nil {
 		return fmt.Errorf("database not initialized")
 	}
 
-	return m.db.AutoMigrate(models...)
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	err = m.withMigrationLock(ctx, sqlDB, func() error {
+		return m.migrateModels(ctx, db, models)
+	})
+	if err != nil {
+		return err
+	}
+
+	m.migrated.Store(true)
+	return nil
 }
 
 // CloseAll closes all database manager instances
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/postgres"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/logger"
+)
+
+// newTestConfig loads the configuration, adjusted by configure if set
+func newTestConfig(t *testing.T, configure func(*config.Config)) *config.Config {
+	t.Helper()
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	if configure != nil {
+		configure(cfg)
+	}
+	return cfg
+}
+
+// discardLogger returns a logger dropping everything
+func discardLogger() logger.Logger {
+	return logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+}
+
+// expectCapabilityDetection expects the probes run when a manager connects,
+// finding advisory locks and the named extensions
+func expectCapabilityDetection(mock sqlmock.Sqlmock, extensions ...string) {
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+	rows := sqlmock.NewRows([]string{"extname"})
+	for _, name := range extensions {
+		rows.AddRow(name)
+	}
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(rows)
+}
+
+// newMockManager returns a manager backed by sqlmock, past the capability
+// detection run when it connects
+func newMockManager(t *testing.T, configure func(*config.Config)) (*DatabaseManager, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	expectCapabilityDetection(mock)
+	m, err := NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), newTestConfig(t, configure), discardLogger())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m, mock
+}
+
+// newLoggingMockManager returns a manager backed by sqlmock whose logs,
+// debug entries included, are written to the returned buffer. The service
+// runs at LOG_LEVEL=info unless configure says otherwise.
+func newLoggingMockManager(t *testing.T, configure func(*config.Config)) (*DatabaseManager, sqlmock.Sqlmock, *bytes.Buffer) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	var buf bytes.Buffer
+	log := logger.NewLogger("debug", func(l *logrus.Logger) { l.SetOutput(&buf) })
+	cfg := newTestConfig(t, func(cfg *config.Config) {
+		cfg.LogLevel = "info"
+		if configure != nil {
+			configure(cfg)
+		}
+	})
+
+	expectCapabilityDetection(mock)
+	m, err := NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, log)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	buf.Reset()
+	return m, mock, &buf
+}
+
+func TestNewManagerClosesConnectionOnFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*config.Config)
+	}{
+		{"missing required extension", func(cfg *config.Config) {
+			cfg.DatabaseRequiredExtensions = []string{"postgis"}
+		}},
+		{"invalid replica", func(cfg *config.Config) {
+			cfg.DatabaseReplicaURLs = []string{"replica-without-scheme"}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			t.Cleanup(func() { conn.Close() })
+
+			expectCapabilityDetection(mock)
+			mock.ExpectClose()
+
+			_, err = NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), newTestConfig(t, tt.configure), discardLogger())
+			if err == nil {
+				t.Fatal("NewManager succeeded")
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("connection left open: %v", err)
+			}
+		})
+	}
+}
+
+func TestHealthCheckTimesOut(t *testing.T) {
+	conn, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mock.ExpectPing()
+	expectCapabilityDetection(mock)
+	m, err := NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), newTestConfig(t, nil), discardLogger())
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+
+	// The database hangs well past the health check timeout
+	mock.ExpectPing().WillDelayFor(5 * time.Second)
+
+	const timeout = 100 * time.Millisecond
+	registry := health.NewRegistry(health.Options{CheckTimeout: timeout})
+	registry.Register("database", true, m.HealthCheck)
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > timeout+500*time.Millisecond {
+		t.Errorf("health check took %s, beyond the %s timeout", elapsed, timeout)
+	}
+
+	if report.Status != health.StatusUnhealthy {
+		t.Errorf("status = %s, want %s", report.Status, health.StatusUnhealthy)
+	}
+	if check := report.Checks["database"]; check.Status != health.StatusUnhealthy || check.Error == "" {
+		t.Errorf("database check = %+v, want unhealthy with an error", check)
+	}
+}
+
+func TestNamingStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		singular bool
+		want     string
+	}{
+		{"default", "", false, "bulk_records"},
+		{"prefixed", "svc_", false, "svc_bulk_records"},
+		{"singular", "", true, "bulk_record"},
+		{"prefixed and singular", "svc_", true, "svc_bulk_record"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newSQLiteManager(t, func(cfg *config.Config) {
+				cfg.DatabaseTablePrefix = tt.prefix
+				cfg.DatabaseSingularTables = tt.singular
+			})
+
+			var tables []string
+			if err := m.DB().Raw(`SELECT name FROM sqlite_master WHERE type = 'table' AND name LIKE '%bulk_record%'`).Scan(&tables).Error; err != nil {
+				t.Fatalf("list tables: %v", err)
+			}
+			if len(tables) != 1 || tables[0] != tt.want {
+				t.Errorf("tables = %v, want [%s]", tables, tt.want)
+			}
+
+			// Queries go to the same table
+			if err := m.DB().Create(&bulkRecord{ID: "1", Name: "one"}).Error; err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if n := countBulkRecords(t, m); n != 1 {
+				t.Errorf("count = %d, want 1", n)
+			}
+		})
+	}
+}
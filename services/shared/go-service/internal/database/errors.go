@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+
+	"{{ module_name }}/internal/apperror"
+)
+
+// ErrDuplicate is returned when a write conflicts with an existing record on
+// a unique column
+var ErrDuplicate = errors.New("duplicate record")
+
+// sqlStateUniqueViolation is the SQLSTATE of a unique constraint violation
+const sqlStateUniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err is a unique constraint violation
+func IsUniqueViolation(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == sqlStateUniqueViolation
+}
+
+// AppError maps errors of this package to the application errors handlers
+// respond with, naming resource in the message: ErrNotFound becomes 404 Not
+// Found and ErrDuplicate 409 Conflict. Other errors are returned unchanged,
+// so they are reported as internal errors.
+func AppError(err error, resource string) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return apperror.NotFound(resource + " not found").WithCause(err)
+	case errors.Is(err, ErrDuplicate):
+		return apperror.Conflict(resource + " already exists").WithCause(err)
+	}
+	return err
+}
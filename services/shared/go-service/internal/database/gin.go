@@ -0,0 +1,26 @@
+package database
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ginKey is the gin context key the manager is stored under
+const ginKey = "database"
+
+// Bind middleware makes m available to handlers through For
+func Bind(m *DatabaseManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ginKey, m)
+		c.Next()
+	}
+}
+
+// For returns a session bound to the request's context, so queries are
+// cancelled with the request and inherit its deadline without handlers
+// passing c.Request.Context() around. It panics if Bind hasn't run.
+//
+//	database.For(c).Where("email = ?", email).First(&user)
+func For(c *gin.Context) *gorm.DB {
+	return c.MustGet(ginKey).(*DatabaseManager).Query(c.Request.Context())
+}
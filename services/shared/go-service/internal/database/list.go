@@ -0,0 +1,53 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"{{ module_name }}/internal/query"
+)
+
+// FilterScope applies filter conditions parsed by query.Filter as a GORM
+// scope
+func FilterScope(conditions []query.Condition) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, cond := range conditions {
+			column := clause.Column{Name: cond.Column}
+			switch cond.Operator {
+			case query.Gt:
+				db = db.Where(clause.Gt{Column: column, Value: cond.Value})
+			case query.Lt:
+				db = db.Where(clause.Lt{Column: column, Value: cond.Value})
+			case query.Like:
+				db = db.Where(clause.Like{Column: column, Value: cond.Value})
+			default:
+				db = db.Where(clause.Eq{Column: column, Value: cond.Value})
+			}
+		}
+		return db
+	}
+}
+
+// PaginationScope applies the pagination as a GORM scope
+func PaginationScope(p query.Pagination) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if column, desc := p.OrderBy(); column != "" {
+			db = db.Order(clause.OrderByColumn{
+				Column: clause.Column{Name: column},
+				Desc:   desc,
+			})
+		}
+		return db.Offset(p.Offset()).Limit(p.PageSize)
+	}
+}
+
+// ListScope applies the filters and pagination of a list request as a
+// single GORM scope
+//
+//	params, err := query.ParseList(c.Request.URL.Query(), filter, sortable)
+//	database.For(c).Scopes(database.ListScope(params)).Find(&users)
+func ListScope(p query.ListParams) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(FilterScope(p.Conditions), PaginationScope(p.Pagination))
+	}
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"{{ module_name }}/internal/query"
+)
+
+func TestListScope(t *testing.T) {
+	conn, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer conn.Close()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open: %v", err)
+	}
+
+	filter := query.NewFilter(map[string]query.Field{
+		"name": {Column: "display_name", Type: query.String, Operators: []query.Operator{query.Like}},
+		"age":  {Column: "age", Type: query.Int, Operators: []query.Operator{query.Gt, query.Lt, query.Eq}},
+	})
+	values, _ := url.ParseQuery("name=ali&age[gt]=18&age[lt]=65&page=2&page_size=10&sort=-name")
+	params, err := query.ParseList(values, filter, map[string]string{"name": "display_name"})
+	if err != nil {
+		t.Fatalf("ParseList: %v", err)
+	}
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&User{}).Scopes(ListScope(params)).Find(&[]User{})
+	})
+	want := `SELECT * FROM "users" WHERE "age" > 18 AND "age" < 65 AND "display_name" LIKE '%ali%' ORDER BY "display_name" DESC LIMIT 10 OFFSET 10`
+	if sql != want {
+		t.Errorf("SQL =\n%s\nwant\n%s", sql, want)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/logger"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// contextLogger is a GORM logger that logs at its configured level, except
+// for queries whose context is flagged with reqctx.WithDebugSQL, which are
+// logged at Info level so SQL can be traced for individual requests.
+type contextLogger struct {
+	base    logger.Interface
+	verbose logger.Interface
+}
+
+func newContextLogger(l logger.Interface, level logger.LogLevel) logger.Interface {
+	return &contextLogger{
+		base:    l.LogMode(level),
+		verbose: l.LogMode(logger.Info),
+	}
+}
+
+func (l *contextLogger) forContext(ctx context.Context) logger.Interface {
+	if ctx != nil && reqctx.DebugSQL(ctx) {
+		return l.verbose
+	}
+	return l.base
+}
+
+func (l *contextLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &contextLogger{
+		base:    l.base.LogMode(level),
+		verbose: l.verbose,
+	}
+}
+
+func (l *contextLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.forContext(ctx).Info(ctx, msg, data...)
+}
+
+func (l *contextLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.forContext(ctx).Warn(ctx, msg, data...)
+}
+
+func (l *contextLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.forContext(ctx).Error(ctx, msg, data...)
+}
+
+func (l *contextLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	l.forContext(ctx).Trace(ctx, begin, fc, err)
+}
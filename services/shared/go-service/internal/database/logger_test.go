@@ -0,0 +1,59 @@
+package database
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/middleware"
+)
+
+func TestDebugSQLHeader(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, mock, buf := newLoggingMockManager(t, nil)
+
+	router := gin.New()
+	router.Use(middleware.DebugSQL("admin-token"))
+	router.GET("/users/:id", func(c *gin.Context) {
+		var user User
+		if err := m.Query(c.Request.Context()).First(&user, "id = ?", c.Param("id")).Error; err != nil {
+			t.Errorf("First: %v", err)
+		}
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		logged  bool
+	}{
+		{"debug header with admin token", map[string]string{"X-Debug-SQL": "true", "X-Admin-Token": "admin-token"}, true},
+		{"debug header without admin token", map[string]string{"X-Debug-SQL": "true"}, false},
+		{"debug header with wrong admin token", map[string]string{"X-Debug-SQL": "true", "X-Admin-Token": "guess"}, false},
+		{"no debug header", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			mock.ExpectQuery(`SELECT \* FROM "users"`).
+				WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice@example.com", "Alice", "hash", true, now, now, nil))
+			buf.Reset()
+
+			req := httptest.NewRequest(http.MethodGet, "/users/u1", nil)
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if logged := strings.Contains(buf.String(), `SELECT * FROM \"users\"`); logged != tt.logged {
+				t.Errorf("SQL logged = %v, want %v: %s", logged, tt.logged, buf)
+			}
+		})
+	}
+}
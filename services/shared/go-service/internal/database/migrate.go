@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Models lists the models whose schema is migrated at startup. Each is
+// migrated separately, so list referenced models before those referencing
+// them.
+func Models() []interface{} {
+	return []interface{}{&User{}}
+}
+
+// Migrated reports whether the schema has been migrated, or confirmed to
+// exist, since startup
+func (m *DatabaseManager) Migrated() bool {
+	return m.migrated.Load()
+}
+
+// VerifySchema confirms that a table exists for every model, for services
+// whose migrations are run by an external tool
+func (m *DatabaseManager) VerifySchema(ctx context.Context, models ...interface{}) error {
+	db := m.DB()
+	if db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	migrator := db.WithContext(ctx).Migrator()
+	for _, model := range models {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table for %T does not exist", model)
+		}
+	}
+
+	m.migrated.Store(true)
+	return nil
+}
+
+// migrateModels migrates each model in its own transaction. Postgres DDL is
+// transactional, so a model's migration is either fully applied or fully
+// rolled back, never half-applied. When ctx is cancelled, e.g. by SIGTERM,
+// the migration in progress is allowed DATABASE_MIGRATION_GRACE_PERIOD to
+// finish before it is rolled back, and the remaining models are left for
+// the next start.
+func (m *DatabaseManager) migrateModels(ctx context.Context, db *gorm.DB, models []interface{}) error {
+	for i, model := range models {
+		if ctx.Err() != nil {
+			m.logger.Warnf("Migrations stopped by shutdown after %d of %d models; the rest will run on next start", i, len(models))
+			return ctx.Err()
+		}
+
+		if err := m.migrateModel(ctx, db, model); err != nil {
+			if ctx.Err() != nil {
+				m.logger.WithError(err).Warnf("Migration of %T rolled back on shutdown after %d of %d models; the rest will run on next start", model, i, len(models))
+				return fmt.Errorf("migration of %T rolled back: %w", model, ctx.Err())
+			}
+			return fmt.Errorf("failed to migrate %T: %w", model, err)
+		}
+	}
+	return nil
+}
+
+// migrateModel migrates one model in a transaction that outlives ctx by the
+// grace period
+func (m *DatabaseManager) migrateModel(ctx context.Context, db *gorm.DB, model interface{}) error {
+	migrateCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	stop := context.AfterFunc(ctx, func() {
+		m.logger.Warnf("Shutdown requested, finishing migration of %T", model)
+		grace := time.AfterFunc(m.config.DatabaseMigrationGracePeriod, cancel)
+		context.AfterFunc(migrateCtx, func() { grace.Stop() })
+	})
+	defer stop()
+
+	return db.WithContext(migrateCtx).Transaction(func(tx *gorm.DB) error {
+		return tx.AutoMigrate(model)
+	})
+}
+
+// migrationLockKey derives the advisory lock key from the service name, so
+// replicas of one service serialize their migrations without blocking other
+// services sharing the database server.
+func migrationLockKey(serviceName string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("migrations:" + serviceName))
+	return int64(h.Sum64())
+}
+
+// withMigrationLock runs fn while holding a Postgres session-level advisory
+// lock. Replicas starting together wait here for the first one to finish, then
+// run fn themselves against the already-migrated schema, which is a no-op.
+// Without advisory lock support fn runs unlocked, so replicas starting
+// together may migrate concurrently.
+func (m *DatabaseManager) withMigrationLock(ctx context.Context, sqlDB *sql.DB, fn func() error) error {
+	if !m.capabilities.AdvisoryLocks {
+		m.logger.Warn("Running migrations without the migration lock")
+		return fn()
+	}
+
+	// Advisory locks belong to a session, so pin one connection for the
+	// lock and unlock. Migrations themselves may use any connection.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migration lock: %w", err)
+	}
+	defer conn.Close()
+
+	key := migrationLockKey(m.config.ServiceName)
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	if !acquired {
+		m.logger.Info("Waiting for another replica to finish migrations")
+		start := time.Now()
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		m.logger.Infof("Acquired migration lock after %s", time.Since(start).Round(time.Millisecond))
+	}
+
+	defer func() {
+		// Unlock even if ctx was cancelled during migration
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", key); err != nil {
+			m.logger.WithError(err).Warn("Failed to release migration lock, discarding connection")
+			// Closing the session is the only other way to release the lock
+			_ = conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+	}()
+
+	return fn()
+}
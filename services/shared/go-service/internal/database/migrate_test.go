@@ -0,0 +1,364 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/config"
+)
+
+// advisoryLockServer imitates Postgres session-level advisory locks for the
+// connections opened through it, which sqlmock can't share between sessions
+type advisoryLockServer struct {
+	mu      sync.Mutex
+	holders map[int64]*advisoryLockConn
+	// released is closed and replaced whenever a lock is released
+	released chan struct{}
+	// waiting receives the key each time a session starts waiting for a lock
+	waiting chan int64
+}
+
+func newAdvisoryLockServer() *advisoryLockServer {
+	return &advisoryLockServer{
+		holders:  make(map[int64]*advisoryLockConn),
+		released: make(chan struct{}),
+		waiting:  make(chan int64, 8),
+	}
+}
+
+func (s *advisoryLockServer) Connect(context.Context) (driver.Conn, error) {
+	return &advisoryLockConn{server: s}, nil
+}
+
+func (s *advisoryLockServer) Driver() driver.Driver { return s }
+
+func (s *advisoryLockServer) Open(string) (driver.Conn, error) {
+	return &advisoryLockConn{server: s}, nil
+}
+
+func (s *advisoryLockServer) tryLock(c *advisoryLockConn, key int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if holder := s.holders[key]; holder != nil && holder != c {
+		return false
+	}
+	s.holders[key] = c
+	return true
+}
+
+func (s *advisoryLockServer) lock(ctx context.Context, c *advisoryLockConn, key int64) error {
+	for {
+		s.mu.Lock()
+		released := s.released
+		s.mu.Unlock()
+
+		if s.tryLock(c, key) {
+			return nil
+		}
+		s.waiting <- key
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (s *advisoryLockServer) unlock(c *advisoryLockConn, key int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.holders[key] == c {
+		delete(s.holders, key)
+		close(s.released)
+		s.released = make(chan struct{})
+	}
+}
+
+// held reports whether any session holds the lock on key
+func (s *advisoryLockServer) held(key int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.holders[key] != nil
+}
+
+// advisoryLockConn is one session of an advisoryLockServer. Closing it
+// releases its locks, as ending a Postgres session does.
+type advisoryLockConn struct {
+	server *advisoryLockServer
+}
+
+func (c *advisoryLockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements not supported")
+}
+
+func (c *advisoryLockConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions not supported")
+}
+
+func (c *advisoryLockConn) Close() error {
+	c.server.mu.Lock()
+	var keys []int64
+	for key, holder := range c.server.holders {
+		if holder == c {
+			keys = append(keys, key)
+		}
+	}
+	c.server.mu.Unlock()
+
+	for _, key := range keys {
+		c.server.unlock(c, key)
+	}
+	return nil
+}
+
+func (c *advisoryLockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if !strings.Contains(query, "pg_try_advisory_lock") {
+		return nil, errors.New("unexpected query: " + query)
+	}
+	return &boolRow{value: c.server.tryLock(c, args[0].Value.(int64))}, nil
+}
+
+func (c *advisoryLockConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	switch {
+	case strings.Contains(query, "pg_advisory_unlock"):
+		c.server.unlock(c, args[0].Value.(int64))
+	case strings.Contains(query, "pg_advisory_lock"):
+		if err := c.server.lock(ctx, c, args[0].Value.(int64)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unexpected statement: " + query)
+	}
+	return driver.ResultNoRows, nil
+}
+
+// boolRow is a single-row, single-column boolean result
+type boolRow struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRow) Columns() []string { return []string{"acquired"} }
+func (r *boolRow) Close() error      { return nil }
+
+func (r *boolRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+// newLockingManager returns a manager migrating under an advisory lock taken
+// on server
+func newLockingManager(t *testing.T, server *advisoryLockServer) (*DatabaseManager, *sql.DB) {
+	t.Helper()
+	sqlDB := sql.OpenDB(server)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	m := &DatabaseManager{
+		config:       newTestConfig(t, nil),
+		logger:       discardLogger(),
+		capabilities: Capabilities{AdvisoryLocks: true},
+	}
+	return m, sqlDB
+}
+
+// waitForLockWaiter waits until a session is blocked on the lock
+func waitForLockWaiter(t *testing.T, server *advisoryLockServer) {
+	t.Helper()
+	select {
+	case <-server.waiting:
+	case <-time.After(5 * time.Second):
+		t.Fatal("second migrator didn't wait for the migration lock")
+	}
+}
+
+func TestMigrationLockSerializesConcurrentMigrators(t *testing.T) {
+	server := newAdvisoryLockServer()
+	first, firstDB := newLockingManager(t, server)
+	second, secondDB := newLockingManager(t, server)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	firstDone := make(chan error, 1)
+	go func() {
+		firstDone <- first.withMigrationLock(ctx, firstDB, func() error {
+			close(holding)
+			<-release
+			record("first")
+			return nil
+		})
+	}()
+	<-holding
+
+	secondDone := make(chan error, 1)
+	go func() {
+		secondDone <- second.withMigrationLock(ctx, secondDB, func() error {
+			record("second")
+			return nil
+		})
+	}()
+
+	// The second migrator must block on the lock rather than migrate
+	// alongside the first
+	waitForLockWaiter(t, server)
+	mu.Lock()
+	if len(order) != 0 {
+		t.Errorf("migrations ran while the lock was held: %v", order)
+	}
+	mu.Unlock()
+
+	close(release)
+	if err := <-firstDone; err != nil {
+		t.Errorf("first migrator: %v", err)
+	}
+	if err := <-secondDone; err != nil {
+		t.Errorf("second migrator: %v", err)
+	}
+
+	if strings.Join(order, ",") != "first,second" {
+		t.Errorf("migration order = %v, want first then second", order)
+	}
+	if server.held(migrationLockKey(first.config.ServiceName)) {
+		t.Error("migration lock still held after both migrators finished")
+	}
+}
+
+func TestMigrationLockWaitBoundedByContext(t *testing.T) {
+	server := newAdvisoryLockServer()
+	first, firstDB := newLockingManager(t, server)
+	second, secondDB := newLockingManager(t, server)
+
+	holding := make(chan struct{})
+	release := make(chan struct{})
+	firstDone := make(chan error, 1)
+	go func() {
+		firstDone <- first.withMigrationLock(context.Background(), firstDB, func() error {
+			close(holding)
+			<-release
+			return nil
+		})
+	}()
+	<-holding
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	secondDone := make(chan error, 1)
+	ran := false
+	go func() {
+		secondDone <- second.withMigrationLock(ctx, secondDB, func() error {
+			ran = true
+			return nil
+		})
+	}()
+
+	waitForLockWaiter(t, server)
+	cancel()
+	if err := <-secondDone; !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+	if ran {
+		t.Error("migrations ran without the lock")
+	}
+
+	close(release)
+	if err := <-firstDone; err != nil {
+		t.Errorf("first migrator: %v", err)
+	}
+}
+
+// Models migrated by TestAutoMigrateCancelled
+type (
+	migrateFirst struct {
+		ID string `gorm:"primaryKey"`
+	}
+	migrateSecond struct {
+		ID string `gorm:"primaryKey"`
+	}
+)
+
+func TestAutoMigrateCancelled(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace time.Duration
+		// createDelay is how long creating the first table takes
+		createDelay time.Duration
+		committed   bool
+	}{
+		{"finishes within the grace period", time.Minute, 0, true},
+		{"rolled back after the grace period", 10 * time.Millisecond, time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newMockManager(t, func(cfg *config.Config) {
+				cfg.DatabaseMigrationGracePeriod = tt.grace
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Shutdown arrives as the first table is being created
+			err := m.DB().Callback().Raw().Before("gorm:raw").Register("test:cancel", func(db *gorm.DB) {
+				if strings.Contains(db.Statement.SQL.String(), `CREATE TABLE "migrate_firsts"`) {
+					cancel()
+				}
+			})
+			if err != nil {
+				t.Fatalf("Register: %v", err)
+			}
+
+			mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+			mock.ExpectBegin()
+			create := mock.ExpectExec(`CREATE TABLE "migrate_firsts"`).WillDelayFor(tt.createDelay).WillReturnResult(sqlmock.NewResult(0, 0))
+			if tt.committed {
+				mock.ExpectCommit()
+			} else {
+				// database/sql rolls back a transaction whose context ends in
+				// the background, racing the unlock
+				mock.MatchExpectationsInOrder(false)
+				create.WillReturnError(context.Canceled)
+				mock.ExpectRollback()
+			}
+			// The second model is left for the next start
+			mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+
+			err = m.AutoMigrateContext(ctx, &migrateFirst{}, &migrateSecond{})
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("err = %v, want context.Canceled", err)
+			}
+			if m.Migrated() {
+				t.Error("Migrated after an interrupted migration")
+			}
+			// The background rollback may still be on its way
+			deadline := time.Now().Add(time.Second)
+			for mock.ExpectationsWereMet() != nil && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	applogger "{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/reqctx"
+)
+
+const slowQueryThreshold = 200 * time.Millisecond
+
+// Query returns a session bound to ctx, so every query issued through it is
+// cancelled with the request and inherits its deadline. Each statement is
+// further bounded by DATABASE_QUERY_TIMEOUT (see registerQueryTimeout). SQL
+// is logged through the request's logger (see serviceLogger). Prefer Query
+// over DB for all request-scoped work.
+// If the manager isn't initialized, every operation on the returned session
+// fails with an error instead of panicking.
+func (m *DatabaseManager) Query(ctx context.Context) *gorm.DB {
+	db := m.DB()
+	if db == nil {
+		return unavailable(fmt.Errorf("database not initialized"))
+	}
+	return db.WithContext(ctx).Set(queryTimeoutKey, m.config.DatabaseQueryTimeout)
+}
+
+// unavailableDB is the handle sessions returned by unavailable are created
+// from, opened once on first use
+var unavailableDB = sync.OnceValues(func() (*gorm.DB, error) {
+	return gorm.Open(postgres.New(postgres.Config{Conn: unavailableConn{errors.New("database unavailable")}}), &gorm.Config{
+		Logger:               logger.Discard,
+		DisableAutomaticPing: true,
+	})
+})
+
+// unavailable returns a session whose every operation fails with err. GORM
+// skips statements on a session that already has an error, so the
+// connection is never used.
+func unavailable(err error) *gorm.DB {
+	db, openErr := unavailableDB()
+	if openErr != nil {
+		err = errors.Join(err, openErr)
+	}
+	session := db.Session(&gorm.Session{NewDB: true})
+	_ = session.AddError(err)
+	return session
+}
+
+// unavailableConn is a gorm.ConnPool failing every statement
+type unavailableConn struct {
+	err error
+}
+
+func (c unavailableConn) PrepareContext(context.Context, string) (*sql.Stmt, error) {
+	return nil, c.err
+}
+
+func (c unavailableConn) ExecContext(context.Context, string, ...interface{}) (sql.Result, error) {
+	return nil, c.err
+}
+
+func (c unavailableConn) QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error) {
+	return nil, c.err
+}
+
+func (c unavailableConn) QueryRowContext(context.Context, string, ...interface{}) *sql.Row {
+	return nil
+}
+
+// logLevel is the GORM log level for queries not flagged for debugging,
+// following LOG_LEVEL: every statement at debug, failed and slow statements
+// at info and warn, and failed statements only at error
+func (m *DatabaseManager) logLevel() logger.LogLevel {
+	switch m.config.LogLevel {
+	case "trace", "debug":
+		return logger.Info
+	case "error", "fatal", "panic":
+		return logger.Error
+	default:
+		return logger.Warn
+	}
+}
+
+// serviceLogger is a GORM logger writing through the service logger. Each
+// statement is logged with the logger found in its context
+// (db.Statement.Context) by logger.FromContext, so SQL logs carry the
+// request's fields. Queries without one fall back to the base logger, tagged
+// with the request ID if ctx has one.
+type serviceLogger struct {
+	base  applogger.Logger
+	level logger.LogLevel
+}
+
+func newServiceLogger(base applogger.Logger) logger.Interface {
+	return &serviceLogger{base: base, level: logger.Warn}
+}
+
+// logFor returns the logger for statements run with ctx
+func (l *serviceLogger) logFor(ctx context.Context) applogger.Logger {
+	if ctx != nil {
+		if requestLog, ok := applogger.FromContext(ctx); ok {
+			return requestLog
+		}
+		if requestID := reqctx.RequestID(ctx); requestID != "" {
+			return l.base.WithRequestID(requestID)
+		}
+	}
+	return l.base
+}
+
+func (l *serviceLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return &serviceLogger{base: l.base, level: level}
+}
+
+func (l *serviceLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.logFor(ctx).Infof(msg, data...)
+	}
+}
+
+func (l *serviceLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.logFor(ctx).Warnf(msg, data...)
+	}
+}
+
+func (l *serviceLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.logFor(ctx).Errorf(msg, data...)
+	}
+}
+
+// Trace logs a statement: failed statements at error level, statements
+// slower than slowQueryThreshold at warn level, and all others at info level
+// when the session logs SQL. Statements stopped because the request was
+// cancelled are logged at debug level, and those that ran out of time at
+// info level, rather than as errors: neither is a database fault, and
+// clients disconnecting would otherwise flood error logs. Record not found
+// isn't an error here; callers handle it.
+func (l *serviceLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	slow := elapsed > slowQueryThreshold
+	if !(failed && l.level >= logger.Error) && !(slow && l.level >= logger.Warn) && l.level < logger.Info {
+		return
+	}
+
+	sql, rows := fc()
+	log := l.logFor(ctx).WithFields(map[string]interface{}{
+		"duration_ms": elapsed.Milliseconds(),
+		"rows":        rows,
+	})
+
+	switch {
+	case failed && stoppedByContext(ctx, err):
+		if errors.Is(err, context.DeadlineExceeded) || (ctx != nil && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			log.WithError(err).Infof("Query timed out: %s", sql)
+			return
+		}
+		log.WithError(err).Debugf("Query cancelled: %s", sql)
+	case failed && l.level >= logger.Error:
+		log.WithError(err).Errorf("Query failed: %s", sql)
+	case slow && l.level >= logger.Warn:
+		log.Warnf("Slow query (over %s): %s", slowQueryThreshold, sql)
+	case l.level >= logger.Info:
+		log.Infof("Query: %s", sql)
+	}
+}
+
+// stoppedByContext reports whether a statement failed because its context
+// was cancelled or its deadline passed. The driver doesn't always wrap the
+// context's error, so a done context counts too; statement budgets aren't
+// visible in ctx by then, so releaseQueryBudget adds their error to err.
+func stoppedByContext(ctx context.Context, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return ctx != nil && ctx.Err() != nil
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm/logger"
+
+	"{{ module_name }}/internal/config"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	m, mock := newMockManager(t, func(cfg *config.Config) {
+		cfg.DatabaseQueryTimeout = 20 * time.Millisecond
+	})
+
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillDelayFor(time.Minute).
+		WillReturnRows(sqlmock.NewRows(userColumns))
+
+	start := time.Now()
+	err := m.Query(context.Background()).First(&User{}, "id = ?", "u1").Error
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query stopped after %s, want DATABASE_QUERY_TIMEOUT", elapsed)
+	}
+}
+
+func TestQueryWithinTimeout(t *testing.T) {
+	m, mock := newMockManager(t, func(cfg *config.Config) {
+		cfg.DatabaseQueryTimeout = time.Minute
+	})
+
+	now := time.Now()
+	mock.ExpectQuery(`SELECT \* FROM "users"`).
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow("u1", "alice@example.com", "Alice", "hash", true, now, now, nil))
+
+	var user User
+	if err := m.Query(context.Background()).First(&user, "id = ?", "u1").Error; err != nil {
+		t.Fatalf("First: %v", err)
+	}
+	if user.ID != "u1" {
+		t.Errorf("user = %+v, want u1", user)
+	}
+}
+
+func TestQueryUninitialized(t *testing.T) {
+	m := &DatabaseManager{config: newTestConfig(t, nil), logger: discardLogger()}
+
+	err := m.Query(context.Background()).First(&User{}).Error
+	if err == nil || err.Error() != "database not initialized" {
+		t.Errorf("err = %v, want database not initialized", err)
+	}
+
+	// Sessions share one handle but not their errors
+	first, second := unavailable(errors.New("first")), unavailable(errors.New("second"))
+	if first.Statement.ConnPool != second.Statement.ConnPool {
+		t.Error("unavailable opened a new handle per call")
+	}
+	if first.Error.Error() != "first" || second.Error.Error() != "second" {
+		t.Errorf("errors = %v, %v; want first, second", first.Error, second.Error)
+	}
+}
+
+// userColumns are the columns of the users table
+var userColumns = []string{"id", "email", "name", "password_hash", "is_verified", "created_at", "updated_at", "deactivated_at"}
+
+func TestLogLevel(t *testing.T) {
+	tests := []struct {
+		logLevel string
+		want     logger.LogLevel
+	}{
+		{"debug", logger.Info},
+		{"info", logger.Warn},
+		{"warn", logger.Warn},
+		{"error", logger.Error},
+		{"", logger.Warn},
+	}
+
+	for _, tt := range tests {
+		m := &DatabaseManager{config: &config.Config{LogLevel: tt.logLevel}}
+		if got := m.logLevel(); got != tt.want {
+			t.Errorf("LOG_LEVEL=%q: GORM level = %v, want %v", tt.logLevel, got, tt.want)
+		}
+	}
+}
+
+func TestQueryErrorsLoggedOutsideDebug(t *testing.T) {
+	m, mock, buf := newLoggingMockManager(t, nil)
+
+	tests := []struct {
+		name  string
+		query func() error
+		level string
+		msg   string
+	}{
+		{
+			name: "failed",
+			query: func() error {
+				mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnError(errors.New("connection reset"))
+				return m.Query(context.Background()).First(&User{}, "id = ?", "u1").Error
+			},
+			level: "error",
+			msg:   "Query failed",
+		},
+		{
+			name: "cancelled",
+			query: func() error {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return m.Query(ctx).First(&User{}, "id = ?", "u1").Error
+			},
+			level: "debug",
+			msg:   "Query cancelled",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			if err := tt.query(); err == nil {
+				t.Fatal("query succeeded")
+			}
+
+			var entry map[string]interface{}
+			if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+				t.Fatalf("want a single log entry, got %q: %v", buf.String(), err)
+			}
+			if msg, _ := entry["msg"].(string); entry["level"] != tt.level || !strings.HasPrefix(msg, tt.msg) {
+				t.Errorf("logged %v, want a %s entry %q", entry, tt.level, tt.msg)
+			}
+		})
+	}
+}
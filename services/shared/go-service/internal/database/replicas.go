@@ -0,0 +1,105 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// Read preferences accepted in DATABASE_READ_PREFERENCE
+const (
+	// ReadNearest sends reads to replicas in the local region, or to any
+	// replica when none are local
+	ReadNearest = "nearest"
+	// ReadPrimaryPreferred sends reads to replicas in the local region, or
+	// to the primary when none are local, rather than across regions
+	ReadPrimaryPreferred = "primary-preferred"
+)
+
+// Replica is a read replica, optionally tagged with the region it runs in
+type Replica struct {
+	Region string
+	DSN    string
+}
+
+// ParseReplicas parses DATABASE_REPLICA_URLS entries, each a postgres:// URL
+// optionally prefixed with its region, e.g. "eu-west-1=postgres://replica-eu/app".
+// Keyword DSNs ("host=... dbname=...") aren't accepted since they couldn't
+// be told apart from a region prefix.
+func ParseReplicas(entries []string) ([]Replica, error) {
+	replicas := make([]Replica, 0, len(entries))
+	for i, entry := range entries {
+		replica := Replica{DSN: entry}
+		// A URL's query string also contains "=", but never before "://"
+		if region, dsn, ok := strings.Cut(entry, "="); ok && !strings.ContainsAny(region, ":/ ") {
+			replica = Replica{Region: region, DSN: dsn}
+		}
+		if !strings.Contains(replica.DSN, "://") {
+			// The entry itself may hold credentials, so it isn't echoed
+			return nil, fmt.Errorf("replica %d is not a postgres:// URL", i+1)
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+// SelectReplicas returns the replicas reads should use from region under
+// preference. An empty result sends reads to the primary.
+func SelectReplicas(replicas []Replica, region, preference string) []Replica {
+	var local []Replica
+	for _, replica := range replicas {
+		if region != "" && replica.Region == region {
+			local = append(local, replica)
+		}
+	}
+	if len(local) > 0 {
+		return local
+	}
+
+	if preference == ReadPrimaryPreferred {
+		return nil
+	}
+	return replicas
+}
+
+// registerReplicas routes reads to the replicas selected for the configured
+// region through the dbresolver plugin. Writes, transactions and queries
+// using dbresolver.Write stay on the primary.
+func (m *DatabaseManager) registerReplicas(db *gorm.DB) error {
+	replicas, err := ParseReplicas(m.config.DatabaseReplicaURLs)
+	if err != nil {
+		return fmt.Errorf("invalid DATABASE_REPLICA_URLS: %w", err)
+	}
+	if len(replicas) == 0 {
+		return nil
+	}
+
+	selected := SelectReplicas(replicas, m.config.DatabaseRegion, m.config.DatabaseReadPreference)
+	log := m.logger.WithFields(map[string]interface{}{
+		"region":          m.config.DatabaseRegion,
+		"read_preference": m.config.DatabaseReadPreference,
+		"replicas":        len(replicas),
+		"selected":        len(selected),
+	})
+	if len(selected) == 0 {
+		log.Warn("No read replica in the local region, reading from the primary")
+		return nil
+	}
+
+	dialectors := make([]gorm.Dialector, 0, len(selected))
+	for _, replica := range selected {
+		dialectors = append(dialectors, postgres.Open(replica.DSN))
+	}
+	if err := db.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	})); err != nil {
+		return fmt.Errorf("failed to register read replicas: %w", err)
+	}
+
+	log.Info("Routing reads to read replicas")
+	return nil
+}
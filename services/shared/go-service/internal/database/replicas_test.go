@@ -0,0 +1,68 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseReplicas(t *testing.T) {
+	untagged := Replica{DSN: "postgres://replica/app"}
+	tagged := Replica{Region: "eu-west-1", DSN: "postgres://replica-eu/app"}
+	withQuery := Replica{DSN: "postgres://replica/app?sslmode=require"}
+	taggedWithQuery := Replica{Region: "us-east-1", DSN: "postgres://replica/app?sslmode=require"}
+
+	tests := []struct {
+		name    string
+		entries []string
+		want    []Replica
+		wantErr bool
+	}{
+		{"none", nil, []Replica{}, false},
+		{"untagged", []string{"postgres://replica/app"}, []Replica{untagged}, false},
+		{"region prefix", []string{"eu-west-1=postgres://replica-eu/app"}, []Replica{tagged}, false},
+		{"query string without region", []string{"postgres://replica/app?sslmode=require"}, []Replica{withQuery}, false},
+		{"query string with region", []string{"us-east-1=postgres://replica/app?sslmode=require"}, []Replica{taggedWithQuery}, false},
+		{"keyword DSN", []string{"host=replica dbname=app"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReplicas(tt.entries)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Fatalf("err = %v, want error %t", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseReplicas = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectReplicas(t *testing.T) {
+	eu := Replica{Region: "eu", DSN: "postgres://eu/app"}
+	us := Replica{Region: "us", DSN: "postgres://us/app"}
+	untagged := Replica{DSN: "postgres://any/app"}
+	replicas := []Replica{eu, us, untagged}
+
+	tests := []struct {
+		name       string
+		region     string
+		preference string
+		want       []Replica
+	}{
+		{"local region", "eu", ReadNearest, []Replica{eu}},
+		{"local region, primary preferred", "eu", ReadPrimaryPreferred, []Replica{eu}},
+		{"no local replica", "ap", ReadNearest, replicas},
+		{"no local replica, primary preferred", "ap", ReadPrimaryPreferred, nil},
+		{"no region", "", ReadNearest, replicas},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectReplicas(replicas, tt.region, tt.preference)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectReplicas = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLSTATE codes Postgres uses for transactions aborted by concurrency
+// control, which succeed when retried from the start
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+const defaultTxRetryBackoff = 50 * time.Millisecond
+
+// IsRetryable reports whether err is a serialization failure or deadlock,
+// in which case the whole transaction can safely be run again
+func IsRetryable(err error) bool {
+	// pgconn.PgError exposes its code through SQLState, which avoids
+	// depending on the driver here
+	var pgErr interface{ SQLState() string }
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	switch pgErr.SQLState() {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// WithTransactionRetry runs fn in a transaction like WithTransaction, and
+// runs the whole transaction again when it fails with a serialization
+// failure or deadlock. It retries up to DATABASE_TX_MAX_RETRIES times,
+// sleeping DATABASE_TX_RETRY_BACKOFF (doubled per attempt, with jitter)
+// in between. fn may therefore run more than once and must not have side
+// effects outside the transaction.
+func (m *DatabaseManager) WithTransactionRetry(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	maxRetries := m.config.DatabaseTxMaxRetries
+	backoff := m.config.DatabaseTxRetryBackoff
+	if backoff <= 0 {
+		backoff = defaultTxRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := m.WithTransaction(ctx, fn)
+		if err == nil || !IsRetryable(err) {
+			return err
+		}
+		if attempt >= maxRetries {
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt+1, err)
+		}
+
+		// Jitter keeps the conflicting transactions from retrying in lockstep
+		delay := backoff<<attempt + time.Duration(rand.Int63n(int64(backoff)))
+		m.logger.Debugf("Retrying transaction in %s after attempt %d failed: %v", delay, attempt+1, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("transaction retry cancelled: %w", errors.Join(ctx.Err(), err))
+		case <-timer.C:
+		}
+	}
+}
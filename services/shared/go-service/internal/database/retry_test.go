@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/config"
+)
+
+// sqlStateError stands in for pgconn.PgError, which IsRetryable only
+// inspects through SQLState
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "ERROR: SQLSTATE " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"serialization failure", sqlStateError("40001"), true},
+		{"deadlock", sqlStateError("40P01"), true},
+		{"wrapped", fmt.Errorf("update stock: %w", sqlStateError("40001")), true},
+		{"unique violation", sqlStateError("23505"), false},
+		{"plain error", errors.New("40001"), false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTransactionRetry(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		failures []error
+		wantRuns int
+		wantErr  string
+		// minimum time spent backing off, backoff doubled per retry
+		minElapsed time.Duration
+	}{
+		{
+			name:       "retried until it succeeds",
+			failures:   []error{sqlStateError("40001"), sqlStateError("40P01")},
+			wantRuns:   3,
+			minElapsed: backoff + 2*backoff,
+		},
+		{
+			name:       "gives up after the retry limit",
+			failures:   []error{sqlStateError("40001"), sqlStateError("40001"), sqlStateError("40001")},
+			wantRuns:   3,
+			wantErr:    "transaction failed after 3 attempts",
+			minElapsed: backoff + 2*backoff,
+		},
+		{
+			name:     "other errors not retried",
+			failures: []error{sqlStateError("23505")},
+			wantRuns: 1,
+			wantErr:  "SQLSTATE 23505",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newMockManager(t, func(cfg *config.Config) {
+				cfg.DatabaseTxMaxRetries = 2
+				cfg.DatabaseTxRetryBackoff = backoff
+			})
+			for i := 0; i < tt.wantRuns; i++ {
+				mock.ExpectBegin()
+				exec := mock.ExpectExec(`UPDATE stock`)
+				if i < len(tt.failures) {
+					exec.WillReturnError(tt.failures[i])
+					mock.ExpectRollback()
+				} else {
+					exec.WillReturnResult(driver.ResultNoRows)
+					mock.ExpectCommit()
+				}
+			}
+
+			runs := 0
+			start := time.Now()
+			err := m.WithTransactionRetry(context.Background(), func(tx *gorm.DB) error {
+				runs++
+				return tx.Exec("UPDATE stock SET count = count - 1").Error
+			})
+			elapsed := time.Since(start)
+
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("WithTransactionRetry: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if runs != tt.wantRuns {
+				t.Errorf("fn ran %d times, want %d", runs, tt.wantRuns)
+			}
+			if elapsed < tt.minElapsed {
+				t.Errorf("took %v, want at least %v of backoff", elapsed, tt.minElapsed)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestWithTransactionRetryCancelled(t *testing.T) {
+	m, mock := newMockManager(t, func(cfg *config.Config) {
+		cfg.DatabaseTxMaxRetries = 3
+		cfg.DatabaseTxRetryBackoff = time.Minute
+	})
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE stock`).WillReturnError(sqlStateError("40001"))
+	mock.ExpectRollback()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := m.WithTransactionRetry(ctx, func(tx *gorm.DB) error {
+		return tx.Exec("UPDATE stock SET count = count - 1").Error
+	})
+
+	if !errors.Is(err, context.DeadlineExceeded) || !IsRetryable(err) {
+		t.Errorf("err = %v, want the deadline joined with the last failure", err)
+	}
+}
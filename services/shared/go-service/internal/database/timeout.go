@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+const (
+	// queryTimeoutKey is the session setting holding the per-statement budget
+	queryTimeoutKey = "service:query_timeout"
+	// queryBudgetKey is the statement instance setting holding its budget
+	queryBudgetKey = "service:query_budget"
+)
+
+// queryBudget is the context a statement ran under before its budget was
+// applied, the budgeted context, and the function releasing the budget
+type queryBudget struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// registerQueryTimeout bounds each statement of sessions created by Query to
+// DATABASE_QUERY_TIMEOUT, clamped to the time left before the request's
+// deadline. Statements run through DB, such as migrations, are unbounded.
+// Row and Rows aren't bounded either, since their results are read after
+// the statement's callbacks have returned.
+func registerQueryTimeout(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("service:budget_create", startQueryBudget),
+		callbacks.Create().After("*").Register("service:release_create", releaseQueryBudget),
+		callbacks.Query().Before("*").Register("service:budget_query", startQueryBudget),
+		callbacks.Query().After("*").Register("service:release_query", releaseQueryBudget),
+		callbacks.Update().Before("*").Register("service:budget_update", startQueryBudget),
+		callbacks.Update().After("*").Register("service:release_update", releaseQueryBudget),
+		callbacks.Delete().Before("*").Register("service:budget_delete", startQueryBudget),
+		callbacks.Delete().After("*").Register("service:release_delete", releaseQueryBudget),
+		callbacks.Raw().Before("*").Register("service:budget_raw", startQueryBudget),
+		callbacks.Raw().After("*").Register("service:release_raw", releaseQueryBudget),
+	)
+}
+
+// startQueryBudget runs first for a statement. The budget covers the
+// implicit transaction GORM wraps writes in, which is committed before
+// releaseQueryBudget runs.
+func startQueryBudget(db *gorm.DB) {
+	value, ok := db.Get(queryTimeoutKey)
+	if !ok {
+		return
+	}
+	timeout, _ := value.(time.Duration)
+	if timeout <= 0 {
+		return
+	}
+
+	parent := db.Statement.Context
+	ctx, cancel := reqctx.WithBudget(parent, timeout)
+	db.Statement.Context = ctx
+	db.InstanceSet(queryBudgetKey, queryBudget{parent: parent, ctx: ctx, cancel: cancel})
+}
+
+// releaseQueryBudget runs last for a statement, before the logger traces
+// it. It restores the parent context, so a statement stopped by its budget
+// has the budget's error added to its own: drivers don't always wrap it, and
+// Trace would otherwise only see the parent, which is still live, and log a
+// timeout as a database fault.
+func releaseQueryBudget(db *gorm.DB) {
+	value, ok := db.InstanceGet(queryBudgetKey)
+	if !ok {
+		return
+	}
+	budget := value.(queryBudget)
+	if ctxErr := budget.ctx.Err(); ctxErr != nil && db.Error != nil && !errors.Is(db.Error, ctxErr) {
+		db.Error = fmt.Errorf("%w: %w", db.Error, ctxErr)
+	}
+	budget.cancel()
+	db.Statement.Context = budget.parent
+}
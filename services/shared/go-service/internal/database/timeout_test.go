@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/middleware"
+)
+
+func TestReleaseQueryBudget(t *testing.T) {
+	driverErr := errors.New("canceling statement due to user request")
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		err       error
+		wantErr   error
+		wantTimed bool
+	}{
+		{"within budget", time.Minute, nil, nil, false},
+		{"failed within budget", time.Minute, driverErr, driverErr, false},
+		{"stopped by budget", time.Millisecond, driverErr, driverErr, true},
+		{"budget error already wrapped", time.Millisecond, context.DeadlineExceeded, context.DeadlineExceeded, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			db := &gorm.DB{Config: &gorm.Config{}, Statement: &gorm.Statement{Context: parent}}
+			db.Statement.Settings.Store(queryTimeoutKey, tt.timeout)
+
+			startQueryBudget(db)
+			if tt.wantTimed {
+				<-db.Statement.Context.Done()
+			}
+			db.Error = tt.err
+			releaseQueryBudget(db)
+
+			if db.Statement.Context != parent {
+				t.Error("statement context wasn't restored to the parent")
+			}
+			if !errors.Is(db.Error, tt.wantErr) || (tt.wantErr == nil && db.Error != nil) {
+				t.Errorf("err = %v, want %v", db.Error, tt.wantErr)
+			}
+			if stopped := db.Error != nil && stoppedByContext(db.Statement.Context, db.Error); stopped != tt.wantTimed {
+				t.Errorf("stoppedByContext = %t, want %t (err: %v)", stopped, tt.wantTimed, db.Error)
+			}
+			if tt.wantTimed && !errors.Is(db.Error, context.DeadlineExceeded) {
+				t.Errorf("err = %v, want it to wrap context.DeadlineExceeded", db.Error)
+			}
+		})
+	}
+}
+
+func TestQueryBudgetClampedToRequest(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	db := &gorm.DB{Config: &gorm.Config{}, Statement: &gorm.Statement{Context: parent}}
+	db.Statement.Settings.Store(queryTimeoutKey, time.Minute)
+
+	startQueryBudget(db)
+	deadline, ok := db.Statement.Context.Deadline()
+	if remaining := time.Until(deadline); !ok || remaining > 2*time.Second || remaining < time.Second {
+		t.Errorf("statement budget = %s, want the request's remaining 2s", remaining)
+	}
+	releaseQueryBudget(db)
+}
+
+func TestRequestTimeoutCancelsQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m, mock := newMockManager(t, func(cfg *config.Config) { cfg.DatabaseQueryTimeout = time.Minute })
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillDelayFor(2 * time.Second).WillReturnRows(sqlmock.NewRows(userColumns))
+
+	const budget = 100 * time.Millisecond
+	var queryErr error
+	router := gin.New()
+	router.Use(Bind(m), middleware.RequestTimeout(budget))
+	router.GET("/slow", func(c *gin.Context) {
+		var users []User
+		queryErr = For(c).Find(&users).Error
+		c.Status(http.StatusOK)
+	})
+
+	start := time.Now()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	elapsed := time.Since(start)
+
+	if !errors.Is(queryErr, context.DeadlineExceeded) {
+		t.Errorf("query error = %v, want it to wrap context.DeadlineExceeded", queryErr)
+	}
+	if elapsed >= time.Second {
+		t.Errorf("request took %v, want the query stopped at the %v budget", elapsed, budget)
+	}
+}
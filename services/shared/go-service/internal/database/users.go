@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"{{ module_name }}/internal/ctxcache"
+)
+
+// ErrNotFound is returned when a looked up record doesn't exist
+var ErrNotFound = errors.New("record not found")
+
+// User is a registered user account
+type User struct {
+	ID           string `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	Name         string
+	PasswordHash string
+	IsVerified   bool
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+
+	// DeactivatedAt is set when the account is deactivated; such users are
+	// rejected by LoadUser even with a valid token
+	DeactivatedAt *time.Time
+}
+
+// Active reports whether the account hasn't been deactivated
+func (u *User) Active() bool {
+	return u.DeactivatedAt == nil
+}
+
+// GetUserByID looks up a user by ID. Lookups are cached for the rest of the
+// request, so middleware and handlers can fetch the same user freely.
+func (m *DatabaseManager) GetUserByID(ctx context.Context, id string) (*User, error) {
+	return ctxcache.GetOrLoad(ctx, "user:id:"+id, func() (*User, error) {
+		return m.findUser(ctx, "id = ?", id)
+	})
+}
+
+// GetUserByEmail looks up a user by email, cached like GetUserByID
+func (m *DatabaseManager) GetUserByEmail(ctx context.Context, email string) (*User, error) {
+	return ctxcache.GetOrLoad(ctx, "user:email:"+email, func() (*User, error) {
+		return m.findUser(ctx, "email = ?", email)
+	})
+}
+
+// CreateUser inserts user. A taken email is reported as ErrDuplicate by the
+// unique index on email rather than checked beforehand, so of concurrent
+// registrations for one email exactly one succeeds.
+func (m *DatabaseManager) CreateUser(ctx context.Context, user *User) error {
+	if err := m.Query(ctx).Create(user).Error; err != nil {
+		if IsUniqueViolation(err) {
+			return fmt.Errorf("%w: %w", ErrDuplicate, err)
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	return nil
+}
+
+func (m *DatabaseManager) findUser(ctx context.Context, query string, arg interface{}) (*User, error) {
+	var user User
+	if err := m.Query(ctx).Where(query, arg).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+	return &user, nil
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WarmUp opens n pool connections up front, so the first requests don't
+// wait for connections to be established. n is capped at the pool's idle
+// limit, beyond which connections would be closed again right away.
+func (m *DatabaseManager) WarmUp(ctx context.Context, n int) error {
+	sqlDB, err := m.DB().DB()
+	if err != nil {
+		return fmt.Errorf("failed to get database instance: %w", err)
+	}
+
+	n = min(n, maxIdleConns)
+
+	// Hold every connection until all are open, otherwise the pool would
+	// hand back the same idle one each time. Closing returns them to the pool.
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to open connection %d of %d: %w", i+1, n, err)
+		}
+		conns = append(conns, conn)
+
+		if err := conn.PingContext(ctx); err != nil {
+			return fmt.Errorf("failed to ping connection %d of %d: %w", i+1, n, err)
+		}
+	}
+	return nil
+}
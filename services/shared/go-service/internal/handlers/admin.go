@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/middleware"
+	"{{ module_name }}/internal/response"
+)
+
+// UpdateRuntimeSettingsRequest changes runtime settings. Omitted fields keep
+// their current value; an empty cors_origins list denies all origins.
+type UpdateRuntimeSettingsRequest struct {
+	RateLimit   *int      `json:"rate_limit"`
+	CORSOrigins *[]string `json:"cors_origins"`
+}
+
+// GetConfig handler returns the effective configuration with secrets
+// redacted. The boot report returned by info, if set, is included under
+// AppInfo, so what was initialized shows alongside the settings behind it.
+func GetConfig(cfg *config.Config, info func() interface{}) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		out := cfg.Redacted()
+		if info != nil {
+			out["AppInfo"] = info()
+		}
+		response.JSON(c, http.StatusOK, out)
+	}
+}
+
+// GetRuntimeSettings handler
+func GetRuntimeSettings(runtime *config.Runtime) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.JSON(c, http.StatusOK, runtime.Load())
+	}
+}
+
+// UpdateRuntimeSettings handler. Every change is audit-logged with the
+// previous and updated values.
+func UpdateRuntimeSettings(runtime *config.Runtime, log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateRuntimeSettingsRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, apperror.InvalidArgument("Invalid request body").WithDetails(err.Error()))
+			return
+		}
+
+		previous := runtime.Load()
+		updated := previous
+		if req.RateLimit != nil {
+			updated.RateLimit = *req.RateLimit
+		}
+		if req.CORSOrigins != nil {
+			updated.CORSOrigins = *req.CORSOrigins
+		}
+
+		if err := runtime.Store(updated); err != nil {
+			response.Error(c, apperror.InvalidArgument("Invalid runtime settings").WithDetails(err.Error()))
+			return
+		}
+
+		log.WithFields(map[string]interface{}{
+			"audit":               true,
+			"action":              "runtime_settings.update",
+			logger.FieldRequestID: c.GetString("request_id"),
+			"client_ip":           c.ClientIP(),
+			"previous":            previous,
+			"updated":             updated,
+		}).Warn("Runtime settings updated")
+
+		response.JSON(c, http.StatusOK, updated)
+	}
+}
+
+// GetCaptures handler returns the most recently captured requests
+func GetCaptures(buffer *middleware.CaptureBuffer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.JSON(c, http.StatusOK, gin.H{
+			"captures": buffer.Recent(),
+		})
+	}
+}
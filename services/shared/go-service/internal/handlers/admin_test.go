@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/middleware"
+)
+
+func TestUpdateRuntimeSettings(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+	runtime := config.NewRuntime(&config.Config{RateLimit: 1})
+
+	router := gin.New()
+	router.Use(middleware.CORS(runtime), middleware.RateLimit(runtime, log, nil))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.PUT("/admin/settings", UpdateRuntimeSettings(runtime, log))
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	// Admin requests are rate limited too, so they come from another client
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/admin/settings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.RemoteAddr = "10.0.0.2:1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The origin isn't allowed and the second request is over the limit
+	if w := get(); w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("first request = %d, origin %q; want 200 without CORS", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+	if w := get(); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second request = %d, want 429", w.Code)
+	}
+
+	if w := put(`{"rate_limit": 100, "cors_origins": ["https://app.example.com"]}`); w.Code != http.StatusOK {
+		t.Fatalf("PUT /admin/settings = %d: %s", w.Code, w.Body)
+	}
+
+	w := get()
+	if w.Code != http.StatusOK {
+		t.Errorf("request after raising the limit = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the newly allowed origin", got)
+	}
+
+	// Invalid settings are rejected and leave the current ones in place
+	if w := put(`{"rate_limit": -1}`); w.Code != http.StatusBadRequest {
+		t.Errorf("PUT with a negative rate limit = %d, want 400", w.Code)
+	}
+	if got := runtime.Load().RateLimit; got != 100 {
+		t.Errorf("RateLimit = %d after a rejected update, want 100", got)
+	}
+}
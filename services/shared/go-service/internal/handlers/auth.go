@@ -7,21 +7,35 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 
+	"{{ module_name }}/internal/apperror"
 	"{{ module_name }}/internal/config"
 	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/middleware"
+	"{{ module_name }}/internal/password"
+	"{{ module_name }}/internal/response"
 	{{- if include_database }}
+
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
 	"{{ module_name }}/internal/database"
 	{{- endif }}
 )
 
+var usersRegistered = metrics.NewCounter("users_registered_total", "Total number of registered users")
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Email string `json:"email" binding:"required,email"`
+	// Strength is checked against the password policy
+	Password string `json:"password" binding:"required,max=128"`
 	Name     string `json:"name" binding:"required"`
 }
 
@@ -41,146 +55,132 @@ type User struct {
 func Login(cfg *config.Config, log logger.Logger{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req LoginRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request body",
-				"details": err.Error(),
-			})
+		if err := bindJSON(c, cfg, &req); err != nil {
+			response.Error(c, err)
 			return
 		}
 
-		// TODO: Implement actual authentication logic
-		// For production, implement:
-		// 1. Hash password verification
-		// 2. Database user lookup
-		// 3. Rate limiting
-		// 4. Account lockout policies
-		// 5. Multi-factor authentication
+		// TODO: Harden authentication
+		// For production, also implement:
+		// 1. Rate limiting
+		// 2. Account lockout policies
+		// 3. Multi-factor authentication
 
 		{{- if include_database }}
-		// Database authentication example:
-		// user, err := dbManager.GetUserByEmail(req.Email)
-		// if err != nil {
-		//     log.Errorf("Database error: %v", err)
-		//     c.JSON(http.StatusInternalServerError, gin.H{"error": "Authentication service unavailable"})
-		//     return
-		// }
-		// if user == nil || !verifyPassword(req.Password, user.PasswordHash) {
-		//     c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
-		//     return
-		// }
+		user, err := dbManager.GetUserByEmail(c.Request.Context(), req.Email)
+		if err != nil && !errors.Is(err, database.ErrNotFound) {
+			err = apperror.Wrap(c.Request.Context(), "handlers.Login", apperror.Internal("Authentication service unavailable").WithCause(err))
+			log.Errorf("%+v", err)
+			response.Error(c, err)
+			return
+		}
+		if user == nil || !user.Active() || !password.Verify(user.PasswordHash, req.Password) {
+			response.Error(c, apperror.Unauthenticated("Invalid credentials"))
+			return
+		}
+		profile := User{ID: user.ID, Email: user.Email, Name: user.Name}
 		{{- else }}
 		// Mock authentication - replace with real implementation
-		{{- endif }}
-
-		// For now, this is a mock implementation
 		if req.Email != "admin@example.com" || req.Password != "password" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid credentials",
-			})
+			response.Error(c, apperror.Unauthenticated("Invalid credentials"))
 			return
 		}
+		profile := User{ID: "1", Email: req.Email, Name: "Admin User"}
+		{{- endif }}
 
 		// Generate JWT token
-		token, expiresAt, err := generateToken(cfg.JWTSecret, "1", req.Email)
+		token, expiresAt, err := generateToken(cfg.JWTSecret, profile.ID, profile.Email)
 		if err != nil {
-			log.Errorf("Failed to generate token: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to generate token",
-			})
+			err = apperror.Wrap(c.Request.Context(), "handlers.Login", apperror.Internal("Failed to generate token").WithCause(err))
+			log.Errorf("%+v", err)
+			response.Error(c, err)
 			return
 		}
 
-		user := User{
-			ID:    "1",
-			Email: req.Email,
-			Name:  "Admin User",
-		}
-
-		c.JSON(http.StatusOK, AuthResponse{
+		response.JSON(c, http.StatusOK, AuthResponse{
 			Token:     token,
 			ExpiresAt: expiresAt,
-			User:      user,
+			User:      profile,
 		})
 	}
 }
 
-// Register handler
-func Register(cfg *config.Config, log logger.Logger{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}) gin.HandlerFunc {
+// Register handler. The password must satisfy passwords.
+func Register(cfg *config.Config, log logger.Logger, passwords *password.Policy{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req RegisterRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request body",
-				"details": err.Error(),
-			})
+		if err := bindJSON(c, cfg, &req); err != nil {
+			response.Error(c, err)
 			return
 		}
 
-		// TODO: Implement actual user registration logic
-		// For production, implement:
-		// 1. Email validation and uniqueness check
-		// 2. Password strength validation
-		// 3. Password hashing (bcrypt, argon2)
-		// 4. Email verification workflow
-		// 5. User profile creation
-		// 6. Terms of service acceptance
+		if err := checkPassword(c, passwords, req.Password); err != nil {
+			response.Error(c, err)
+			return
+		}
+
+		// TODO: Complete user registration
+		// For production, also implement:
+		// 1. Email verification workflow
+		// 2. Terms of service acceptance
 
 		{{- if include_database }}
-		// Database registration example:
-		// // Validate email uniqueness
-		// existingUser, _ := dbManager.GetUserByEmail(req.Email)
-		// if existingUser != nil {
-		//     c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
-		//     return
-		// }
-		//
-		// // Hash password
-		// hashedPassword, err := hashPassword(req.Password)
-		// if err != nil {
-		//     log.Errorf("Password hashing failed: %v", err)
-		//     c.JSON(http.StatusInternalServerError, gin.H{"error": "Registration failed"})
-		//     return
-		// }
-		//
-		// // Create user
-		// newUser := &User{
-		//     Email:    req.Email,
-		//     Name:     req.Name,
-		//     PasswordHash: hashedPassword,
-		//     CreatedAt:    time.Now(),
-		//     IsVerified:   false,
-		// }
-		//
-		// err = dbManager.CreateUser(newUser)
-		// if err != nil {
-		//     log.Errorf("User creation failed: %v", err)
-		//     c.JSON(http.StatusInternalServerError, gin.H{"error": "Registration failed"})
-		//     return
-		// }
+		// Only the password's hash is stored
+		hash, err := password.Hash(req.Password)
+		if err != nil {
+			if errors.Is(err, password.ErrTooLong) {
+				tooLong := FieldError{Field: "password", Rule: "max_bytes", Message: fmt.Sprintf("must be at most %d bytes long", password.MaxBytes)}
+				response.Error(c, apperror.InvalidArgument("Password does not meet the password policy").WithDetails(gin.H{"fields": []FieldError{tooLong}}))
+				return
+			}
+			err = apperror.Wrap(c.Request.Context(), "handlers.Register", apperror.Internal("Registration failed").WithCause(err))
+			log.Errorf("%+v", err)
+			response.Error(c, err)
+			return
+		}
+
+		// Don't look the email up first: two concurrent registrations could
+		// both find it free. The unique index on email lets exactly one
+		// insert succeed; AppError maps the other's ErrDuplicate to 409
+		// Conflict.
+		newUser := &database.User{
+			ID:           uuid.NewString(),
+			Email:        req.Email,
+			Name:         req.Name,
+			PasswordHash: hash,
+		}
+		if err := dbManager.CreateUser(c.Request.Context(), newUser); err != nil {
+			err = apperror.Wrap(c.Request.Context(), "handlers.Register", database.AppError(err, "User"))
+			if !errors.Is(err, database.ErrDuplicate) {
+				log.Errorf("%+v", err)
+			}
+			response.Error(c, err)
+			return
+		}
+		userID := newUser.ID
 		{{- else }}
 		// Mock registration - replace with real implementation
+		userID := "2"
 		{{- endif }}
 
-		// For now, this is a mock implementation
-
 		// Generate JWT token
-		token, expiresAt, err := generateToken(cfg.JWTSecret, "2", req.Email)
+		token, expiresAt, err := generateToken(cfg.JWTSecret, userID, req.Email)
 		if err != nil {
-			log.Errorf("Failed to generate token: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to generate token",
-			})
+			err = apperror.Wrap(c.Request.Context(), "handlers.Register", apperror.Internal("Failed to generate token").WithCause(err))
+			log.Errorf("%+v", err)
+			response.Error(c, err)
 			return
 		}
 
+		usersRegistered.Inc()
+
 		user := User{
-			ID:    "2",
+			ID:    userID,
 			Email: req.Email,
 			Name:  req.Name,
 		}
 
-		c.JSON(http.StatusCreated, AuthResponse{
+		response.JSON(c, http.StatusCreated, AuthResponse{
 			Token:     token,
 			ExpiresAt: expiresAt,
 			User:      user,
@@ -188,6 +188,23 @@ func Register(cfg *config.Config, log logger.Logger{{- if include_database }}, d
 	}
 }
 
+// checkPassword applies the password policy to a new password, reporting
+// every violated rule against the password field. Register is the only
+// handler setting passwords so far; call it from any handler added for
+// password changes or resets.
+func checkPassword(c *gin.Context, passwords *password.Policy, pw string) error {
+	violations := passwords.Validate(c.Request.Context(), pw)
+	if len(violations) == 0 {
+		return nil
+	}
+
+	fields := make([]FieldError, 0, len(violations))
+	for _, v := range violations {
+		fields = append(fields, FieldError{Field: "password", Rule: v.Rule, Message: v.Message})
+	}
+	return apperror.InvalidArgument("Password does not meet the password policy").WithDetails(gin.H{"fields": fields})
+}
+
 // RefreshToken handler
 func RefreshToken(cfg *config.Config, log logger.Logger{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -204,32 +221,27 @@ func RefreshToken(cfg *config.Config, log logger.Logger{{- if include_database }
 			RefreshToken string `json:"refresh_token" binding:"required"`
 		}
 
-		if err := c.ShouldBindJSON(&req); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Invalid request body",
-				"details": err.Error(),
-			})
+		if err := bindJSON(c, cfg, &req); err != nil {
+			response.Error(c, err)
 			return
 		}
 
 		// Validate refresh token
-		claims, err := parseToken(req.RefreshToken, cfg.JWTSecret)
+		claims, err := parseToken(req.RefreshToken, cfg)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid refresh token",
-			})
+			response.Error(c, apperror.Unauthenticated("Invalid refresh token"))
 			return
 		}
 
 		{{- if include_database }}
 		// Verify user still exists in database
-		// user, err := dbManager.GetUserByID(claims.UserID)
+		// user, err := dbManager.GetUserByID(c.Request.Context(), claims.UserID)
 		// if err != nil || user == nil {
-		//     c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		//     response.Error(c, apperror.Unauthenticated("User not found"))
 		//     return
 		// }
 		// if !user.IsActive {
-		//     c.JSON(http.StatusUnauthorized, gin.H{"error": "Account deactivated"})
+		//     response.Error(c, apperror.Unauthenticated("Account deactivated"))
 		//     return
 		// }
 		{{- endif }}
@@ -237,14 +249,13 @@ func RefreshToken(cfg *config.Config, log logger.Logger{{- if include_database }
 		// Generate new access token
 		newToken, expiresAt, err := generateToken(cfg.JWTSecret, claims.UserID, claims.Email)
 		if err != nil {
-			log.Errorf("Failed to generate new token: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to refresh token",
-			})
+			err = apperror.Wrap(c.Request.Context(), "handlers.RefreshToken", apperror.Internal("Failed to refresh token").WithCause(err))
+			log.Errorf("%+v", err)
+			response.Error(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{
+		response.JSON(c, http.StatusOK, gin.H{
 			"token": newToken,
 			"expires_at": expiresAt,
 		})
@@ -252,64 +263,55 @@ func RefreshToken(cfg *config.Config, log logger.Logger{{- if include_database }
 }
 
 // GetProfile handler
-func GetProfile(log logger.Logger{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}) gin.HandlerFunc {
+{{- if include_database }}. It must run after database.LoadUser, which has
+// rejected deleted and deactivated accounts and cached the user, so it
+// doesn't query again.
+{{- endif }}
+func GetProfile(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		userID := c.GetString("user_id")
-		email := c.GetString("email")
-
-		// TODO: Fetch user from database
-		// For production, implement:
-		// 1. Fetch complete user profile from database
-		// 2. Handle user not found scenarios
-		// 3. Return appropriate user fields
-		// 4. Implement field selection/filtering
-		// 5. Add caching for frequently accessed profiles
-
 		{{- if include_database }}
-		// Database implementation example:
-		// user, err := dbManager.GetUserByID(userID)
-		// if err != nil {
-		//     log.Errorf("Failed to fetch user profile: %v", err)
-		//     c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch profile"})
-		//     return
-		// }
-		// if user == nil {
-		//     c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
-		//     return
-		// }
-		//
-		// // Return user profile (exclude sensitive fields)
-		// profile := User{
-		//     ID:        user.ID,
-		//     Email:     user.Email,
-		//     Name:      user.Name,
-		//     CreatedAt: user.CreatedAt,
-		//     UpdatedAt: user.UpdatedAt,
-		//     // Don't include PasswordHash, sensitive data
-		// }
-		//
-		// c.JSON(http.StatusOK, profile)
+		user, err := database.CurrentUser(c)
+		if err != nil {
+			err = apperror.Wrap(c.Request.Context(), "handlers.GetProfile", database.AppError(err, "User"))
+			if !errors.Is(err, database.ErrNotFound) {
+				log.Errorf("%+v", err)
+			}
+			response.Error(c, err)
+			return
+		}
+
+		// Sensitive fields such as PasswordHash are left out
+		response.JSON(c, http.StatusOK, User{
+			ID:    user.ID,
+			Email: user.Email,
+			Name:  user.Name,
+		})
 		{{- else }}
 		// Mock profile - replace with real implementation
 		user := User{
-			ID:    userID,
-			Email: email,
+			ID:    c.GetString("user_id"),
+			Email: c.GetString("email"),
 			Name:  "User Name",
 		}
 
-		c.JSON(http.StatusOK, user)
+		response.JSON(c, http.StatusOK, user)
 		{{- endif }}
 	}
 }
 
 func generateToken(secret, userID, email string) (string, int64, error) {
-	expiresAt := time.Now().Add(24 * time.Hour).Unix()
-
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     expiresAt,
-		"iat":     time.Now().Unix(),
+	now := time.Now()
+	expiresAt := now.Add(24 * time.Hour)
+
+	// All timestamps are encoded as NumericDate (seconds since the epoch, UTC)
+	claims := TokenClaims{
+		UserID: userID,
+		Email:  email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -318,7 +320,7 @@ func generateToken(secret, userID, email string) (string, int64, error) {
 		return "", 0, err
 	}
 
-	return tokenString, expiresAt, nil
+	return tokenString, claims.ExpiresAt.Unix(), nil
 }
 
 // TokenClaims represents the claims in our JWT token
@@ -328,10 +330,11 @@ type TokenClaims struct {
 	jwt.RegisteredClaims
 }
 
-func parseToken(tokenString, secret string) (*TokenClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// parseToken verifies a token signed with the current or a previous secret,
+// allowing JWT_LEEWAY of clock skew
+func parseToken(tokenString string, cfg *config.Config) (*TokenClaims, error) {
+	keyfunc := middleware.JWTKeyfunc(cfg.JWTSecret, cfg.JWTPreviousSecrets...)
+	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, keyfunc, jwt.WithIssuedAt(), jwt.WithLeeway(cfg.JWTLeeway))
 
 	if err != nil {
 		return nil, err
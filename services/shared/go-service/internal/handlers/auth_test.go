@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/middleware"
+	"{{ module_name }}/internal/password"
+	{{- if include_database }}
+
+	"context"
+	"database/sql/driver"
+	"sync"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+
+	"{{ module_name }}/internal/database"
+	{{- endif }}
+)
+
+// newAuthTestConfig loads the configuration and a logger discarding output
+func newAuthTestConfig(t *testing.T) (*config.Config, logger.Logger) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	return cfg, logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+}
+
+// postJSON serves a POST of body to path on router
+func postJSON(router http.Handler, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterRejectsWeakPassword(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+	policy := password.NewPolicy(password.Policy{MinLength: 12})
+
+	router := gin.New()
+	router.POST("/register", Register(cfg, log, policy{{- if include_database }}, nil{{- endif }}))
+
+	w := postJSON(router, "/register", `{"email":"alice@example.com","password":"short","name":"Alice"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", w.Code, w.Body)
+	}
+}
+
+func TestLoginInvalidBodyCarriesRequestID(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+
+	router := gin.New()
+	router.Use(middleware.RequestID())
+	router.POST("/login", Login(cfg, log{{- if include_database }}, nil{{- endif }}))
+
+	tests := []struct {
+		name      string
+		body      string
+		requestID string
+	}{
+		{"malformed JSON", `{"email":`, "req-login-1"},
+		{"failed validation", `{"email":"not-an-email","password":"secret-password"}`, "req-login-2"},
+		{"empty body", ``, "req-login-3"},
+		{"generated request ID", `{"email":`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.requestID != "" {
+				req.Header.Set("X-Request-ID", tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", w.Code, w.Body)
+			}
+			var body struct {
+				Code      string `json:"code"`
+				RequestID string `json:"request_id"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode %s: %v", w.Body, err)
+			}
+			want := w.Header().Get("X-Request-ID")
+			if tt.requestID != "" && want != tt.requestID {
+				t.Errorf("X-Request-ID = %q, want the client's %q", want, tt.requestID)
+			}
+			if want == "" || body.RequestID != want {
+				t.Errorf("request_id = %q, want %q from X-Request-ID", body.RequestID, want)
+			}
+			if body.Code != "invalid_argument" {
+				t.Errorf("code = %q, want invalid_argument", body.Code)
+			}
+		})
+	}
+}
+
+func TestTokenTimeZones(t *testing.T) {
+	cfg, _ := newAuthTestConfig(t)
+	cfg.JWTLeeway = 0
+
+	local := time.Local
+	t.Cleanup(func() { time.Local = local })
+
+	// Issued far east of UTC and verified far west of it
+	time.Local = time.FixedZone("UTC+14", 14*60*60)
+	token, expiresAt, err := generateToken(cfg.JWTSecret, "user-1", "alice@example.com")
+	if err != nil {
+		t.Fatalf("generateToken: %v", err)
+	}
+	time.Local = time.FixedZone("UTC-12", -12*60*60)
+
+	claims, err := parseToken(token, cfg)
+	if err != nil {
+		t.Fatalf("parseToken across time zones: %v", err)
+	}
+	if claims.ExpiresAt.Unix() != expiresAt {
+		t.Errorf("exp = %d, want the returned expiry %d", claims.ExpiresAt.Unix(), expiresAt)
+	}
+	if lifetime := claims.ExpiresAt.Sub(claims.IssuedAt.Time); lifetime != 24*time.Hour {
+		t.Errorf("lifetime = %s, want 24h", lifetime)
+	}
+
+	// Timestamps are encoded as seconds since the epoch, not zoned strings
+	payload, err := base64.RawURLEncoding.DecodeString(strings.Split(token, ".")[1])
+	if err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		t.Fatalf("unmarshal payload: %v", err)
+	}
+	for _, claim := range []string{"iat", "nbf", "exp"} {
+		if _, ok := raw[claim].(float64); !ok {
+			t.Errorf("%s = %v, want a NumericDate", claim, raw[claim])
+		}
+	}
+}
+
+func TestParseTokenLeeway(t *testing.T) {
+	cfg, _ := newAuthTestConfig(t)
+
+	expired := func(ago time.Duration) string {
+		now := time.Now()
+		claims := TokenClaims{
+			UserID: "user-1",
+			RegisteredClaims: jwt.RegisteredClaims{
+				IssuedAt:  jwt.NewNumericDate(now.Add(-time.Hour)),
+				ExpiresAt: jwt.NewNumericDate(now.Add(-ago)),
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(cfg.JWTSecret))
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return token
+	}
+
+	cfg.JWTLeeway = 30 * time.Second
+	if _, err := parseToken(expired(10*time.Second), cfg); err != nil {
+		t.Errorf("token expired within the leeway rejected: %v", err)
+	}
+	if _, err := parseToken(expired(time.Minute), cfg); err == nil {
+		t.Error("token expired beyond the leeway accepted")
+	}
+
+	cfg.JWTLeeway = 0
+	if _, err := parseToken(expired(10*time.Second), cfg); err == nil {
+		t.Error("expired token accepted without leeway")
+	}
+}
+{{- if include_database }}
+
+// uniqueViolation is the error Postgres reports for a duplicate key
+type uniqueViolation struct{}
+
+func (uniqueViolation) Error() string    { return "duplicate key value violates unique constraint" }
+func (uniqueViolation) SQLState() string { return "23505" }
+
+// newMockDatabase returns a manager backed by sqlmock, past the capability
+// detection run when it connects
+func newMockDatabase(t *testing.T, cfg *config.Config, log logger.Logger) (*database.DatabaseManager, sqlmock.Sqlmock) {
+	t.Helper()
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	mock.ExpectQuery(`SELECT pg_try_advisory_lock`).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(true))
+	mock.ExpectExec(`SELECT pg_advisory_unlock`).WillReturnResult(driver.ResultNoRows)
+	mock.ExpectQuery(`SELECT extname FROM pg_extension`).WillReturnRows(sqlmock.NewRows([]string{"extname"}))
+
+	m, err := database.NewManager(context.Background(), postgres.New(postgres.Config{Conn: conn}), cfg, log)
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	return m, mock
+}
+
+func TestRegisterConcurrentDuplicateEmail(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+	m, mock := newMockDatabase(t, cfg, log)
+	policy := password.NewPolicy(password.Policy{MinLength: 12})
+
+	// The unique index on email lets the first insert through and rejects
+	// the second, whichever request gets there first
+	mock.MatchExpectationsInOrder(false)
+	mock.ExpectBegin()
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO "users"`).WillReturnError(uniqueViolation{})
+	mock.ExpectCommit()
+	mock.ExpectRollback()
+
+	router := gin.New()
+	router.POST("/register", Register(cfg, log, policy, m))
+
+	const body = `{"email":"alice@example.com","password":"correct-Horse-42","name":"Alice"}`
+	var wg sync.WaitGroup
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- postJSON(router, "/register", body).Code
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+
+	counts := make(map[int]int)
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusCreated] != 1 || counts[http.StatusConflict] != 1 {
+		t.Errorf("statuses = %v, want one 201 and one 409", counts)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// userColumns are the columns of the users table
+var userColumns = []string{"id", "email", "name", "password_hash", "is_verified", "created_at", "updated_at", "deactivated_at"}
+
+// expectUserLookup expects one lookup of the user with id, returning row or,
+// when row is nil, no rows
+func expectUserLookup(mock sqlmock.Sqlmock, id string, row []driver.Value) {
+	rows := sqlmock.NewRows(userColumns)
+	if row != nil {
+		rows.AddRow(row...)
+	}
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE id = \$1`).WithArgs(id).WillReturnRows(rows)
+}
+
+// newProfileRouter serves GetProfile as the protected routes do, for
+// requests authenticated as userID
+func newProfileRouter(m *database.DatabaseManager, log logger.Logger, userID string, handlers ...gin.HandlerFunc) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.RequestCache(), database.Bind(m), func(c *gin.Context) {
+		c.Set("user_id", userID)
+	}, database.LoadUser(m))
+	router.GET("/profile", append(handlers, GetProfile(log))...)
+	return router
+}
+
+func TestGetProfileLoadsUserOnce(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+	m, mock := newMockDatabase(t, cfg, log)
+
+	now := time.Now()
+	expectUserLookup(mock, "u1", []driver.Value{"u1", "alice@example.com", "Alice", "hash", true, now, now, nil})
+
+	// Another handler of the route reads the user as well
+	var shared *database.User
+	router := newProfileRouter(m, log, "u1", func(c *gin.Context) {
+		user, err := database.CurrentUser(c)
+		if err != nil {
+			t.Errorf("CurrentUser: %v", err)
+		}
+		shared = user
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "alice@example.com") {
+		t.Fatalf("profile = %d: %s", w.Code, w.Body)
+	}
+	if strings.Contains(w.Body.String(), "hash") {
+		t.Errorf("profile exposes the password hash: %s", w.Body)
+	}
+	if shared == nil || shared.ID != "u1" {
+		t.Errorf("handler saw user %+v, want u1", shared)
+	}
+
+	// A second lookup would have been an unexpected query
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestGetProfileRejectsRemovedUser(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+	now := time.Now()
+
+	tests := []struct {
+		name string
+		row  []driver.Value
+	}{
+		{"deleted", nil},
+		{"deactivated", []driver.Value{"u1", "alice@example.com", "Alice", "hash", true, now, now, now}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newMockDatabase(t, cfg, log)
+			expectUserLookup(mock, "u1", tt.row)
+
+			called := false
+			router := newProfileRouter(m, log, "u1", func(c *gin.Context) { called = true })
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile", nil))
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("status = %d, want 401: %s", w.Code, w.Body)
+			}
+			if called {
+				t.Error("route handlers ran for a removed user")
+			}
+		})
+	}
+}
+
+func TestLoginVerifiesStoredPassword(t *testing.T) {
+	cfg, log := newAuthTestConfig(t)
+	hash, err := password.Hash("correct-Horse-42")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		password string
+		row      []driver.Value
+		want     int
+	}{
+		{"correct password", "correct-Horse-42", []driver.Value{"u1", "alice@example.com", "Alice", hash, true, now, now, nil}, http.StatusOK},
+		{"wrong password", "wrong-Horse-42", []driver.Value{"u1", "alice@example.com", "Alice", hash, true, now, now, nil}, http.StatusUnauthorized},
+		{"deactivated", "correct-Horse-42", []driver.Value{"u1", "alice@example.com", "Alice", hash, true, now, now, now}, http.StatusUnauthorized},
+		{"unknown email", "correct-Horse-42", nil, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, mock := newMockDatabase(t, cfg, log)
+			rows := sqlmock.NewRows(userColumns)
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			mock.ExpectQuery(`SELECT \* FROM "users" WHERE email = \$1`).WithArgs("alice@example.com").WillReturnRows(rows)
+
+			router := gin.New()
+			router.Use(middleware.RequestCache())
+			router.POST("/login", Login(cfg, log, m))
+
+			w := postJSON(router, "/login", `{"email":"alice@example.com","password":"`+tt.password+`"}`)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+{{- endif }}
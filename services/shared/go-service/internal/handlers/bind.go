@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/patch"
+)
+
+const unknownFieldPrefix = "json: unknown field "
+
+// FieldError describes a single invalid request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// Report fields by the name clients use rather than the Go field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+
+		// Validate patch.Optional fields by their value; absent and null
+		// fields validate as nil, so pair rules with omitempty. The
+		// validator matches exact types, so only these instantiations are
+		// validated: binding rules on any other Optional[T] are ignored
+		// until T is added here.
+		v.RegisterCustomTypeFunc(patch.ValidationValue,
+			patch.Optional[string]{}, patch.Optional[int]{}, patch.Optional[int64]{},
+			patch.Optional[float64]{}, patch.Optional[bool]{}, patch.Optional[time.Time]{})
+	}
+}
+
+// bindJSON decodes and validates the request body into obj. In strict mode,
+// enabled globally via config or per route with middleware.StrictJSON,
+// fields not present on obj are rejected instead of silently ignored.
+func bindJSON(c *gin.Context, cfg *config.Config, obj interface{}) error {
+	if err := decodeJSON(c, cfg, obj); err != nil {
+		return err
+	}
+	return validate("Invalid request body", obj)
+}
+
+// bindQuery binds and validates query parameters into obj's form-tagged fields
+func bindQuery(c *gin.Context, obj interface{}) error {
+	if err := binding.MapFormWithTag(obj, c.Request.URL.Query(), "form"); err != nil {
+		return invalidRequest("Invalid query parameters", err)
+	}
+	return validate("Invalid query parameters", obj)
+}
+
+// bindURI binds and validates path parameters into obj's uri-tagged fields
+func bindURI(c *gin.Context, obj interface{}) error {
+	if err := mapURI(c, obj); err != nil {
+		return err
+	}
+	return validate("Invalid path parameters", obj)
+}
+
+// bindRequest binds path parameters, query parameters and, when present, the
+// JSON body into obj, then validates it once. Each field is read from the
+// source matching its uri, form or json tag.
+func bindRequest(c *gin.Context, cfg *config.Config, obj interface{}) error {
+	if err := mapURI(c, obj); err != nil {
+		return err
+	}
+	if err := binding.MapFormWithTag(obj, c.Request.URL.Query(), "form"); err != nil {
+		return invalidRequest("Invalid query parameters", err)
+	}
+	if c.Request.Body != nil && c.Request.Body != http.NoBody && c.Request.ContentLength != 0 {
+		if err := decodeJSON(c, cfg, obj); err != nil {
+			return err
+		}
+	}
+	return validate("Invalid request", obj)
+}
+
+func mapURI(c *gin.Context, obj interface{}) error {
+	params := make(map[string][]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.Key] = []string{param.Value}
+	}
+	if err := binding.MapFormWithTag(obj, params, "uri"); err != nil {
+		return invalidRequest("Invalid path parameters", err)
+	}
+	return nil
+}
+
+func decodeJSON(c *gin.Context, cfg *config.Config, obj interface{}) error {
+	if c.Request.Body == nil {
+		return apperror.InvalidArgument("Invalid request body").WithDetails("empty body")
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	if cfg.StrictJSONBinding || c.GetBool("strict_json") {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(obj); err != nil {
+		if field, ok := unknownField(err); ok {
+			unknown := FieldError{Field: field, Rule: "unknown", Message: "is not a known field"}
+			return apperror.InvalidArgument("Invalid request body").WithDetails(gin.H{
+				"fields": []FieldError{unknown},
+			})
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return apperror.PayloadTooLarge("Request body too large").
+				WithDetails(fmt.Sprintf("limit is %d bytes", tooLarge.Limit))
+		}
+		if decodeErr := jsonDecodeError(err); decodeErr != nil {
+			return decodeErr
+		}
+		return invalidRequest("Invalid request body", err)
+	}
+	return nil
+}
+
+func validate(message string, obj interface{}) error {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return invalidRequest(message, err)
+	}
+	return nil
+}
+
+// invalidRequest translates binding and validation errors into an
+// invalid_argument error. Validation failures are reported per field.
+func invalidRequest(message string, err error) *apperror.Error {
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return apperror.InvalidArgument(message).WithDetails(err.Error())
+	}
+
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fe := range validationErrs {
+		fields = append(fields, FieldError{
+			Field:   fe.Field(),
+			Rule:    fe.Tag(),
+			Message: fieldMessage(fe),
+		})
+	}
+	return apperror.InvalidArgument(message).WithDetails(gin.H{"fields": fields})
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max":
+		if fe.Kind() == reflect.String {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	default:
+		return fmt.Sprintf("failed %s validation", fe.Tag())
+	}
+}
+
+// jsonDecodeError explains malformed JSON and values of the wrong type in
+// terms of the request rather than Go types, or returns nil for other errors
+func jsonDecodeError(err error) *apperror.Error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return apperror.InvalidArgument("Malformed JSON in request body").
+			WithDetails(fmt.Sprintf("%s at byte %d", syntaxErr.Error(), syntaxErr.Offset))
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return apperror.InvalidArgument("Malformed JSON in request body").
+			WithDetails("unexpected end of body")
+	}
+	if errors.Is(err, io.EOF) {
+		return apperror.InvalidArgument("Invalid request body").WithDetails("empty body")
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		field := typeErr.Field
+		if field == "" {
+			field = "body"
+		}
+		mismatch := FieldError{
+			Field:   field,
+			Rule:    "type",
+			Message: fmt.Sprintf("must be %s, got %s", jsonTypeName(typeErr.Type), typeErr.Value),
+		}
+		return apperror.InvalidArgument("Invalid request body").WithDetails(gin.H{
+			"fields": []FieldError{mismatch},
+		})
+	}
+
+	return nil
+}
+
+// jsonTypeName names the JSON type a Go type is decoded from
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "an RFC 3339 timestamp string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	default:
+		return "a " + t.String()
+	}
+}
+
+// unknownField extracts the field name from encoding/json's unknown field error
+func unknownField(err error) (string, bool) {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, unknownFieldPrefix) {
+		return "", false
+	}
+	field, unquoteErr := strconv.Unquote(strings.TrimPrefix(msg, unknownFieldPrefix))
+	if unquoteErr != nil {
+		return "", false
+	}
+	return field, true
+}
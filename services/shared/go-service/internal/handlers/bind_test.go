@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/middleware"
+	"{{ module_name }}/internal/patch"
+)
+
+func TestValidateOptional(t *testing.T) {
+	type request struct {
+		Name patch.Optional[string] `json:"name" binding:"omitempty,min=3"`
+	}
+
+	tests := []struct {
+		name    string
+		field   patch.Optional[string]
+		wantErr bool
+	}{
+		{"absent", patch.Optional[string]{}, false},
+		{"null", patch.Null[string](), false},
+		{"valid", patch.Some("alice"), false},
+		{"too short", patch.Some("al"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate("Invalid request body", &request{Name: tt.field})
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("validate error = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONDecodeError(t *testing.T) {
+	type payload struct {
+		Age     int       `json:"age"`
+		Tags    []string  `json:"tags"`
+		Started time.Time `json:"started"`
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		wantMessage string
+		wantDetails string
+	}{
+		{"syntax", `{"age": 1,}`, "Malformed JSON in request body", "at byte 11"},
+		{"truncated", `{"age": 1`, "Malformed JSON in request body", "unexpected end of body"},
+		{"empty", ``, "Invalid request body", "empty body"},
+		{"string for integer", `{"age": "ten"}`, "Invalid request body", "age must be an integer, got string"},
+		{"object for array", `{"tags": {}}`, "Invalid request body", "tags must be an array, got object"},
+		{"number for timestamp", `{"started": 5}`, "Invalid request body", "started must be an RFC 3339 timestamp string, got number"},
+		{"number for body", `5`, "Invalid request body", "body must be an object, got number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p payload
+			err := json.NewDecoder(strings.NewReader(tt.body)).Decode(&p)
+			appErr := jsonDecodeError(err)
+			if appErr == nil {
+				t.Fatalf("jsonDecodeError(%v) = nil", err)
+			}
+			if appErr.Message != tt.wantMessage {
+				t.Errorf("message = %q, want %q", appErr.Message, tt.wantMessage)
+			}
+
+			details := fmt.Sprint(appErr.Details)
+			if fields, ok := fieldErrors(appErr.Details); ok {
+				details = fields[0].Field + " " + fields[0].Message
+			}
+			if !strings.Contains(details, tt.wantDetails) {
+				t.Errorf("details = %q, want them to contain %q", details, tt.wantDetails)
+			}
+		})
+	}
+
+	if appErr := jsonDecodeError(errors.New("read: connection reset")); appErr != nil {
+		t.Errorf("jsonDecodeError of a non-JSON error = %v, want nil", appErr)
+	}
+}
+
+// fieldErrors returns the per-field errors of an error's details, if any
+func fieldErrors(details interface{}) ([]FieldError, bool) {
+	h, ok := details.(gin.H)
+	if !ok {
+		return nil, false
+	}
+	fields, ok := h["fields"].([]FieldError)
+	return fields, ok && len(fields) > 0
+}
+
+func TestBindJSONUnknownFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name        string
+		global      bool
+		perRoute    bool
+		body        string
+		wantUnknown string
+	}{
+		{"ignored by default", false, false, `{"name":"Alice","admin":true}`, ""},
+		{"rejected globally", true, false, `{"name":"Alice","admin":true}`, "admin"},
+		{"rejected per route", false, true, `{"name":"Alice","admin":true}`, "admin"},
+		{"known fields accepted", true, true, `{"name":"Alice"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{StrictJSONBinding: tt.global}
+
+			var bindErr error
+			var req request
+			router := gin.New()
+			if tt.perRoute {
+				router.Use(middleware.StrictJSON())
+			}
+			router.POST("/", func(c *gin.Context) {
+				bindErr = bindJSON(c, cfg, &req)
+			})
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body)))
+
+			if tt.wantUnknown == "" {
+				if bindErr != nil || req.Name != "Alice" {
+					t.Errorf("bindJSON = %v with name %q, want Alice bound", bindErr, req.Name)
+				}
+				return
+			}
+
+			var appErr *apperror.Error
+			if !errors.As(bindErr, &appErr) {
+				t.Fatalf("bindJSON = %v, want an *apperror.Error", bindErr)
+			}
+			fields, ok := fieldErrors(appErr.Details)
+			if !ok || fields[0].Field != tt.wantUnknown || fields[0].Rule != "unknown" {
+				t.Errorf("details = %v, want %s reported as unknown", appErr.Details, tt.wantUnknown)
+			}
+		})
+	}
+}
+
+func TestBindQueryAndURI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type request struct {
+		ID   int    `uri:"id" binding:"omitempty,min=1"`
+		Page int    `form:"page" binding:"omitempty,min=1"`
+		Sort string `form:"sort" binding:"omitempty,oneof=name created_at"`
+		Name string `json:"name" binding:"omitempty,min=3"`
+	}
+	uri := func(c *gin.Context, obj interface{}) error { return bindURI(c, obj) }
+	query := func(c *gin.Context, obj interface{}) error { return bindQuery(c, obj) }
+	all := func(c *gin.Context, obj interface{}) error { return bindRequest(c, &config.Config{}, obj) }
+
+	tests := []struct {
+		name        string
+		bind        func(c *gin.Context, obj interface{}) error
+		target      string
+		body        string
+		want        request
+		wantMessage string
+		wantField   FieldError
+	}{
+		{name: "valid path", bind: uri, target: "/items/7", want: request{ID: 7}},
+		{name: "valid query", bind: query, target: "/items/7?page=2&sort=name", want: request{Page: 2, Sort: "name"}},
+		{name: "valid request", bind: all, target: "/items/7?page=2", body: `{"name":"Alice"}`, want: request{ID: 7, Page: 2, Name: "Alice"}},
+		{
+			name: "path not a number", bind: uri, target: "/items/abc",
+			wantMessage: "Invalid path parameters",
+		},
+		{
+			name: "path below minimum", bind: uri, target: "/items/-1",
+			wantMessage: "Invalid path parameters",
+			wantField:   FieldError{Field: "id", Rule: "min", Message: "must be at least 1"},
+		},
+		{
+			name: "query not a number", bind: query, target: "/items/7?page=two",
+			wantMessage: "Invalid query parameters",
+		},
+		{
+			name: "query not one of", bind: query, target: "/items/7?sort=price",
+			wantMessage: "Invalid query parameters",
+			wantField:   FieldError{Field: "sort", Rule: "oneof", Message: "must be one of: name, created_at"},
+		},
+		{
+			name: "request validated once", bind: all, target: "/items/7?page=-1", body: `{"name":"Alice"}`,
+			wantMessage: "Invalid request",
+			wantField:   FieldError{Field: "page", Rule: "min", Message: "must be at least 1"},
+		},
+		{
+			name: "request body field", bind: all, target: "/items/7", body: `{"name":"Al"}`,
+			wantMessage: "Invalid request",
+			wantField:   FieldError{Field: "name", Rule: "min", Message: "must be at least 3 characters long"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bindErr error
+			var req request
+			router := gin.New()
+			router.POST("/items/:id", func(c *gin.Context) {
+				bindErr = tt.bind(c, &req)
+			})
+			var body io.Reader = http.NoBody
+			if tt.body != "" {
+				body = strings.NewReader(tt.body)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, tt.target, body))
+
+			if tt.wantMessage == "" {
+				if bindErr != nil {
+					t.Fatalf("bind = %v, want no error", bindErr)
+				}
+				if req != tt.want {
+					t.Errorf("bound %+v, want %+v", req, tt.want)
+				}
+				return
+			}
+
+			var appErr *apperror.Error
+			if !errors.As(bindErr, &appErr) {
+				t.Fatalf("bind = %v, want an *apperror.Error", bindErr)
+			}
+			if appErr.Status != http.StatusBadRequest || appErr.Message != tt.wantMessage {
+				t.Errorf("error = %d %q, want 400 %q", appErr.Status, appErr.Message, tt.wantMessage)
+			}
+			if tt.wantField == (FieldError{}) {
+				return
+			}
+			fields, ok := fieldErrors(appErr.Details)
+			if !ok || len(fields) != 1 || fields[0] != tt.wantField {
+				t.Errorf("details = %v, want %+v", appErr.Details, tt.wantField)
+			}
+		})
+	}
+}
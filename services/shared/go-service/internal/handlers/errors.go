@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/response"
+)
+
+// NotFound handler for requests that match no route
+func NotFound() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Error(c, apperror.NotFound("Route not found"))
+	}
+}
+
+// MethodNotAllowed handler for requests whose path is routed for other
+// methods only. The allowed methods are listed in the Allow header and the
+// error details.
+func MethodNotAllowed(routes func() gin.RoutesInfo) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed := allowedMethods(routes(), c.Request.URL.Path)
+		if len(allowed) > 0 {
+			c.Header("Allow", strings.Join(allowed, ", "))
+		}
+
+		response.Error(c, apperror.MethodNotAllowed("Method not allowed").WithDetails(gin.H{
+			"allowed_methods": allowed,
+		}))
+	}
+}
+
+// allowedMethods returns the methods of every route matching path
+func allowedMethods(routes gin.RoutesInfo, path string) []string {
+	seen := make(map[string]bool)
+	allowed := []string{}
+	for _, route := range routes {
+		if !seen[route.Method] && matchRoute(route.Path, path) {
+			seen[route.Method] = true
+			allowed = append(allowed, route.Method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
+}
+
+// matchRoute reports whether path matches a gin route pattern with :param
+// and *wildcard segments
+func matchRoute(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(patternSegments) == len(pathSegments)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/middleware"
+)
+
+func TestNotFoundAndMethodNotAllowed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	router.Use(middleware.RequestID())
+	router.GET("/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.PUT("/items/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.NoRoute(NotFound())
+	router.NoMethod(MethodNotAllowed(router.Routes))
+
+	type body struct {
+		Error     string                 `json:"error"`
+		Code      string                 `json:"code"`
+		RequestID string                 `json:"request_id"`
+		Details   map[string]interface{} `json:"details"`
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		wantStatus  int
+		wantError   string
+		wantCode    string
+		wantAllow   string
+		wantAllowed []interface{}
+	}{
+		{
+			name:       "unknown route",
+			method:     http.MethodGet,
+			path:       "/missing",
+			wantStatus: http.StatusNotFound,
+			wantError:  "Route not found",
+			wantCode:   "not_found",
+		},
+		{
+			name:        "wrong method",
+			method:      http.MethodDelete,
+			path:        "/items/42",
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantError:   "Method not allowed",
+			wantCode:    "method_not_allowed",
+			wantAllow:   "GET, PUT",
+			wantAllowed: []interface{}{"GET", "PUT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.wantStatus {
+				t.Fatalf("%s %s = %d, want %d: %s", tt.method, tt.path, w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+				t.Errorf("Content-Type = %q, want JSON", got)
+			}
+
+			var got body
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("decode body %s: %v", w.Body, err)
+			}
+			if got.Error != tt.wantError || got.Code != tt.wantCode {
+				t.Errorf("body = %s, want error %q, code %q", w.Body, tt.wantError, tt.wantCode)
+			}
+			if got.RequestID == "" || got.RequestID != w.Header().Get("X-Request-ID") {
+				t.Errorf("request_id = %q, want the X-Request-ID header %q", got.RequestID, w.Header().Get("X-Request-ID"))
+			}
+			if allow := w.Header().Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+			if tt.wantAllowed != nil && !reflect.DeepEqual(got.Details["allowed_methods"], tt.wantAllowed) {
+				t.Errorf("details.allowed_methods = %v, want %v", got.Details["allowed_methods"], tt.wantAllowed)
+			}
+		})
+	}
+}
@@ -7,86 +7,105 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
 	"{{ module_name }}/internal/logger"
-	{{- if include_database }}
-	"{{ module_name }}/internal/database"
-	{{- endif }}
-	{{- if include_redis }}
-	"{{ module_name }}/internal/redis"
-	{{- endif }}
+	"{{ module_name }}/internal/response"
 )
 
 type HealthResponse struct {
-	Status    string                 `json:"status"`
-	Timestamp time.Time              `json:"timestamp"`
-	Service   string                 `json:"service"`
-	Version   string                 `json:"version"`
-	Checks    map[string]interface{} `json:"checks"`
+	Status    string                        `json:"status"`
+	Timestamp time.Time                     `json:"timestamp"`
+	Service   string                        `json:"service"`
+	Version   string                        `json:"version"`
+	Checks    map[string]health.CheckResult `json:"checks"`
 }
 
-// HealthCheck returns the health status of the service
-func HealthCheck(cfg *config.Config, log logger.Logger{{- if include_database }}, dbManager *database.DatabaseManager{{- endif }}{{- if include_redis }}, redis *redis.Client{{- endif }}) gin.HandlerFunc {
+// HealthCheck returns the health status of the service. A failing critical
+// check makes the service unhealthy (503); a failing non-critical check only
+// marks it degraded. Results may be cached briefly by the registry, and a
+// draining service always reports 503.
+func HealthCheck(cfg *config.Config, log logger.Logger, registry *health.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		checks := make(map[string]interface{})
-		healthy := true
-
-		{{- if include_database }}
-		// Check database connection
-		if dbManager != nil {
-			if err := dbManager.HealthCheck(); err != nil {
-				checks["database"] = map[string]interface{}{
-					"status": "unhealthy",
-					"error":  err.Error(),
-				}
-				healthy = false
-			} else {
-				checks["database"] = map[string]interface{}{
-					"status": "healthy",
-				}
-			}
-		}
-		{{- endif }}
-
-		{{- if include_redis }}
-		// Check Redis connection
-		if redis != nil {
-			if err := redis.Ping(); err != nil {
-				checks["redis"] = map[string]interface{}{
-					"status": "unhealthy",
-					"error":  err.Error(),
-				}
-				healthy = false
-			} else {
-				checks["redis"] = map[string]interface{}{
-					"status": "healthy",
-				}
-			}
-		}
-		{{- endif }}
+		report := registry.Run(c.Request.Context())
 
-		status := "healthy"
 		statusCode := http.StatusOK
-		if !healthy {
-			status = "unhealthy"
+		if report.Status == health.StatusUnhealthy || report.Status == health.StatusDraining {
 			statusCode = http.StatusServiceUnavailable
 		}
 
-		response := HealthResponse{
-			Status:    status,
-			Timestamp: time.Now(),
+		body := HealthResponse{
+			Status:    report.Status,
+			Timestamp: report.CheckedAt,
 			Service:   "{{ service_name }}",
 			Version:   "1.0.0",
-			Checks:    checks,
+			Checks:    report.Checks,
+		}
+
+		response.JSON(c, statusCode, body)
+	}
+}
+
+type StartupResponse struct {
+	Status  string   `json:"status"`
+	Pending []string `json:"pending,omitempty"`
+	Failed  []string `json:"failed,omitempty"`
+}
+
+// StartupCheck serves the startup probe. It returns 503 until every startup
+// task (migrations, warm-up) has completed and 200 from then on, without
+// running dependency checks, so a generous startup probe doesn't require
+// loosening liveness. A failed task keeps it at 503 for good, so the
+// orchestrator restarts the service.
+func StartupCheck(registry *health.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if failed := registry.FailedStartup(); len(failed) > 0 {
+			response.JSON(c, http.StatusServiceUnavailable, StartupResponse{
+				Status: health.StatusStartupFailed,
+				Failed: failed,
+			})
+			return
+		}
+
+		pending := registry.PendingStartup()
+		if len(pending) > 0 {
+			response.JSON(c, http.StatusServiceUnavailable, StartupResponse{
+				Status:  health.StatusStarting,
+				Pending: pending,
+			})
+			return
+		}
+
+		response.JSON(c, http.StatusOK, StartupResponse{Status: health.StatusStarted})
+	}
+}
+
+type LivenessResponse struct {
+	Status  string   `json:"status"`
+	Stalled []string `json:"stalled,omitempty"`
+}
+
+// LivenessCheck serves the liveness probe. It returns 503 when a critical
+// goroutine registered with registry.Heartbeat has stopped beating, so the
+// orchestrator restarts a deadlocked process, and 200 otherwise. Dependencies
+// aren't checked: an unreachable database is no reason to restart.
+func LivenessCheck(registry *health.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if stalled := registry.Stalled(); len(stalled) > 0 {
+			response.JSON(c, http.StatusServiceUnavailable, LivenessResponse{
+				Status:  health.StatusStalled,
+				Stalled: stalled,
+			})
+			return
 		}
 
-		c.JSON(statusCode, response)
+		response.JSON(c, http.StatusOK, LivenessResponse{Status: health.StatusAlive})
 	}
 }
 
 // Root handler
 func Root(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
+		response.JSON(c, http.StatusOK, gin.H{
 			"message": "Welcome to {{ service_name }}",
 			"service": "{{ service_name }}",
 			"version": "1.0.0",
@@ -97,7 +116,7 @@ func Root(log logger.Logger) gin.HandlerFunc {
 // Ping handler
 func Ping(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
+		response.JSON(c, http.StatusOK, gin.H{
 			"message": "pong",
 			"timestamp": time.Now(),
 		})
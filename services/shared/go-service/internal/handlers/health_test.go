@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/health"
+	"{{ module_name }}/internal/logger"
+)
+
+// keys returns the sorted keys of a decoded JSON object
+func keys(object map[string]interface{}) []string {
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getJSON serves GET path on router and decodes the JSON object it returns
+func getJSON(t *testing.T, router http.Handler, path string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+	return w.Code, body
+}
+
+func TestHealthCheckResponseShape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	tests := []struct {
+		name       string
+		critical   bool
+		wantCode   int
+		wantStatus string
+	}{
+		{"failing non-critical check", false, http.StatusOK, health.StatusDegraded},
+		{"failing critical check", true, http.StatusServiceUnavailable, health.StatusUnhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := health.NewRegistry(health.Options{CheckTimeout: time.Second})
+			registry.Register("database", true, func(ctx context.Context) (health.CheckResult, error) {
+				return health.CheckResult{Details: map[string]interface{}{"open_connections": 3}}, nil
+			})
+			registry.Register("cache", tt.critical, func(ctx context.Context) (health.CheckResult, error) {
+				return health.CheckResult{}, errors.New("connection refused")
+			})
+
+			router := gin.New()
+			router.GET("/health", HealthCheck(&config.Config{}, log, registry))
+
+			code, body := getJSON(t, router, "/health")
+			if code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", code, tt.wantCode)
+			}
+			if got, want := keys(body), []string{"checks", "service", "status", "timestamp", "version"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("response keys = %v, want %v", got, want)
+			}
+			if body["status"] != tt.wantStatus {
+				t.Errorf("status = %v, want %s", body["status"], tt.wantStatus)
+			}
+			if timestamp, _ := body["timestamp"].(string); timestamp == "" {
+				t.Errorf("timestamp = %v, want an RFC 3339 string", body["timestamp"])
+			} else if _, err := time.Parse(time.RFC3339, timestamp); err != nil {
+				t.Errorf("timestamp %q: %v", timestamp, err)
+			}
+
+			checks, _ := body["checks"].(map[string]interface{})
+			database, _ := checks["database"].(map[string]interface{})
+			if got, want := keys(database), []string{"critical", "details", "latency_ms", "status"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("passing check keys = %v, want %v", got, want)
+			}
+			if database["status"] != health.StatusHealthy || database["critical"] != true {
+				t.Errorf("database check = %v, want healthy and critical", database)
+			}
+
+			cache, _ := checks["cache"].(map[string]interface{})
+			if got, want := keys(cache), []string{"critical", "error", "latency_ms", "status"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("failing check keys = %v, want %v", got, want)
+			}
+			if cache["status"] != health.StatusUnhealthy || cache["error"] != "connection refused" || cache["critical"] != tt.critical {
+				t.Errorf("cache check = %v, want unhealthy with the error", cache)
+			}
+		})
+	}
+}
+
+func TestStartupCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	type probe struct {
+		code    int
+		status  string
+		pending []interface{}
+		failed  []interface{}
+	}
+	check := func(t *testing.T, router http.Handler, want probe) {
+		t.Helper()
+		code, body := getJSON(t, router, "/health/startup")
+		if code != want.code || body["status"] != want.status {
+			t.Errorf("startup probe = %d %v, want %d %s", code, body["status"], want.code, want.status)
+		}
+		if got, _ := body["pending"].([]interface{}); !reflect.DeepEqual(got, want.pending) {
+			t.Errorf("pending = %v, want %v", got, want.pending)
+		}
+		if got, _ := body["failed"].([]interface{}); !reflect.DeepEqual(got, want.failed) {
+			t.Errorf("failed = %v, want %v", got, want.failed)
+		}
+	}
+
+	t.Run("tasks complete", func(t *testing.T) {
+		registry := health.NewRegistry(health.Options{})
+		router := gin.New()
+		router.GET("/health/startup", StartupCheck(registry))
+
+		migrated := registry.StartupTask("migrations")
+		warmed := registry.StartupTask("warmup")
+		check(t, router, probe{http.StatusServiceUnavailable, health.StatusStarting, []interface{}{"migrations", "warmup"}, nil})
+
+		migrated(nil)
+		check(t, router, probe{http.StatusServiceUnavailable, health.StatusStarting, []interface{}{"warmup"}, nil})
+
+		warmed(nil)
+		check(t, router, probe{http.StatusOK, health.StatusStarted, nil, nil})
+	})
+
+	t.Run("task fails", func(t *testing.T) {
+		registry := health.NewRegistry(health.Options{})
+		router := gin.New()
+		router.GET("/health/startup", StartupCheck(registry))
+
+		migrated := registry.StartupTask("migrations")
+		warmed := registry.StartupTask("warmup")
+		migrated(errors.New("relation does not exist"))
+		check(t, router, probe{http.StatusServiceUnavailable, health.StatusStartupFailed, nil, []interface{}{"migrations"}})
+
+		// The remaining tasks finishing doesn't bring the probe back
+		warmed(nil)
+		migrated(nil)
+		check(t, router, probe{http.StatusServiceUnavailable, health.StatusStartupFailed, nil, []interface{}{"migrations"}})
+	})
+}
@@ -0,0 +1,225 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Overall service statuses
+const (
+	StatusHealthy   = "healthy"
+	StatusDegraded  = "degraded"
+	StatusUnhealthy = "unhealthy"
+	StatusDraining  = "draining"
+)
+
+// CheckResult is the outcome of a single dependency check as reported by the
+// health endpoint. Checks fill in Details (and optionally Error); the
+// registry sets Status, Critical and LatencyMs.
+type CheckResult struct {
+	Status    string                 `json:"status"`
+	Critical  bool                   `json:"critical"`
+	Error     string                 `json:"error,omitempty"`
+	LatencyMs int64                  `json:"latency_ms"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// CheckFunc checks a single dependency. It returns a result to include in the
+// health response and a non-nil error when the dependency is unhealthy.
+type CheckFunc func(ctx context.Context) (CheckResult, error)
+
+// Check is a named dependency check. A failing critical check makes the
+// service unhealthy; a failing non-critical check only degrades it.
+type Check struct {
+	Name     string
+	Critical bool
+	Run      CheckFunc
+}
+
+// Report is the combined result of running every check
+type Report struct {
+	Status    string
+	Checks    map[string]CheckResult
+	CheckedAt time.Time
+}
+
+// Registry holds the dependency checks reported by the health endpoint
+type Registry struct {
+	mu     sync.RWMutex
+	checks []Check
+
+	// Results are cached for cacheTTL so frequent probes don't load
+	// dependencies; runMu lets concurrent probes share a single run
+	cacheTTL time.Duration
+	runMu    sync.Mutex
+	cached   *Report
+
+	checkTimeout time.Duration
+
+	draining atomic.Bool
+
+	// Startup tasks that haven't completed or have failed; the startup
+	// probe fails while there are any
+	startupMu sync.Mutex
+	pending   map[string]struct{}
+	failed    map[string]error
+
+	// Critical goroutines watched by the liveness probe
+	heartbeatMu sync.Mutex
+	heartbeats  map[string]*Heartbeat
+}
+
+// Options configures how a Registry runs checks
+type Options struct {
+	// CacheTTL reuses the previous report for this long; zero or less
+	// disables caching
+	CacheTTL time.Duration
+	// CheckTimeout bounds the checks, which run concurrently, so one hung
+	// dependency can't delay the others or the response; zero or less
+	// disables the bound
+	CheckTimeout time.Duration
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry(opts Options) *Registry {
+	return &Registry{cacheTTL: opts.CacheTTL, checkTimeout: opts.CheckTimeout}
+}
+
+// Register adds a check, replacing any existing check with the same name
+func (r *Registry) Register(name string, critical bool, fn CheckFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	check := Check{Name: name, Critical: critical, Run: fn}
+	for i := range r.checks {
+		if r.checks[i].Name == name {
+			r.checks[i] = check
+			return
+		}
+	}
+	r.checks = append(r.checks, check)
+}
+
+// Checks returns the registered checks in registration order
+func (r *Registry) Checks() []Check {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Check(nil), r.checks...)
+}
+
+// SetDraining marks the service as shutting down. While draining, Run
+// reports StatusDraining without running checks or consulting the cache.
+func (r *Registry) SetDraining(draining bool) {
+	r.draining.Store(draining)
+}
+
+// Run runs every check and combines the results, reusing the previous
+// report if it is younger than the cache TTL
+func (r *Registry) Run(ctx context.Context) Report {
+	if r.draining.Load() {
+		return Report{Status: StatusDraining, Checks: map[string]CheckResult{}, CheckedAt: time.Now()}
+	}
+
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	if r.cached != nil && time.Since(r.cached.CheckedAt) < r.cacheTTL {
+		return *r.cached
+	}
+
+	report := r.run(ctx)
+	if r.cacheTTL > 0 {
+		r.cached = &report
+	}
+	return report
+}
+
+// run runs every check concurrently, bounded by the check timeout, and
+// combines the results. The timeout is the only bound: checks get it
+// through ctx rather than applying their own. A check that ignores ctx is
+// reported as not completing and its goroutine exits once it returns.
+func (r *Registry) run(ctx context.Context) Report {
+	if r.checkTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.checkTimeout)
+		defer cancel()
+	}
+
+	type outcome struct {
+		i      int
+		result CheckResult
+		err    error
+	}
+
+	checks := r.Checks()
+	start := time.Now()
+	done := make(chan outcome, len(checks))
+	for i, check := range checks {
+		go func(i int, check Check) {
+			result, err := runCheck(ctx, check)
+			result.LatencyMs = time.Since(start).Milliseconds()
+			done <- outcome{i, result, err}
+		}(i, check)
+	}
+
+	results := make([]CheckResult, len(checks))
+	errs := make([]error, len(checks))
+	completed := make([]bool, len(checks))
+collect:
+	for remaining := len(checks); remaining > 0; remaining-- {
+		select {
+		case o := <-done:
+			results[o.i], errs[o.i] = o.result, o.err
+			completed[o.i] = true
+		case <-ctx.Done():
+			break collect
+		}
+	}
+	for i := range checks {
+		if !completed[i] {
+			results[i] = CheckResult{LatencyMs: time.Since(start).Milliseconds()}
+			errs[i] = fmt.Errorf("check did not complete: %w", ctx.Err())
+		}
+	}
+
+	report := Report{
+		Status: StatusHealthy,
+		Checks: make(map[string]CheckResult, len(checks)),
+	}
+	for i, check := range checks {
+		result := results[i]
+		result.Critical = check.Critical
+
+		if err := errs[i]; err != nil {
+			result.Status = StatusUnhealthy
+			if result.Error == "" {
+				result.Error = err.Error()
+			}
+			if check.Critical {
+				report.Status = StatusUnhealthy
+			} else if report.Status == StatusHealthy {
+				report.Status = StatusDegraded
+			}
+		} else {
+			result.Status = StatusHealthy
+		}
+
+		report.Checks[check.Name] = result
+	}
+
+	report.CheckedAt = time.Now()
+	return report
+}
+
+// runCheck runs a single check, reporting a panic as a failure
+func runCheck(ctx context.Context, check Check) (result CheckResult, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = CheckResult{}, fmt.Errorf("check panicked: %v", p)
+		}
+	}()
+	return check.Run(ctx)
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRegistryRun(t *testing.T) {
+	registry := NewRegistry(Options{CheckTimeout: 50 * time.Millisecond})
+	registry.Register("ok", true, func(ctx context.Context) (CheckResult, error) {
+		return CheckResult{}, nil
+	})
+	registry.Register("hung", false, func(ctx context.Context) (CheckResult, error) {
+		<-ctx.Done()
+		return CheckResult{}, ctx.Err()
+	})
+	release := make(chan struct{})
+	defer close(release)
+	registry.Register("ignores_ctx", false, func(ctx context.Context) (CheckResult, error) {
+		<-release
+		return CheckResult{}, nil
+	})
+	registry.Register("panics", false, func(ctx context.Context) (CheckResult, error) {
+		panic("boom")
+	})
+
+	start := time.Now()
+	report := registry.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Run took %s, want it bounded by the check timeout", elapsed)
+	}
+
+	if report.Status != StatusDegraded {
+		t.Errorf("status = %s, want %s", report.Status, StatusDegraded)
+	}
+	want := map[string]string{
+		"ok":          StatusHealthy,
+		"hung":        StatusUnhealthy,
+		"ignores_ctx": StatusUnhealthy,
+		"panics":      StatusUnhealthy,
+	}
+	for name, status := range want {
+		if got := report.Checks[name].Status; got != status {
+			t.Errorf("%s: status = %s, want %s (error: %s)", name, got, status, report.Checks[name].Error)
+		}
+	}
+}
+
+func TestStartupTask(t *testing.T) {
+	registry := NewRegistry(Options{})
+	migrated := registry.StartupTask("migrations")
+	warmed := registry.StartupTask("warmup")
+
+	if pending := registry.PendingStartup(); len(pending) != 2 {
+		t.Fatalf("PendingStartup = %v, want both tasks", pending)
+	}
+
+	warmed(nil)
+	migrated(errors.New("table for *User does not exist"))
+	migrated(nil)
+
+	if pending := registry.PendingStartup(); len(pending) != 0 {
+		t.Errorf("PendingStartup = %v, want none", pending)
+	}
+	if failed := registry.FailedStartup(); len(failed) != 1 || failed[0] != "migrations" {
+		t.Errorf("FailedStartup = %v, want [migrations]", failed)
+	}
+	if err := registry.StartupErr(); err == nil || err.Error() != "migrations: table for *User does not exist" {
+		t.Errorf("StartupErr = %v", err)
+	}
+}
+
+func TestRegistryCachesReport(t *testing.T) {
+	const ttl = 100 * time.Millisecond
+	registry := NewRegistry(Options{CacheTTL: ttl})
+	var calls atomic.Int32
+	registry.Register("database", true, func(ctx context.Context) (CheckResult, error) {
+		calls.Add(1)
+		return CheckResult{}, nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Run(context.Background())
+		}()
+	}
+	wg.Wait()
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("dependency checked %d times within the cache window, want 1", got)
+	}
+
+	time.Sleep(ttl + 20*time.Millisecond)
+	registry.Run(context.Background())
+	if got := calls.Load(); got != 2 {
+		t.Errorf("dependency checked %d times after the cache window, want 2", got)
+	}
+}
+
+func TestRegistryWithoutCache(t *testing.T) {
+	registry := NewRegistry(Options{})
+	var calls atomic.Int32
+	registry.Register("database", true, func(ctx context.Context) (CheckResult, error) {
+		calls.Add(1)
+		return CheckResult{}, nil
+	})
+
+	for i := 0; i < 3; i++ {
+		registry.Run(context.Background())
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("dependency checked %d times in 3 runs without a cache, want 3", got)
+	}
+}
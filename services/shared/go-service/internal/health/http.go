@@ -0,0 +1,36 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTPCheck probes a downstream HTTP dependency with method (GET or HEAD),
+// reporting its status code. Any response outside 2xx/3xx, or no response
+// before ctx is done, is unhealthy.
+func HTTPCheck(client *http.Client, method, url string) CheckFunc {
+	return func(ctx context.Context) (CheckResult, error) {
+		result := CheckResult{Details: map[string]interface{}{"url": url}}
+
+		req, err := http.NewRequestWithContext(ctx, method, url, nil)
+		if err != nil {
+			return result, err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return result, err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		result.Details["status_code"] = resp.StatusCode
+		if resp.StatusCode >= http.StatusBadRequest {
+			return result, fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+		}
+
+		return result, nil
+	}
+}
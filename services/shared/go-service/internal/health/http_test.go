@@ -0,0 +1,59 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPCheck(t *testing.T) {
+	var gotMethod string
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer up.Close()
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	defer close(release)
+
+	tests := []struct {
+		name    string
+		method  string
+		url     string
+		healthy bool
+	}{
+		{"up", http.MethodHead, up.URL, true},
+		{"down", http.MethodGet, down.URL, false},
+		{"slow", http.MethodGet, slow.URL, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			_, err := HTTPCheck(http.DefaultClient, tt.method, tt.url)(ctx)
+			if healthy := err == nil; healthy != tt.healthy {
+				t.Errorf("healthy = %t, want %t (err: %v)", healthy, tt.healthy, err)
+			}
+		})
+	}
+
+	if gotMethod != http.MethodHead {
+		t.Errorf("up was probed with %s, want HEAD", gotMethod)
+	}
+}
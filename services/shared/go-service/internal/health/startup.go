@@ -0,0 +1,86 @@
+package health
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Startup probe statuses
+const (
+	StatusStarting      = "starting"
+	StatusStarted       = "started"
+	StatusStartupFailed = "failed"
+)
+
+// StartupTask records a slow initialization step, such as migrations or
+// cache warm-up, that must finish before the startup probe succeeds. Call
+// the returned function with nil once the step has completed, or with its
+// error once it has failed; only the first call has an effect. A failed step
+// is no longer pending, so waiting for startup ends, but it keeps the startup
+// probe failing so the orchestrator restarts the service once the probe
+// gives up. A step stopped by shutdown should leave it uncalled.
+func (r *Registry) StartupTask(name string) (done func(err error)) {
+	r.startupMu.Lock()
+	defer r.startupMu.Unlock()
+
+	if r.pending == nil {
+		r.pending = make(map[string]struct{})
+	}
+	r.pending[name] = struct{}{}
+
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			r.startupMu.Lock()
+			defer r.startupMu.Unlock()
+			delete(r.pending, name)
+			if err != nil {
+				if r.failed == nil {
+					r.failed = make(map[string]error)
+				}
+				r.failed[name] = err
+			}
+		})
+	}
+}
+
+// PendingStartup returns the names of startup tasks that haven't completed,
+// sorted. Startup is complete when it is empty.
+func (r *Registry) PendingStartup() []string {
+	r.startupMu.Lock()
+	defer r.startupMu.Unlock()
+
+	pending := make([]string, 0, len(r.pending))
+	for name := range r.pending {
+		pending = append(pending, name)
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// FailedStartup returns the names of startup tasks that failed, sorted
+func (r *Registry) FailedStartup() []string {
+	r.startupMu.Lock()
+	defer r.startupMu.Unlock()
+
+	failed := make([]string, 0, len(r.failed))
+	for name := range r.failed {
+		failed = append(failed, name)
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// StartupErr returns the errors of the startup tasks that failed, or nil
+func (r *Registry) StartupErr() error {
+	var errs []error
+	for _, name := range r.FailedStartup() {
+		r.startupMu.Lock()
+		err := r.failed[name]
+		r.startupMu.Unlock()
+		errs = append(errs, fmt.Errorf("%s: %w", name, err))
+	}
+	return errors.Join(errs...)
+}
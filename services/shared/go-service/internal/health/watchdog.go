@@ -0,0 +1,80 @@
+package health
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Liveness probe statuses
+const (
+	StatusAlive   = "alive"
+	StatusStalled = "stalled"
+)
+
+// Heartbeat is a critical goroutine watched by the liveness probe. The
+// goroutine calls Beat on every iteration of its loop; if it misses beats
+// for longer than the heartbeat's timeout, liveness fails so the
+// orchestrator restarts the process.
+type Heartbeat struct {
+	registry *Registry
+	name     string
+	timeout  time.Duration
+	last     atomic.Int64
+}
+
+// Heartbeat registers a critical goroutine with the liveness watchdog. Its
+// clock starts now, so the first beat is due within timeout.
+//
+//	hb := registry.Heartbeat("consumer", time.Minute)
+//	defer hb.Stop()
+//	for msg := range messages {
+//		hb.Beat()
+//		handle(msg)
+//	}
+//
+// A loop that may legitimately sit idle longer than timeout, waiting for
+// work, should beat from a ticker case in its select as well.
+func (r *Registry) Heartbeat(name string, timeout time.Duration) *Heartbeat {
+	hb := &Heartbeat{registry: r, name: name, timeout: timeout}
+	hb.Beat()
+
+	r.heartbeatMu.Lock()
+	defer r.heartbeatMu.Unlock()
+	if r.heartbeats == nil {
+		r.heartbeats = make(map[string]*Heartbeat)
+	}
+	r.heartbeats[name] = hb
+	return hb
+}
+
+// Beat records that the goroutine is making progress
+func (hb *Heartbeat) Beat() {
+	hb.last.Store(time.Now().UnixNano())
+}
+
+// Stop unregisters the heartbeat, for goroutines exiting normally
+func (hb *Heartbeat) Stop() {
+	hb.registry.heartbeatMu.Lock()
+	defer hb.registry.heartbeatMu.Unlock()
+	if hb.registry.heartbeats[hb.name] == hb {
+		delete(hb.registry.heartbeats, hb.name)
+	}
+}
+
+// Stalled returns the names of heartbeats overdue by more than their
+// timeout, sorted. The process is live when it is empty.
+func (r *Registry) Stalled() []string {
+	r.heartbeatMu.Lock()
+	defer r.heartbeatMu.Unlock()
+
+	now := time.Now()
+	stalled := make([]string, 0)
+	for name, hb := range r.heartbeats {
+		if now.Sub(time.Unix(0, hb.last.Load())) > hb.timeout {
+			stalled = append(stalled, name)
+		}
+	}
+	sort.Strings(stalled)
+	return stalled
+}
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRegistryStalled(t *testing.T) {
+	registry := NewRegistry(Options{})
+	consumer := registry.Heartbeat("consumer", time.Minute)
+	relay := registry.Heartbeat("relay", time.Minute)
+	registry.Heartbeat("scheduler", time.Minute)
+
+	if stalled := registry.Stalled(); len(stalled) != 0 {
+		t.Fatalf("Stalled = %v right after registering, want none", stalled)
+	}
+
+	// Both miss their beats; the relay then recovers
+	overdue := time.Now().Add(-2 * time.Minute).UnixNano()
+	consumer.last.Store(overdue)
+	relay.last.Store(overdue)
+	if stalled := registry.Stalled(); !reflect.DeepEqual(stalled, []string{"consumer", "relay"}) {
+		t.Errorf("Stalled = %v, want [consumer relay]", stalled)
+	}
+
+	relay.Beat()
+	if stalled := registry.Stalled(); !reflect.DeepEqual(stalled, []string{"consumer"}) {
+		t.Errorf("Stalled = %v after the relay beat, want [consumer]", stalled)
+	}
+
+	// A goroutine that exits normally stops being watched
+	consumer.Stop()
+	if stalled := registry.Stalled(); len(stalled) != 0 {
+		t.Errorf("Stalled = %v after the consumer stopped, want none", stalled)
+	}
+}
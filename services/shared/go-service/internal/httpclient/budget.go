@@ -0,0 +1,45 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// budgetTransport bounds each round trip, reading the response body
+// included, to timeout, clamped to the time left before the deadline of the
+// request's context. So a call made with c.Request.Context() never outlives
+// the incoming request, however much of its own budget is left.
+type budgetTransport struct {
+	next    http.RoundTripper
+	timeout time.Duration
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := reqctx.WithBudget(req.Context(), t.timeout)
+
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	// The body is read after RoundTrip returns, under the same budget
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a round trip's budget when its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
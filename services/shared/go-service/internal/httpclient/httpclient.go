@@ -0,0 +1,77 @@
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// Options configures outbound HTTP clients
+type Options struct {
+	Timeout             time.Duration
+	MaxIdleConnsPerHost int
+	// TenantHeader is the header the tenant ID is forwarded in
+	TenantHeader string
+	// TokenSource, when set, supplies a bearer token for requests to the
+	// TokenAudience hosts that don't carry their own Authorization header
+	TokenSource TokenSource
+	// TokenAudience lists the hosts, with or without a port, that accept
+	// the service token. Requests to other hosts are sent without it, so
+	// the token doesn't leak to third parties.
+	TokenAudience []string
+	// MaxRetries is how many times a request turned away with 429 or 503
+	// is retried; zero disables retries
+	MaxRetries int
+	// MaxRetryAfter caps the wait before each retry, however long the
+	// downstream's Retry-After asks for
+	MaxRetryAfter time.Duration
+}
+
+// New creates an HTTP client for calls to downstream services. Use it instead
+// of http.DefaultClient, which has no timeout. The request ID, tenant and
+// trace context of the request context are forwarded as headers, so build
+// outbound requests with http.NewRequestWithContext(c.Request.Context(), ...).
+// Each round trip gets Timeout, clamped to the time left before the request
+// context's deadline. With MaxRetries, requests turned away with 429 or 503
+// are retried after the wait their Retry-After asks for. With a TokenSource,
+// requests are authenticated as this service, and fetching the token counts
+// against the same budget.
+func New(opts Options) *http.Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxIdleConnsPerHost <= 0 {
+		opts.MaxIdleConnsPerHost = 10
+	}
+	if opts.MaxRetryAfter <= 0 {
+		opts.MaxRetryAfter = 10 * time.Second
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   opts.MaxIdleConnsPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+
+	var next http.RoundTripper = transport
+	if opts.TokenSource != nil {
+		next = newAuthTransport(transport, opts.TokenSource, opts.TokenAudience)
+	}
+
+	next = &propagatingTransport{next: next, tenantHeader: opts.TenantHeader}
+
+	// Retries wrap the budget, so each attempt gets a budget of its own
+	next = &budgetTransport{next: next, timeout: opts.Timeout}
+	if opts.MaxRetries > 0 {
+		next = &retryTransport{next: next, maxRetries: opts.MaxRetries, maxWait: opts.MaxRetryAfter}
+	}
+
+	return &http.Client{Transport: next}
+}
@@ -0,0 +1,46 @@
+package httpclient
+
+import (
+	"net/http"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// propagatingTransport copies request-scoped values from the outbound
+// request's context onto its headers, so downstream services see the same
+// request ID, tenant and trace context. Headers already set by the caller
+// are left untouched.
+type propagatingTransport struct {
+	next         http.RoundTripper
+	tenantHeader string
+}
+
+func (t *propagatingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	headers := make(http.Header)
+	if requestID := reqctx.RequestID(ctx); requestID != "" {
+		headers.Set("X-Request-ID", requestID)
+	}
+	if tenantID := reqctx.TenantID(ctx); tenantID != "" && t.tenantHeader != "" {
+		headers.Set(t.tenantHeader, tenantID)
+	}
+	for name, values := range reqctx.TraceHeaders(ctx) {
+		headers[name] = values
+	}
+
+	var cloned bool
+	for name, values := range headers {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		// RoundTrippers must not modify the caller's request
+		if !cloned {
+			req = req.Clone(ctx)
+			cloned = true
+		}
+		req.Header[name] = values
+	}
+
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,104 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+const testTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestPropagatingTransport(t *testing.T) {
+	ctx := reqctx.WithRequestID(context.Background(), "req-1")
+	ctx = reqctx.WithTenantID(ctx, "acme")
+	ctx = reqctx.WithTraceHeaders(ctx, http.Header{"Traceparent": {testTraceparent}})
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		callerSet map[string]string
+		want      map[string]string
+	}{
+		{
+			name: "values from the context",
+			ctx:  ctx,
+			want: map[string]string{"X-Request-ID": "req-1", "X-Tenant-ID": "acme", "Traceparent": testTraceparent},
+		},
+		{
+			name:      "caller-set headers win",
+			ctx:       ctx,
+			callerSet: map[string]string{"X-Request-ID": "caller-req", "X-Tenant-ID": "caller-tenant"},
+			want:      map[string]string{"X-Request-ID": "caller-req", "X-Tenant-ID": "caller-tenant", "Traceparent": testTraceparent},
+		},
+		{
+			name: "nothing to propagate",
+			ctx:  context.Background(),
+			want: map[string]string{"X-Request-ID": "", "X-Tenant-ID": "", "Traceparent": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent http.Header
+			transport := &propagatingTransport{
+				tenantHeader: "X-Tenant-ID",
+				next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					sent = req.Header.Clone()
+					return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+				}),
+			}
+
+			req, err := http.NewRequestWithContext(tt.ctx, http.MethodGet, "http://downstream.test/", nil)
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+			for name, value := range tt.callerSet {
+				req.Header.Set(name, value)
+			}
+			original := req.Header.Clone()
+
+			if _, err := transport.RoundTrip(req); err != nil {
+				t.Fatalf("RoundTrip: %v", err)
+			}
+
+			for name, want := range tt.want {
+				if got := sent.Get(name); got != want {
+					t.Errorf("sent %s = %q, want %q", name, got, want)
+				}
+			}
+			// RoundTrippers must not modify the caller's request
+			if len(req.Header) != len(original) {
+				t.Errorf("caller's headers = %v, want them unchanged (%v)", req.Header, original)
+			}
+			for name := range original {
+				if req.Header.Get(name) != original.Get(name) {
+					t.Errorf("caller's %s = %q, want %q", name, req.Header.Get(name), original.Get(name))
+				}
+			}
+		})
+	}
+}
+
+func TestPropagatingTransportWithoutTenantHeader(t *testing.T) {
+	var sent http.Header
+	transport := &propagatingTransport{
+		next: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			sent = req.Header.Clone()
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+	}
+
+	ctx := reqctx.WithTenantID(context.Background(), "acme")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://downstream.test/", nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	if _, err := transport.RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("sent headers = %v, want none without a tenant header configured", sent)
+	}
+}
@@ -0,0 +1,145 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryBackoff is the wait before the first retry of a response without a
+// Retry-After header; it doubles per retry
+const retryBackoff = 500 * time.Millisecond
+
+// retryTransport retries requests the downstream turned away with 429 Too
+// Many Requests or 503 Service Unavailable, waiting as long as its
+// Retry-After header asks, up to maxWait. A wait that wouldn't end before
+// the request context's deadline isn't started, and the response is
+// returned as is. Only requests that are safe to send twice are retried:
+// those with an idempotent method or an Idempotency-Key header, and a body
+// that can be replayed.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !replayable(req) {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	for retry := 0; ; retry++ {
+		attempt := req
+		if retry > 0 {
+			// RoundTrippers must not modify the caller's request
+			attempt = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				attempt.Body = body
+			}
+		}
+
+		resp, err := t.next.RoundTrip(attempt)
+		if err != nil || retry >= t.maxRetries || !retryableStatus(resp.StatusCode) {
+			return resp, err
+		}
+
+		wait := t.wait(resp, retry, time.Now())
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return resp, nil
+		}
+
+		// Drain a little of the body so the connection can be reused
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait returns how long to wait before retrying resp: its Retry-After, or
+// the backoff for retry when it has none, capped at maxWait
+func (t *retryTransport) wait(resp *http.Response, retry int, now time.Time) time.Duration {
+	delay, ok := ParseRetryAfter(resp.Header.Get("Retry-After"), now)
+	if !ok {
+		delay = t.maxWait
+		if retry < 16 {
+			delay = retryBackoff << retry
+		}
+	}
+	if delay < 0 || delay > t.maxWait {
+		return t.maxWait
+	}
+	return delay
+}
+
+// ParseRetryAfter parses a Retry-After header value, either delay-seconds
+// ("120") or an HTTP-date ("Fri, 31 Dec 1999 23:59:59 GMT"), into the wait
+// it asks for from now. A date in the past asks for no wait. ok is false
+// when the value is missing or malformed.
+func ParseRetryAfter(value string, now time.Time) (wait time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	if wait := date.Sub(now); wait > 0 {
+		return wait, true
+	}
+	return 0, true
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+}
+
+// replayable reports whether req may be sent more than once, following the
+// same rules net/http uses to retry requests on a broken connection
+func replayable(req *http.Request) bool {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
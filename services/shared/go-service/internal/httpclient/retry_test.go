@@ -0,0 +1,102 @@
+package httpclient
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{"seconds", "120", 2 * time.Minute, true},
+		{"zero seconds", "0", 0, true},
+		{"padded seconds", " 5 ", 5 * time.Second, true},
+		{"seconds overflowing a duration", "9223372036854775807", math.MaxInt64, true},
+		{"negative seconds", "-1", 0, false},
+		{"fractional seconds", "1.5", 0, false},
+		{"future date", "Fri, 16 Oct 2026 12:00:30 GMT", 30 * time.Second, true},
+		{"past date", "Thu, 15 Oct 2026 12:00:00 GMT", 0, true},
+		{"RFC 850 date", "Friday, 16-Oct-26 12:01:00 GMT", time.Minute, true},
+		{"missing", "", 0, false},
+		{"malformed", "soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := ParseRetryAfter(tt.value, now)
+			if wait != tt.wantWait || ok != tt.wantOK {
+				t.Errorf("ParseRetryAfter(%q) = %s, %t; want %s, %t", tt.value, wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRetryTransportWait(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	transport := &retryTransport{maxWait: 10 * time.Second}
+
+	tests := []struct {
+		name       string
+		retryAfter string
+		retry      int
+		want       time.Duration
+	}{
+		{"retry-after within the cap", "3", 0, 3 * time.Second},
+		{"retry-after beyond the cap", "3600", 0, 10 * time.Second},
+		{"retry-after overflowing a duration", "9223372036854775807", 0, 10 * time.Second},
+		{"date beyond the cap", "Fri, 16 Oct 2026 13:00:00 GMT", 0, 10 * time.Second},
+		{"first backoff", "", 0, retryBackoff},
+		{"doubled backoff", "", 2, 4 * retryBackoff},
+		{"backoff beyond the cap", "", 5, 10 * time.Second},
+		{"backoff that would overflow", "", 100, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			if tt.retryAfter != "" {
+				resp.Header.Set("Retry-After", tt.retryAfter)
+			}
+			if got := transport.wait(resp, tt.retry, now); got != tt.want {
+				t.Errorf("wait = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryTransportCapsRetryAfter(t *testing.T) {
+	var attempts int
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		w := httptest.NewRecorder()
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		return w.Result(), nil
+	})
+	transport := &retryTransport{next: next, maxRetries: 2, maxWait: 10 * time.Millisecond}
+
+	start := time.Now()
+	resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://backend.internal/", nil))
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || attempts != 2 {
+		t.Errorf("got %d after %d attempts, want 200 after 2", resp.StatusCode, attempts)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry waited %s, beyond the 10ms cap", elapsed)
+	}
+}
@@ -0,0 +1,206 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Token is a bearer token for service-to-service calls
+type Token struct {
+	Value string
+	// ExpiresAt is when the token stops being accepted; zero means never
+	ExpiresAt time.Time
+}
+
+// TokenSource supplies the bearer token attached to outbound requests
+type TokenSource interface {
+	Token(ctx context.Context) (Token, error)
+}
+
+// ClientCredentialsConfig configures an OAuth2 client credentials grant
+type ClientCredentialsConfig struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// ClientCredentials returns a TokenSource that obtains service tokens from
+// an OAuth2 token endpoint using the client credentials grant. Tokens are
+// cached and refreshed refreshBefore ahead of their expiry, so callers don't
+// hit the token endpoint on every request. client must not itself attach
+// tokens; a client from New without a TokenSource will do.
+func ClientCredentials(client *http.Client, cfg ClientCredentialsConfig, refreshBefore time.Duration) TokenSource {
+	return &cachedTokenSource{
+		source:        &clientCredentialsSource{client: client, cfg: cfg},
+		refreshBefore: refreshBefore,
+	}
+}
+
+type clientCredentialsSource struct {
+	client *http.Client
+	cfg    ClientCredentialsConfig
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (s *clientCredentialsSource) Token(ctx context.Context) (Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.cfg.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.cfg.Scopes, " "))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.cfg.ClientSecret))
+
+	requested := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to request service token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Token{}, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var parsed tokenResponse
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return Token{}, fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if parsed.AccessToken == "" {
+		return Token{}, fmt.Errorf("token response has no access_token")
+	}
+	if parsed.TokenType != "" && !strings.EqualFold(parsed.TokenType, "bearer") {
+		return Token{}, fmt.Errorf("unsupported token type %q", parsed.TokenType)
+	}
+
+	token := Token{Value: parsed.AccessToken}
+	// Measured from when the request was sent so the token never outlives
+	// its actual lifetime
+	if parsed.ExpiresIn > 0 {
+		token.ExpiresAt = requested.Add(time.Duration(parsed.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// cachedTokenSource reuses a token until refreshBefore ahead of its expiry.
+// Concurrent callers needing a refresh share a single fetch, made without
+// holding mu so callers can give up when their context is done. The fetch
+// isn't cancelled with the caller that started it, so the others still get
+// the token; it is bounded by the token client's timeout instead.
+type cachedTokenSource struct {
+	source        TokenSource
+	refreshBefore time.Duration
+	fetches       singleflight.Group
+
+	mu    sync.Mutex
+	token Token
+}
+
+func (s *cachedTokenSource) Token(ctx context.Context) (Token, error) {
+	s.mu.Lock()
+	token := s.token
+	s.mu.Unlock()
+
+	if s.valid(token, time.Now()) {
+		return token, nil
+	}
+
+	fetchCtx := context.WithoutCancel(ctx)
+	fetched := s.fetches.DoChan("token", func() (interface{}, error) {
+		token, err := s.source.Token(fetchCtx)
+		if err != nil {
+			return Token{}, err
+		}
+
+		s.mu.Lock()
+		s.token = token
+		s.mu.Unlock()
+		return token, nil
+	})
+
+	select {
+	case result := <-fetched:
+		if result.Err != nil {
+			return Token{}, result.Err
+		}
+		return result.Val.(Token), nil
+	case <-ctx.Done():
+		return Token{}, ctx.Err()
+	}
+}
+
+func (s *cachedTokenSource) valid(token Token, now time.Time) bool {
+	if token.Value == "" {
+		return false
+	}
+	return token.ExpiresAt.IsZero() || now.Before(token.ExpiresAt.Add(-s.refreshBefore))
+}
+
+// authTransport attaches a bearer token from source to requests to the
+// audience hosts that don't already carry an Authorization header
+type authTransport struct {
+	next     http.RoundTripper
+	source   TokenSource
+	audience map[string]struct{}
+}
+
+func newAuthTransport(next http.RoundTripper, source TokenSource, audience []string) *authTransport {
+	hosts := make(map[string]struct{}, len(audience))
+	for _, host := range audience {
+		hosts[strings.ToLower(host)] = struct{}{}
+	}
+	return &authTransport{next: next, source: source, audience: hosts}
+}
+
+// inAudience reports whether the token may be sent to u's host. Audience
+// entries match with or without a port.
+func (t *authTransport) inAudience(u *url.URL) bool {
+	if _, ok := t.audience[strings.ToLower(u.Host)]; ok {
+		return true
+	}
+	_, ok := t.audience[strings.ToLower(u.Hostname())]
+	return ok
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" || !t.inAudience(req.URL) {
+		return t.next.RoundTrip(req)
+	}
+
+	token, err := t.source.Token(req.Context())
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("failed to obtain service token: %w", err)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token.Value)
+	return t.next.RoundTrip(req)
+}
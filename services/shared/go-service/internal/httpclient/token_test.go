@@ -0,0 +1,97 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type blockingSource struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (s *blockingSource) Token(ctx context.Context) (Token, error) {
+	s.calls.Add(1)
+	<-s.release
+	return Token{Value: "token"}, nil
+}
+
+func TestCachedTokenSourceSharesFetch(t *testing.T) {
+	source := &blockingSource{release: make(chan struct{})}
+	cached := &cachedTokenSource{source: source}
+
+	// A caller that gives up isn't held behind the fetch
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := cached.Token(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Token with an expiring context = %v, want DeadlineExceeded", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if token, err := cached.Token(context.Background()); err != nil || token.Value != "token" {
+				t.Errorf("Token = %q, %v", token.Value, err)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if n := source.calls.Load(); n != 1 {
+		t.Errorf("token fetched %d times, want 1", n)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type staticSource struct{}
+
+func (staticSource) Token(ctx context.Context) (Token, error) {
+	return Token{Value: "secret"}, nil
+}
+
+func TestAuthTransportAudience(t *testing.T) {
+	var got string
+	next := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		got = req.Header.Get("Authorization")
+		return httptest.NewRecorder().Result(), nil
+	})
+	transport := newAuthTransport(next, staticSource{}, []string{"orders.internal", "billing.internal:8443"})
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://orders.internal/v1/orders", "Bearer secret"},
+		{"http://ORDERS.internal:8080/v1/orders", "Bearer secret"},
+		{"https://billing.internal:8443/invoices", "Bearer secret"},
+		{"https://billing.internal/invoices", ""},
+		{"https://api.example.com/", ""},
+	}
+
+	for _, tt := range tests {
+		u, _ := url.Parse(tt.url)
+		got = ""
+		if _, err := transport.RoundTrip(&http.Request{Method: http.MethodGet, URL: u, Header: http.Header{}}); err != nil {
+			t.Fatalf("RoundTrip(%s): %v", tt.url, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Authorization = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/metrics"
+)
+
+// exitFlushTimeout bounds the flush before Fatal exits the process
+const exitFlushTimeout = 2 * time.Second
+
+var droppedEntriesTotal = metrics.Register(nil, prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "log_entries_dropped_total",
+		Help: "The total number of log entries dropped because the async log buffer was full",
+	},
+))
+
+// AsyncWriter decouples logging from a slow output such as a blocked stdout
+// pipe. Writes are queued in a bounded buffer and written by a background
+// goroutine, so logging never blocks the caller. When the buffer is full
+// the oldest entry is dropped to make room and counted in
+// log_entries_dropped_total.
+type AsyncWriter struct {
+	out     io.Writer
+	entries chan []byte
+	done    chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewAsyncWriter starts writing entries queued in a buffer of size entries
+// to out. Close it on shutdown to flush what is still queued.
+func NewAsyncWriter(out io.Writer, size int) *AsyncWriter {
+	if size < 1 {
+		size = 1
+	}
+
+	w := &AsyncWriter{
+		out:     out,
+		entries: make(chan []byte, size),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write queues a copy of p, since logrus reuses its buffers. Once the
+// writer is closed entries are written synchronously so nothing logged late
+// in shutdown is lost.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return w.out.Write(p)
+	}
+
+	entry := append([]byte(nil), p...)
+	for {
+		select {
+		case w.entries <- entry:
+			return len(p), nil
+		default:
+		}
+
+		// Buffer full: drop the oldest entry, unless the writer just took it
+		select {
+		case <-w.entries:
+			droppedEntriesTotal.Inc()
+		default:
+		}
+	}
+}
+
+// Close stops queueing and waits until the queued entries are written or
+// ctx is done
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+	for entry := range w.entries {
+		// There is nowhere to report a failed log write
+		_, _ = w.out.Write(entry)
+	}
+}
+
+// WithAsyncOutput writes entries through w instead of directly to stdout.
+// Fatal flushes w before exiting the process. Hooks such as syslog are not
+// affected and still run synchronously.
+func WithAsyncOutput(w *AsyncWriter) Option {
+	return func(log *logrus.Logger) {
+		log.SetOutput(w)
+		logrus.RegisterExitHandler(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), exitFlushTimeout)
+			defer cancel()
+			_ = w.Close(ctx)
+		})
+	}
+}
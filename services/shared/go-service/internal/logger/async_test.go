@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// blockedWriter blocks every write until released, like a stdout pipe
+// nobody reads
+type blockedWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func TestAsyncWriterDropsOldest(t *testing.T) {
+	out := &blockedWriter{release: make(chan struct{})}
+	w := NewAsyncWriter(out, 2)
+	droppedBefore := testutil.ToFloat64(droppedEntriesTotal)
+
+	const entries = 10
+	start := time.Now()
+	for i := 1; i <= entries; i++ {
+		if _, err := fmt.Fprintf(w, "entry %d\n", i); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("writes blocked for %s behind a stalled output", elapsed)
+	}
+
+	close(out.release)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := w.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	written := strings.Count(out.buf.String(), "\n")
+	dropped := int(testutil.ToFloat64(droppedEntriesTotal) - droppedBefore)
+	if dropped == 0 {
+		t.Error("no entries were dropped with a full buffer")
+	}
+	if written+dropped != entries {
+		t.Errorf("%d written + %d dropped, want %d entries accounted for", written, dropped, entries)
+	}
+	if !strings.Contains(out.buf.String(), fmt.Sprintf("entry %d\n", entries)) {
+		t.Errorf("newest entry was dropped; output:\n%s", out.buf.String())
+	}
+}
@@ -0,0 +1,17 @@
+package logger
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying log, typically a logger tagged
+// with the request's fields, for code that only receives a context
+func NewContext(ctx context.Context, log Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx by NewContext
+func FromContext(ctx context.Context) (Logger, bool) {
+	log, ok := ctx.Value(contextKey{}).(Logger)
+	return log, ok
+}
@@ -6,6 +6,13 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Standard field names, so logs can be queried the same way across services
+const (
+	FieldError     = "error"
+	FieldRequestID = "request_id"
+	FieldUserID    = "user_id"
+)
+
 type Logger interface {
 	Debug(args ...interface{})
 	Debugf(format string, args ...interface{})
@@ -19,6 +26,11 @@ type Logger interface {
 	Fatalf(format string, args ...interface{})
 	WithField(key string, value interface{}) Logger
 	WithFields(fields map[string]interface{}) Logger
+	WithError(err error) Logger
+	WithRequestID(requestID string) Logger
+	WithUser(userID string) Logger
+	Level() string
+	SetLevel(level string) error
 }
 
 type logrusLogger struct {
@@ -26,7 +38,10 @@ type logrusLogger struct {
 	entry  *logrus.Entry
 }
 
-func NewLogger(level string) Logger {
+// Option configures an additional logger output
+type Option func(*logrus.Logger)
+
+func NewLogger(level string, opts ...Option) Logger {
 	log := logrus.New()
 
 	// Set log level
@@ -44,6 +59,10 @@ func NewLogger(level string) Logger {
 	// Set output
 	log.SetOutput(os.Stdout)
 
+	for _, opt := range opts {
+		opt(log)
+	}
+
 	return &logrusLogger{
 		logger: log,
 		entry:  log.WithFields(logrus.Fields{}),
@@ -108,3 +127,37 @@ func (l *logrusLogger) WithFields(fields map[string]interface{}) Logger {
 		entry:  l.entry.WithFields(logrusFields),
 	}
 }
+
+// WithError adds err under the standard "error" field
+func (l *logrusLogger) WithError(err error) Logger {
+	return &logrusLogger{
+		logger: l.logger,
+		entry:  l.entry.WithField(FieldError, err),
+	}
+}
+
+// WithRequestID adds the request ID under the standard "request_id" field
+func (l *logrusLogger) WithRequestID(requestID string) Logger {
+	return l.WithField(FieldRequestID, requestID)
+}
+
+// WithUser adds the user ID under the standard "user_id" field
+func (l *logrusLogger) WithUser(userID string) Logger {
+	return l.WithField(FieldUserID, userID)
+}
+
+// Level returns the current log level
+func (l *logrusLogger) Level() string {
+	return l.logger.GetLevel().String()
+}
+
+// SetLevel changes the log level at runtime. The change applies to every
+// logger derived from the same root, since they share its level.
+func (l *logrusLogger) SetLevel(level string) error {
+	logLevel, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.logger.SetLevel(logLevel)
+	return nil
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newBufferLogger returns a logger writing JSON entries to the returned
+// buffer, with opts applied after the output is set
+func newBufferLogger(level string, opts ...Option) (Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	opts = append([]Option{func(l *logrus.Logger) { l.SetOutput(&buf) }}, opts...)
+	return NewLogger(level, opts...), &buf
+}
+
+// lastEntry decodes the last entry written to buf
+func lastEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+		t.Fatalf("decode entry %q: %v", lines[len(lines)-1], err)
+	}
+	return entry
+}
+
+func TestDerivedLoggersInheritFields(t *testing.T) {
+	root, buf := newBufferLogger("info")
+	request := root.WithRequestID("req-1").WithUser("user-1")
+
+	request.WithFields(map[string]interface{}{"order_id": "o-1"}).WithError(errors.New("boom")).Error("failed")
+	entry := lastEntry(t, buf)
+	want := map[string]interface{}{
+		FieldRequestID: "req-1",
+		FieldUserID:    "user-1",
+		FieldError:     "boom",
+		"order_id":     "o-1",
+		"msg":          "failed",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v in %v", key, entry[key], value, entry)
+		}
+	}
+
+	// Deriving doesn't add fields to the parent
+	request.Info("parent")
+	entry = lastEntry(t, buf)
+	if _, ok := entry["order_id"]; ok {
+		t.Errorf("parent logger picked up a derived field: %v", entry)
+	}
+	if entry[FieldRequestID] != "req-1" {
+		t.Errorf("parent lost %s: %v", FieldRequestID, entry)
+	}
+
+	root.Info("root")
+	entry = lastEntry(t, buf)
+	if _, ok := entry[FieldRequestID]; ok {
+		t.Errorf("root logger picked up a derived field: %v", entry)
+	}
+}
+
+func TestDerivedLoggersShareLevel(t *testing.T) {
+	root, buf := newBufferLogger("info")
+	derived := root.WithRequestID("req-1")
+
+	derived.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug entry written at info: %s", buf)
+	}
+
+	if err := derived.SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel: %v", err)
+	}
+	root.Debug("shown")
+	if entry := lastEntry(t, buf); entry["msg"] != "shown" {
+		t.Errorf("root didn't follow the derived logger's level: %v", entry)
+	}
+	if root.Level() != "debug" {
+		t.Errorf("Level = %q, want debug", root.Level())
+	}
+}
@@ -0,0 +1,30 @@
+package logger
+
+import "github.com/sirupsen/logrus"
+
+// SyslogOptions configures an additional syslog output
+type SyslogOptions struct {
+	// Network is "udp" or "tcp" for a remote daemon, empty for the local one
+	Network string
+	// Address of the remote daemon (host:port), ignored when Network is empty
+	Address string
+	// Facility name, e.g. "local0" or "daemon"
+	Facility string
+	// Tag identifies the service in syslog messages
+	Tag string
+}
+
+// WithSyslog sends every entry to syslog in addition to stdout. If the
+// daemon cannot be reached at startup a warning is logged and the service
+// keeps logging to stdout only. Syslog isn't available on Windows, where the
+// same warning is logged.
+func WithSyslog(opts SyslogOptions) Option {
+	return func(log *logrus.Logger) {
+		hook, err := newSyslogHook(opts)
+		if err != nil {
+			log.WithField(FieldError, err).Warn("Syslog unreachable, logging to stdout only")
+			return
+		}
+		log.AddHook(hook)
+	}
+}
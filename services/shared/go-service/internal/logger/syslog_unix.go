@@ -0,0 +1,51 @@
+//go:build !windows
+
+package logger
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	logrus_syslog "github.com/sirupsen/logrus/hooks/syslog"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+// newSyslogHook connects to the syslog daemon described by opts
+func newSyslogHook(opts SyslogOptions) (logrus.Hook, error) {
+	facility, ok := syslogFacilities[strings.ToLower(opts.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", opts.Facility)
+	}
+
+	address := opts.Address
+	if opts.Network == "" {
+		address = ""
+	}
+
+	// The hook maps each entry's level to the matching syslog severity
+	return logrus_syslog.NewSyslogHook(opts.Network, address, facility|syslog.LOG_INFO, opts.Tag)
+}
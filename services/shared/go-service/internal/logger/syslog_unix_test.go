@@ -0,0 +1,97 @@
+//go:build !windows
+
+package logger
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenSyslog starts a mock UDP syslog daemon, returning its address and a
+// channel of the messages it receives
+func listenSyslog(t *testing.T) (string, <-chan string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	messages := make(chan string, 16)
+	go func() {
+		buf := make([]byte, 64*1024)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			messages <- string(buf[:n])
+		}
+	}()
+	return conn.LocalAddr().String(), messages
+}
+
+func TestSyslogHook(t *testing.T) {
+	address, messages := listenSyslog(t)
+	log, buf := newBufferLogger("info", WithSyslog(SyslogOptions{
+		Network:  "udp",
+		Address:  address,
+		Facility: "local0",
+		Tag:      "svc",
+	}))
+
+	log.WithRequestID("req-1").Warn("disk almost full")
+
+	select {
+	case msg := <-messages:
+		// local0 (16) * 8 + warning (4)
+		if !strings.HasPrefix(msg, "<132>") {
+			t.Errorf("message %q, want priority <132> for local0.warning", msg)
+		}
+		for _, want := range []string{"svc", "disk almost full", "req-1"} {
+			if !strings.Contains(msg, want) {
+				t.Errorf("message %q doesn't contain %q", msg, want)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no message reached the syslog daemon")
+	}
+
+	// Entries still go to stdout too
+	if !strings.Contains(buf.String(), "disk almost full") {
+		t.Errorf("stdout output = %q, want the entry", buf)
+	}
+}
+
+func TestSyslogFallsBackToStdout(t *testing.T) {
+	// A TCP address nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	closed := listener.Addr().String()
+	listener.Close()
+
+	tests := []struct {
+		name string
+		opts SyslogOptions
+	}{
+		{"unknown facility", SyslogOptions{Network: "udp", Address: "127.0.0.1:514", Facility: "local9", Tag: "svc"}},
+		{"unreachable daemon", SyslogOptions{Network: "tcp", Address: closed, Facility: "local0", Tag: "svc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log, buf := newBufferLogger("info", WithSyslog(tt.opts))
+			if !strings.Contains(buf.String(), "Syslog unreachable, logging to stdout only") {
+				t.Errorf("output = %q, want the fallback warning", buf)
+			}
+
+			log.Info("still logging")
+			if entry := lastEntry(t, buf); entry["msg"] != "still logging" {
+				t.Errorf("last entry = %v, want it logged to stdout", entry)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build windows
+
+package logger
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// newSyslogHook fails: log/syslog isn't implemented on Windows
+func newSyslogHook(SyslogOptions) (logrus.Hook, error) {
+	return nil, fmt.Errorf("syslog is not supported on windows")
+}
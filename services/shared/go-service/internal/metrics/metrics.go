@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// maxLabels is the most labels a custom metric may declare
+	maxLabels = 3
+
+	// maxSeries is the most distinct label value combinations tracked per
+	// metric; further combinations are recorded under overflowValue
+	maxSeries = 100
+
+	overflowValue = "other"
+)
+
+// Custom metrics live in the default Prometheus registry so they are served
+// from the metrics endpoint alongside the HTTP metrics.
+var (
+	mu         sync.Mutex
+	registered = make(map[string]interface{})
+)
+
+// Counter is a monotonically increasing business metric, e.g.
+// users_registered_total. Use labels sparingly and only with bounded values
+// such as plan or outcome, never user IDs or emails.
+type Counter struct {
+	vec    *prometheus.CounterVec
+	series *series
+}
+
+// Gauge is a business metric that can go up and down, e.g. active_sessions
+type Gauge struct {
+	vec    *prometheus.GaugeVec
+	series *series
+}
+
+// NewCounter registers a counter, or returns the existing one if a counter
+// with the same name was already registered. It panics on more than 3
+// labels. A name the default registry rejects, e.g. one already taken by
+// another collector, is reported by RegistrationErrors instead.
+func NewCounter(name, help string, labels ...string) *Counter {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := registered[name]; ok {
+		counter, ok := existing.(*Counter)
+		if !ok {
+			panic(fmt.Sprintf("metrics: %s already registered as a different type", name))
+		}
+		return counter
+	}
+
+	checkLabels(name, labels)
+	vec := Register(nil, prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels))
+
+	counter := &Counter{vec: vec, series: newSeries()}
+	registered[name] = counter
+	return counter
+}
+
+// NewGauge registers a gauge, or returns the existing one if a gauge with the
+// same name was already registered
+func NewGauge(name, help string, labels ...string) *Gauge {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if existing, ok := registered[name]; ok {
+		gauge, ok := existing.(*Gauge)
+		if !ok {
+			panic(fmt.Sprintf("metrics: %s already registered as a different type", name))
+		}
+		return gauge
+	}
+
+	checkLabels(name, labels)
+	vec := Register(nil, prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels))
+
+	gauge := &Gauge{vec: vec, series: newSeries()}
+	registered[name] = gauge
+	return gauge
+}
+
+// Inc increments the counter by one
+func (c *Counter) Inc(labelValues ...string) {
+	c.vec.WithLabelValues(c.series.values(labelValues)...).Inc()
+}
+
+// Add increments the counter by v, which must not be negative
+func (c *Counter) Add(v float64, labelValues ...string) {
+	c.vec.WithLabelValues(c.series.values(labelValues)...).Add(v)
+}
+
+// Set sets the gauge to v
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.vec.WithLabelValues(g.series.values(labelValues)...).Set(v)
+}
+
+// Inc increments the gauge by one
+func (g *Gauge) Inc(labelValues ...string) {
+	g.vec.WithLabelValues(g.series.values(labelValues)...).Inc()
+}
+
+// Dec decrements the gauge by one
+func (g *Gauge) Dec(labelValues ...string) {
+	g.vec.WithLabelValues(g.series.values(labelValues)...).Dec()
+}
+
+func checkLabels(name string, labels []string) {
+	if len(labels) > maxLabels {
+		panic(fmt.Sprintf("metrics: %s declares %d labels, at most %d are allowed", name, len(labels), maxLabels))
+	}
+}
+
+// series bounds the number of distinct label value combinations of a metric
+type series struct {
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newSeries() *series {
+	return &series{seen: make(map[string]struct{})}
+}
+
+func (s *series) values(labelValues []string) []string {
+	if len(labelValues) == 0 {
+		return labelValues
+	}
+
+	key := fmt.Sprintf("%q", labelValues)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.seen[key]; ok {
+		return labelValues
+	}
+	if len(s.seen) >= maxSeries {
+		overflow := make([]string, len(labelValues))
+		for i := range overflow {
+			overflow[i] = overflowValue
+		}
+		return overflow
+	}
+	s.seen[key] = struct{}{}
+	return labelValues
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCounter(t *testing.T) {
+	counter := NewCounter("test_users_registered_total", "Test registrations", "plan")
+	free, pro := counter.vec.WithLabelValues("free"), counter.vec.WithLabelValues("pro")
+	freeBefore, proBefore := testutil.ToFloat64(free), testutil.ToFloat64(pro)
+
+	counter.Inc("free")
+	counter.Add(2, "pro")
+
+	if got := testutil.ToFloat64(free) - freeBefore; got != 1 {
+		t.Errorf("plan=free = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(pro) - proBefore; got != 2 {
+		t.Errorf("plan=pro = %v, want 2", got)
+	}
+
+	if NewCounter("test_users_registered_total", "Test registrations", "plan") != counter {
+		t.Error("registering the counter again didn't return the existing one")
+	}
+}
+
+func TestGauge(t *testing.T) {
+	gauge := NewGauge("test_active_sessions", "Test sessions")
+	gauge.Set(5)
+	gauge.Inc()
+	gauge.Dec()
+	gauge.Dec()
+
+	if got := testutil.ToFloat64(gauge.vec.WithLabelValues()); got != 4 {
+		t.Errorf("gauge = %v, want 4", got)
+	}
+}
+
+func TestRegisterMisuse(t *testing.T) {
+	NewCounter("test_misuse_total", "Test misuse")
+
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{"different type", func() { NewGauge("test_misuse_total", "Test misuse") }},
+		{"too many labels", func() { NewCounter("test_labels_total", "Test labels", "a", "b", "c", "d") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("registration didn't panic")
+				}
+			}()
+			tt.register()
+		})
+	}
+}
+
+func TestCounterSeriesLimit(t *testing.T) {
+	counter := NewCounter("test_series_total", "Test series", "customer")
+	overflow, first := counter.vec.WithLabelValues(overflowValue), counter.vec.WithLabelValues("0")
+	overflowBefore := testutil.ToFloat64(overflow)
+
+	for i := 0; i < maxSeries+10; i++ {
+		counter.Inc(fmt.Sprint(i))
+	}
+
+	// Label values past the limit are folded into a single series
+	if got := testutil.CollectAndCount(counter.vec); got != maxSeries+1 {
+		t.Errorf("series = %d, want %d plus the overflow series", got, maxSeries)
+	}
+	if got := testutil.ToFloat64(overflow) - overflowBefore; got != 10 {
+		t.Errorf("overflow series = %v, want 10", got)
+	}
+
+	// Values seen before the limit keep their own series
+	firstBefore := testutil.ToFloat64(first)
+	counter.Inc("0")
+	if got := testutil.ToFloat64(first) - firstBefore; got != 1 {
+		t.Errorf("customer=0 incremented by %v, want 1", got)
+	}
+}
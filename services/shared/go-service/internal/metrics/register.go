@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	failuresMu sync.Mutex
+	failures   []error
+)
+
+// Register registers c with reg (the default registry when nil) without
+// panicking. If an equivalent collector is already registered, for example
+// because two services share the default registry in one process, the
+// existing collector is returned so both feed the same series. Any other
+// registration failure is kept for RegistrationErrors and c is returned
+// unregistered: it still works but isn't exported.
+func Register[T prometheus.Collector](reg prometheus.Registerer, c T) T {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+
+	err := reg.Register(c)
+	if err == nil {
+		return c
+	}
+
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		if existing, ok := already.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	failures = append(failures, fmt.Errorf("collector not registered, it will not be exported: %w", err))
+	return c
+}
+
+// RegistrationErrors returns the failures of Register so far, joined, or nil.
+// Collectors are mostly registered at package initialization, before there
+// is a logger, so the app reports these once it has started.
+func RegistrationErrors() error {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	return errors.Join(failures...)
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// resetRegistrationErrors clears the failures recorded by earlier tests
+func resetRegistrationErrors() {
+	failuresMu.Lock()
+	defer failuresMu.Unlock()
+	failures = nil
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistrationErrors()
+	t.Cleanup(resetRegistrationErrors)
+
+	reg := prometheus.NewRegistry()
+	opts := prometheus.CounterOpts{Name: "test_events_total", Help: "Test events"}
+
+	first := Register(reg, prometheus.NewCounter(opts))
+	second := Register(reg, prometheus.NewCounter(opts))
+	if first != second {
+		t.Error("registering an equivalent collector didn't return the existing one")
+	}
+	if err := RegistrationErrors(); err != nil {
+		t.Fatalf("RegistrationErrors = %v, want nil", err)
+	}
+
+	// Same name, different help: a conflict rather than a duplicate
+	Register(reg, prometheus.NewCounter(prometheus.CounterOpts{Name: "test_events_total", Help: "Other"}))
+	if err := RegistrationErrors(); err == nil {
+		t.Error("RegistrationErrors = nil after a conflicting registration")
+	}
+}
+
+func TestNewCounterNameTaken(t *testing.T) {
+	resetRegistrationErrors()
+	t.Cleanup(resetRegistrationErrors)
+
+	// Another collector already uses the name with a different help string
+	taken := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_taken_total", Help: "Other"})
+	if err := prometheus.Register(taken); err != nil {
+		var already prometheus.AlreadyRegisteredError
+		if !errors.As(err, &already) {
+			t.Fatalf("Register: %v", err)
+		}
+	}
+
+	counter := NewCounter("test_taken_total", "Test taken")
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(registered, "test_taken_total")
+	})
+	counter.Inc()
+
+	if err := RegistrationErrors(); err == nil {
+		t.Error("RegistrationErrors = nil after a name collision")
+	}
+}
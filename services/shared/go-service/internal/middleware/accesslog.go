@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/logger"
+)
+
+// Access log formats accepted by Logger
+const (
+	// AccessLogJSON logs each request as a structured entry through the
+	// service logger
+	AccessLogJSON = "json"
+	// AccessLogCommon writes Apache Common Log Format lines
+	AccessLogCommon = "common"
+	// AccessLogCombined writes Apache Combined Log Format lines, which add
+	// the referer and user agent to the common format
+	AccessLogCombined = "combined"
+)
+
+// clfTimeFormat is the timestamp layout of the Apache log formats
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// Logger middleware logs every request in the given format. The Apache
+// formats are written to stdout as plain lines, bypassing the JSON logger,
+// for pipelines that parse them. The JSON format also logs the request
+// headers named in headers, except credentials (see loggedHeaders).
+func Logger(log logger.Logger, format string, headers []string) gin.HandlerFunc {
+	switch format {
+	case AccessLogCommon:
+		return gin.LoggerWithConfig(gin.LoggerConfig{Formatter: commonLogFormat, Output: os.Stdout})
+	case AccessLogCombined:
+		return gin.LoggerWithConfig(gin.LoggerConfig{Formatter: combinedLogFormat, Output: os.Stdout})
+	}
+
+	headers = loggedHeaders(headers, log)
+
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		fields := map[string]interface{}{
+			"client_ip":  param.ClientIP,
+			"timestamp":  param.TimeStamp.Format(time.RFC3339),
+			"method":     param.Method,
+			"path":       param.Path,
+			"protocol":   param.Request.Proto,
+			"status":     param.StatusCode,
+			"latency":    param.Latency,
+			"user_agent": param.Request.UserAgent(),
+			"error":      param.ErrorMessage,
+		}
+		if values := headerValues(param.Request.Header, headers); len(values) > 0 {
+			fields["headers"] = values
+		}
+		log.WithFields(fields).Info("HTTP Request")
+		return ""
+	})
+}
+
+// sensitiveHeaderWords mark header names as credentials when they contain
+// one, matched case-insensitively, e.g. X-Goog-Api-Key, X-Auth-Request-User
+// and X-Session-Id
+var sensitiveHeaderWords = []string{"key", "auth", "session", "cookie"}
+
+// loggedHeaders canonicalizes the header names to log, dropping credentials
+// and PII: the headers redacted from captures, and any whose name contains a
+// sensitive word such as "token" or "key". These are never logged, even when
+// listed by mistake.
+func loggedHeaders(names []string, log logger.Logger) []string {
+	logged := make([]string, 0, len(names))
+	for _, name := range names {
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		if sensitiveHeaders[name] || isSensitiveField(name) || isSensitiveHeader(name) {
+			log.Warnf("Header %s is never written to the access log", name)
+			continue
+		}
+		logged = append(logged, name)
+	}
+	return logged
+}
+
+// isSensitiveHeader reports whether name contains one of
+// sensitiveHeaderWords
+func isSensitiveHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, word := range sensitiveHeaderWords {
+		if strings.Contains(name, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerValues returns the values of the named headers present in header
+func headerValues(header http.Header, names []string) map[string]string {
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		if value := header.Values(name); len(value) > 0 {
+			values[name] = strings.Join(value, ", ")
+		}
+	}
+	return values
+}
+
+// commonLogFormat renders %h %l %u %t "%r" %>s %b
+func commonLogFormat(param gin.LogFormatterParams) string {
+	return commonLogLine(param) + "\n"
+}
+
+// combinedLogFormat renders the common format plus "%{Referer}i" "%{User-agent}i"
+func combinedLogFormat(param gin.LogFormatterParams) string {
+	return fmt.Sprintf("%s %s %s\n",
+		commonLogLine(param),
+		strconv.Quote(orDash(param.Request.Referer())),
+		strconv.Quote(orDash(param.Request.UserAgent())),
+	)
+}
+
+func commonLogLine(param gin.LogFormatterParams) string {
+	user := "-"
+	if userID, ok := param.Keys["user_id"].(string); ok && userID != "" {
+		user = userID
+	}
+
+	size := "-"
+	if param.BodySize > 0 {
+		size = strconv.Itoa(param.BodySize)
+	}
+
+	return fmt.Sprintf("%s - %s [%s] %s %d %s",
+		orDash(param.ClientIP),
+		user,
+		param.TimeStamp.Format(clfTimeFormat),
+		strconv.Quote(param.Method+" "+param.Path+" "+param.Request.Proto),
+		param.StatusCode,
+		size,
+	)
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/logger"
+)
+
+func TestLoggedHeaders(t *testing.T) {
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	names := []string{
+		"x-forwarded-for",
+		" Referer ",
+		"Authorization",
+		"Cookie",
+		"X-Admin-Token",
+		"X-Goog-Api-Key",
+		"X-Auth-Request-User",
+		"X-Session-Id",
+		"X-Csrf-Cookie-Name",
+		"X-User-Email",
+		"X-Client-Secret",
+		"X-Request-Id",
+	}
+	want := []string{"X-Forwarded-For", "Referer", "X-Request-Id"}
+
+	if got := loggedHeaders(names, log); !reflect.DeepEqual(got, want) {
+		t.Errorf("loggedHeaders = %v, want %v", got, want)
+	}
+}
+
+func TestApacheLogFormats(t *testing.T) {
+	timestamp := time.Date(2024, time.March, 5, 14, 7, 9, 0, time.FixedZone("", -7*60*60))
+
+	req := httptest.NewRequest(http.MethodGet, "/items?page=2", nil)
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set("User-Agent", `curl/8.0 "quoted"`)
+	param := gin.LogFormatterParams{
+		Request:    req,
+		TimeStamp:  timestamp,
+		StatusCode: http.StatusOK,
+		ClientIP:   "203.0.113.7",
+		Method:     http.MethodGet,
+		Path:       "/items?page=2",
+		BodySize:   512,
+		Keys:       map[string]interface{}{"user_id": "u-42"},
+	}
+	// No body, no user and no headers render as dashes
+	bare := gin.LogFormatterParams{
+		Request:    httptest.NewRequest(http.MethodDelete, "/items/1", nil),
+		TimeStamp:  timestamp,
+		StatusCode: http.StatusNoContent,
+		ClientIP:   "203.0.113.7",
+		Method:     http.MethodDelete,
+		Path:       "/items/1",
+	}
+	bare.Request.Header.Del("User-Agent")
+
+	tests := []struct {
+		name   string
+		format gin.LogFormatter
+		param  gin.LogFormatterParams
+		want   string
+	}{
+		{
+			"common", commonLogFormat, param,
+			`203.0.113.7 - u-42 [05/Mar/2024:14:07:09 -0700] "GET /items?page=2 HTTP/1.1" 200 512` + "\n",
+		},
+		{
+			"common without body or user", commonLogFormat, bare,
+			`203.0.113.7 - - [05/Mar/2024:14:07:09 -0700] "DELETE /items/1 HTTP/1.1" 204 -` + "\n",
+		},
+		{
+			"combined", combinedLogFormat, param,
+			`203.0.113.7 - u-42 [05/Mar/2024:14:07:09 -0700] "GET /items?page=2 HTTP/1.1" 200 512 "https://example.com/" "curl/8.0 \"quoted\""` + "\n",
+		},
+		{
+			"combined without headers", combinedLogFormat, bare,
+			`203.0.113.7 - - [05/Mar/2024:14:07:09 -0700] "DELETE /items/1 HTTP/1.1" 204 - "-" "-"` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.format(tt.param); got != tt.want {
+				t.Errorf("got  %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONAccessLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var buf bytes.Buffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&buf) })
+
+	router := gin.New()
+	router.Use(Logger(log, AccessLogJSON, []string{"X-Request-Id", "Authorization"}))
+	router.GET("/items", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	buf.Reset() // drop the warning about Authorization
+
+	req := httptest.NewRequest(http.MethodGet, "/items?page=2", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("User-Agent", "test-agent")
+	serve(router, req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("want one JSON entry, got %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"msg":        "HTTP Request",
+		"method":     "GET",
+		"path":       "/items?page=2",
+		"protocol":   "HTTP/1.1",
+		"status":     float64(http.StatusCreated),
+		"user_agent": "test-agent",
+		"headers":    map[string]interface{}{"X-Request-Id": "req-1"},
+	}
+	for key, value := range want {
+		if !reflect.DeepEqual(entry[key], value) {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, entry["timestamp"].(string)); err != nil {
+		t.Errorf("timestamp: %v", err)
+	}
+}
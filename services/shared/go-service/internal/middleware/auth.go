@@ -1,21 +1,104 @@
 package middleware
 
 import (
-	"net/http"
+	"crypto/subtle"
+	"net/netip"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/reqctx"
+	"{{ module_name }}/internal/response"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// JWTKeyfunc returns a jwt.Keyfunc accepting HMAC-signed tokens verified by
+// the current secret or any of the previous ones. Keeping recently rotated
+// secrets in previousSecrets lets tokens issued before a rotation validate
+// until they expire.
+func JWTKeyfunc(secret string, previousSecrets ...string) jwt.Keyfunc {
+	keys := jwt.VerificationKeySet{Keys: []jwt.VerificationKey{[]byte(secret)}}
+	for _, previous := range previousSecrets {
+		keys.Keys = append(keys.Keys, []byte(previous))
+	}
+
+	return func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return keys, nil
+	}
+}
+
+// AuthOptions configures AuthMiddleware
+type AuthOptions struct {
+	// Secret signs and verifies tokens; PreviousSecrets still verify tokens
+	// during a rotation
+	Secret          string
+	PreviousSecrets []string
+
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+
+	// Gateway, when TrustHeaders is set, accepts the identity forwarded in
+	// headers by an API gateway that has already verified the JWT
+	Gateway GatewayOptions
+}
+
+// GatewayOptions configures trust in identity headers set by an API gateway.
+// Headers are only honoured on requests whose direct peer is one of
+// TrustedProxies, and are stripped from all other requests.
+type GatewayOptions struct {
+	TrustHeaders   bool
+	TrustedProxies []netip.Prefix
+	UserIDHeader   string
+	EmailHeader    string
+}
+
+func (g GatewayOptions) trusts(remoteIP string) bool {
+	addr, err := netip.ParseAddr(remoteIP)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range g.TrustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthMiddleware validates JWT tokens signed with the current or a previous
+// secret. When gateway headers are trusted, requests from a trusted proxy
+// carrying a user ID header are authenticated from the headers instead.
+func AuthMiddleware(opts AuthOptions) gin.HandlerFunc {
+	keyfunc := JWTKeyfunc(opts.Secret, opts.PreviousSecrets...)
+	parser := jwt.NewParser(jwt.WithIssuedAt(), jwt.WithLeeway(opts.Leeway))
+	gateway := opts.Gateway
+
 	return func(c *gin.Context) {
+		if gateway.TrustHeaders {
+			if gateway.trusts(c.RemoteIP()) {
+				if userID := c.GetHeader(gateway.UserIDHeader); userID != "" {
+					c.Set("user_id", userID)
+					c.Set("email", c.GetHeader(gateway.EmailHeader))
+					c.Set("auth_source", "gateway")
+					c.Next()
+					return
+				}
+			} else {
+				// Don't let handlers mistake spoofed headers for identity
+				c.Request.Header.Del(gateway.UserIDHeader)
+				c.Request.Header.Del(gateway.EmailHeader)
+			}
+		}
+
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header required",
-			})
+			response.Error(c, apperror.Unauthenticated("Authorization header required"))
 			c.Abort()
 			return
 		}
@@ -23,25 +106,16 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		// Extract token from Bearer header
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format",
-			})
+			response.Error(c, apperror.Unauthenticated("Invalid authorization header format"))
 			c.Abort()
 			return
 		}
 
 		// Parse and validate token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(jwtSecret), nil
-		})
+		token, err := parser.Parse(tokenString, keyfunc)
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid token",
-			})
+			response.Error(c, apperror.Unauthenticated("Invalid token"))
 			c.Abort()
 			return
 		}
@@ -55,3 +129,33 @@ func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// AdminAuth restricts routes to callers presenting the admin token in the
+// X-Admin-Token header
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader("X-Admin-Token")
+		if provided == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			response.Error(c, apperror.Unauthenticated("Admin authorization required"))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// DebugSQL middleware enables verbose SQL logging for requests sending
+// "X-Debug-SQL: true". The header is only honoured alongside a valid
+// X-Admin-Token so clients can't flood the logs.
+func DebugSQL(adminToken string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("X-Debug-SQL") == "true" {
+			provided := c.GetHeader("X-Admin-Token")
+			if provided != "" && subtle.ConstantTimeCompare([]byte(provided), []byte(adminToken)) == 1 {
+				c.Request = c.Request.WithContext(reqctx.WithDebugSQL(c.Request.Context()))
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testJWTSecret = "test-secret-at-least-32-bytes-long"
+
+// signToken signs claims for user-1 with secret, adjusted by configure
+func signToken(t *testing.T, secret string, configure func(claims jwt.MapClaims)) string {
+	t.Helper()
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"user_id": "user-1",
+		"email":   "alice@example.com",
+		"iat":     now.Unix(),
+		"nbf":     now.Unix(),
+		"exp":     now.Add(time.Hour).Unix(),
+	}
+	if configure != nil {
+		configure(claims)
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return token
+}
+
+// newAuthRouter returns a router serving GET /private behind AuthMiddleware,
+// responding with the authenticated user ID
+func newAuthRouter(opts AuthOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/private", AuthMiddleware(opts), func(c *gin.Context) {
+		c.String(http.StatusOK, "%v", c.Value("user_id"))
+	})
+	return router
+}
+
+// getPrivate sends GET /private with token as a bearer token, if set
+func getPrivate(router http.Handler, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/private", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return serve(router, req)
+}
+
+func TestAuthMiddlewareLeeway(t *testing.T) {
+	inFuture := func(claim string, d time.Duration) func(jwt.MapClaims) {
+		return func(claims jwt.MapClaims) {
+			claims[claim] = time.Now().Add(d).Unix()
+		}
+	}
+
+	tests := []struct {
+		name      string
+		leeway    time.Duration
+		configure func(jwt.MapClaims)
+		want      int
+	}{
+		{"valid", 0, nil, http.StatusOK},
+		{"just expired within leeway", 30 * time.Second, inFuture("exp", -10*time.Second), http.StatusOK},
+		{"just expired without leeway", 0, inFuture("exp", -10*time.Second), http.StatusUnauthorized},
+		{"expired beyond leeway", 30 * time.Second, inFuture("exp", -time.Minute), http.StatusUnauthorized},
+		{"not yet valid within leeway", 30 * time.Second, inFuture("nbf", 10*time.Second), http.StatusOK},
+		{"not yet valid without leeway", 0, inFuture("nbf", 10*time.Second), http.StatusUnauthorized},
+		{"issued in the future within leeway", 30 * time.Second, inFuture("iat", 10*time.Second), http.StatusOK},
+		{"issued in the future beyond leeway", 30 * time.Second, inFuture("iat", time.Minute), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthRouter(AuthOptions{Secret: testJWTSecret, Leeway: tt.leeway})
+
+			w := getPrivate(router, signToken(t, testJWTSecret, tt.configure))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareSecretRotation(t *testing.T) {
+	const (
+		previous = "previous-secret-at-least-32-bytes-long"
+		older    = "older-secret-at-least-32-bytes-long!!"
+		unknown  = "unknown-secret-at-least-32-bytes-long"
+	)
+
+	tests := []struct {
+		name     string
+		previous []string
+		signedBy string
+		want     int
+	}{
+		{"current secret", []string{previous}, testJWTSecret, http.StatusOK},
+		{"previous secret", []string{previous}, previous, http.StatusOK},
+		{"any previous secret", []string{previous, older}, older, http.StatusOK},
+		{"unknown secret", []string{previous}, unknown, http.StatusUnauthorized},
+		{"rotated out", nil, previous, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newAuthRouter(AuthOptions{Secret: testJWTSecret, PreviousSecrets: tt.previous})
+
+			w := getPrivate(router, signToken(t, tt.signedBy, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && w.Body.String() != "user-1" {
+				t.Errorf("authenticated as %q, want user-1", w.Body)
+			}
+		})
+	}
+}
+
+func TestJWTKeyfuncRejectsNonHMAC(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{"user_id": "user-1"}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+
+	w := getPrivate(newAuthRouter(AuthOptions{Secret: testJWTSecret}), token)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("unsigned token: status = %d, want 401", w.Code)
+	}
+}
+
+func TestAuthMiddlewareGatewayHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/private", AuthMiddleware(AuthOptions{
+		Secret: testJWTSecret,
+		Gateway: GatewayOptions{
+			TrustHeaders:   true,
+			TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			UserIDHeader:   "X-User-ID",
+			EmailHeader:    "X-User-Email",
+		},
+	}), func(c *gin.Context) {
+		// Report the identity and the identity headers handlers see
+		c.String(http.StatusOK, "%v|%s|%s", c.Value("user_id"), c.GetHeader("X-User-ID"), c.GetHeader("X-User-Email"))
+	})
+	token := signToken(t, testJWTSecret, nil)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		token      string
+		want       int
+		wantBody   string
+	}{
+		{"trusted peer", "10.1.2.3:40000", "", http.StatusOK, "spoof|spoof|spoof@example.com"},
+		{"trusted IPv4-mapped IPv6 peer", "[::ffff:10.1.2.3]:40000", "", http.StatusOK, "spoof|spoof|spoof@example.com"},
+		{"untrusted peer falls back to the JWT", "203.0.113.5:40000", token, http.StatusOK, "user-1||"},
+		{"untrusted peer without a JWT", "203.0.113.5:40000", "", http.StatusUnauthorized, ""},
+		{"untrusted IPv6 peer", "[2001:db8::1]:40000", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/private", nil)
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-User-ID", "spoof")
+			req.Header.Set("X-User-Email", "spoof@example.com")
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := serve(router, req)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("user|headers = %q, want %q", w.Body, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bodyLimitKey holds the *limitedBody installed by MaxBodySize
+const bodyLimitKey = "body_limit"
+
+// limitedBody fails reads past limit with *http.MaxBytesError, like
+// http.MaxBytesReader, but its limit can still be changed by
+// RouteMaxBodySize after later middleware (such as DecompressRequest) has
+// wrapped it
+type limitedBody struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+	// declared is the Content-Length sent by the client, -1 if unknown
+	declared int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	remaining := b.limit - b.read
+	if remaining < 0 || b.declared > b.limit {
+		return 0, &http.MaxBytesError{Limit: b.limit}
+	}
+
+	// Read one byte past the limit to tell a body of exactly limit bytes
+	// from a larger one
+	if int64(len(p)) > remaining+1 {
+		p = p[:remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > remaining {
+		b.read = b.limit + 1
+		return int(remaining), &http.MaxBytesError{Limit: b.limit}
+	}
+	b.read += int64(n)
+	return n, err
+}
+
+// MaxBodySize middleware limits request bodies to limit bytes as sent on the
+// wire. Reading past the limit, or reading at all when the Content-Length
+// already exceeds it, fails with *http.MaxBytesError, which request binding
+// reports as 413. The check happens on read rather than up front so that
+// routes needing a different limit, such as file uploads, can still override
+// it with RouteMaxBodySize.
+func MaxBodySize(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body := &limitedBody{ReadCloser: c.Request.Body, limit: limit, declared: c.Request.ContentLength}
+		c.Request.Body = body
+		c.Set(bodyLimitKey, body)
+		c.Next()
+	}
+}
+
+// RouteMaxBodySize overrides the MaxBodySize limit for a route or group,
+// raising or lowering it:
+//
+//	api.POST("/files", middleware.RouteMaxBodySize(100<<20), handlers.Upload())
+//
+// It must run before the body is read. Without MaxBodySize installed it
+// applies the limit itself.
+func RouteMaxBodySize(limit int64) gin.HandlerFunc {
+	apply := MaxBodySize(limit)
+	return func(c *gin.Context) {
+		value, ok := c.Get(bodyLimitKey)
+		if !ok {
+			apply(c)
+			return
+		}
+
+		value.(*limitedBody).limit = limit
+		c.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRouteMaxBodySize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	readBody := func(c *gin.Context) {
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.Status(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+
+	router := gin.New()
+	router.Use(MaxBodySize(10), DecompressRequest(1<<20))
+	router.POST("/default", readBody)
+	router.POST("/raised", RouteMaxBodySize(100), readBody)
+	router.POST("/lowered", RouteMaxBodySize(4), readBody)
+
+	standalone := gin.New()
+	standalone.POST("/raised", RouteMaxBodySize(100), readBody)
+
+	var gzipped bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	_, _ = zw.Write([]byte(strings.Repeat("a", 500)))
+	_ = zw.Close()
+
+	tests := []struct {
+		name     string
+		router   *gin.Engine
+		path     string
+		body     string
+		encoding string
+		want     int
+	}{
+		{"within default", router, "/default", "0123456789", "", http.StatusOK},
+		{"over default", router, "/default", "0123456789a", "", http.StatusRequestEntityTooLarge},
+		{"raised", router, "/raised", strings.Repeat("a", 50), "", http.StatusOK},
+		{"over raised", router, "/raised", strings.Repeat("a", 101), "", http.StatusRequestEntityTooLarge},
+		{"raised after decompression is set up", router, "/raised", gzipped.String(), "gzip", http.StatusOK},
+		{"invalid gzip", router, "/raised", "not gzip", "gzip", http.StatusBadRequest},
+		{"lowered", router, "/lowered", "01234", "", http.StatusRequestEntityTooLarge},
+		{"without MaxBodySize", standalone, "/raised", strings.Repeat("a", 101), "", http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			tt.router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxCaptureBody = 4 << 10
+	redacted       = "[REDACTED]"
+)
+
+// sensitiveHeaders are never captured verbatim or written to the access log
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Admin-Token":       true,
+	"X-Api-Key":           true,
+}
+
+// sensitiveFields are body fields redacted from captures, matched
+// case-insensitively as substrings of the field name
+var sensitiveFields = []string{"password", "secret", "token", "email", "phone", "ssn", "card"}
+
+// CapturedRequest is a redacted snapshot of an incoming request
+type CapturedRequest struct {
+	Timestamp time.Time         `json:"timestamp"`
+	RequestID string            `json:"request_id"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Query     string            `json:"query,omitempty"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body,omitempty"`
+	Truncated bool              `json:"truncated,omitempty"`
+}
+
+// CaptureBuffer is a fixed-size ring buffer of captured requests
+type CaptureBuffer struct {
+	mu      sync.Mutex
+	entries []CapturedRequest
+	next    int
+	full    bool
+}
+
+// NewCaptureBuffer creates a buffer holding the most recent size captures
+func NewCaptureBuffer(size int) *CaptureBuffer {
+	if size <= 0 {
+		size = 100
+	}
+	return &CaptureBuffer{entries: make([]CapturedRequest, size)}
+}
+
+// Add stores a capture, evicting the oldest once the buffer is full
+func (b *CaptureBuffer) Add(entry CapturedRequest) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Recent returns the buffered captures, oldest first
+func (b *CaptureBuffer) Recent() []CapturedRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]CapturedRequest(nil), b.entries[:b.next]...)
+	}
+	return append(append([]CapturedRequest(nil), b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// Capture middleware records a sampled fraction of requests into buffer for
+// debugging. Credentials and PII are redacted from headers and bodies, and
+// bodies are truncated to 4KB.
+func Capture(sampleRate float64, buffer *CaptureBuffer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if sampleRate <= 0 || rand.Float64() >= sampleRate {
+			c.Next()
+			return
+		}
+
+		entry := CapturedRequest{
+			Timestamp: time.Now(),
+			RequestID: c.GetString("request_id"),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Query:     redactQuery(c.Request.URL.Query()),
+			Headers:   redactHeaders(c.Request.Header),
+		}
+
+		if c.Request.Body != nil && c.Request.Body != http.NoBody {
+			body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxCaptureBody+1))
+			if err == nil {
+				// Hand the full body back to the handler
+				c.Request.Body = readCloser{io.MultiReader(bytes.NewReader(body), c.Request.Body), c.Request.Body}
+
+				entry.Truncated = len(body) > maxCaptureBody
+				if entry.Truncated {
+					body = body[:maxCaptureBody]
+				}
+				entry.Body = redactBody(c.ContentType(), body, entry.Truncated)
+			}
+		}
+
+		buffer.Add(entry)
+		c.Next()
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			headers[name] = redacted
+			continue
+		}
+		headers[name] = strings.Join(values, ", ")
+	}
+	return headers
+}
+
+func redactQuery(values url.Values) string {
+	for key := range values {
+		if isSensitiveField(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+func redactBody(contentType string, body []byte, truncated bool) string {
+	switch contentType {
+	case "application/json":
+		if truncated {
+			return "[truncated JSON body omitted]"
+		}
+		var payload interface{}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return "[invalid JSON body omitted]"
+		}
+		out, err := json.Marshal(redactValue(payload))
+		if err != nil {
+			return "[unencodable JSON body omitted]"
+		}
+		return string(out)
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return "[invalid form body omitted]"
+		}
+		return redactQuery(values)
+	default:
+		return "[" + contentType + " body omitted]"
+	}
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if isSensitiveField(key) {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactValue(inner)
+		}
+	case []interface{}:
+		for i, inner := range v {
+			v[i] = redactValue(inner)
+		}
+	}
+	return value
+}
+
+func isSensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, field := range sensitiveFields {
+		if strings.Contains(name, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCaptureRouter returns a router capturing at sampleRate into buffer,
+// whose POST / handler records the body it reads in body if set
+func newCaptureRouter(sampleRate float64, buffer *CaptureBuffer, body *string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Capture(sampleRate, buffer))
+	router.POST("/", func(c *gin.Context) {
+		data, _ := io.ReadAll(c.Request.Body)
+		if body != nil {
+			*body = string(data)
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestCaptureSampling(t *testing.T) {
+	const requests = 1000
+
+	tests := []struct {
+		name       string
+		sampleRate float64
+		min, max   int
+	}{
+		{"disabled", 0, 0, 0},
+		{"every request", 1, requests, requests},
+		{"half", 0.5, 400, 600},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buffer := NewCaptureBuffer(requests)
+			router := newCaptureRouter(tt.sampleRate, buffer, nil)
+
+			for i := 0; i < requests; i++ {
+				serve(router, httptest.NewRequest(http.MethodPost, "/", nil))
+			}
+
+			if got := len(buffer.Recent()); got < tt.min || got > tt.max {
+				t.Errorf("captured %d of %d requests, want %d to %d", got, requests, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestCaptureBuffer(t *testing.T) {
+	buffer := NewCaptureBuffer(3)
+	paths := func() string {
+		var got []string
+		for _, entry := range buffer.Recent() {
+			got = append(got, entry.Path)
+		}
+		return strings.Join(got, " ")
+	}
+
+	buffer.Add(CapturedRequest{Path: "/1"})
+	buffer.Add(CapturedRequest{Path: "/2"})
+	if got := paths(); got != "/1 /2" {
+		t.Errorf("Recent = %s, want /1 /2", got)
+	}
+
+	// Once full, the oldest captures are evicted
+	for _, path := range []string{"/3", "/4", "/5"} {
+		buffer.Add(CapturedRequest{Path: path})
+	}
+	if got := paths(); got != "/3 /4 /5" {
+		t.Errorf("Recent = %s, want /3 /4 /5 oldest first", got)
+	}
+}
+
+func TestCaptureRedaction(t *testing.T) {
+	buffer := NewCaptureBuffer(1)
+	var handled string
+	router := newCaptureRouter(1, buffer, &handled)
+
+	body := `{"name":"Alice","password":"hunter2","profile":{"email":"alice@example.com"}}`
+	req := httptest.NewRequest(http.MethodPost, "/?page=2&access_token=abc", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("Cookie", "session=abc")
+	req.Header.Set("X-Api-Key", "key-123")
+	req.Header.Set("X-Request-ID", "req-1")
+	serve(router, req)
+
+	if handled != body {
+		t.Errorf("handler read %q, want the full body", handled)
+	}
+
+	captures := buffer.Recent()
+	if len(captures) != 1 {
+		t.Fatalf("captured %d requests, want 1", len(captures))
+	}
+	entry := captures[0]
+
+	for _, name := range []string{"Authorization", "Cookie", "X-Api-Key"} {
+		if entry.Headers[name] != redacted {
+			t.Errorf("header %s = %q, want it redacted", name, entry.Headers[name])
+		}
+	}
+	if entry.Headers["X-Request-Id"] != "req-1" {
+		t.Errorf("X-Request-ID = %q, want it kept", entry.Headers["X-Request-Id"])
+	}
+	if entry.Query != "access_token=%5BREDACTED%5D&page=2" {
+		t.Errorf("query = %q, want access_token redacted", entry.Query)
+	}
+	for _, secret := range []string{"hunter2", "alice@example.com", "secret-token"} {
+		if strings.Contains(entry.Body, secret) {
+			t.Errorf("body %s leaks %q", entry.Body, secret)
+		}
+	}
+	if !strings.Contains(entry.Body, `"name":"Alice"`) {
+		t.Errorf("body = %s, want non-sensitive fields kept", entry.Body)
+	}
+}
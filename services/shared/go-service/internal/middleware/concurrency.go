@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/response"
+)
+
+var requestsInFlight = metrics.Register(nil, prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "http_requests_in_flight",
+		Help: "The number of HTTP requests currently being served",
+	},
+))
+
+// inFlight mirrors requestsInFlight for RequestsInFlight
+var inFlight atomic.Int64
+
+// RequestsInFlight returns the number of requests currently being served,
+// as tracked by ConcurrencyLimit
+func RequestsInFlight() int64 {
+	return inFlight.Load()
+}
+
+// trackInFlight counts a request as in flight until the returned function
+// is called
+func trackInFlight() func() {
+	requestsInFlight.Inc()
+	inFlight.Add(1)
+	return func() {
+		requestsInFlight.Dec()
+		inFlight.Add(-1)
+	}
+}
+
+// ConcurrencyLimit middleware sheds load by capping the number of requests
+// served at once. A request arriving at capacity waits up to queueTimeout for
+// a slot and is then rejected with 503. A max of 0 disables the limit; the
+// in-flight gauge is maintained either way.
+func ConcurrencyLimit(max int, queueTimeout time.Duration) gin.HandlerFunc {
+	if max <= 0 {
+		return func(c *gin.Context) {
+			defer trackInFlight()()
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, max)
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			timer := time.NewTimer(queueTimeout)
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				c.Header("Retry-After", "1")
+				response.Error(c, apperror.Unavailable("Server is at capacity"))
+				c.Abort()
+				return
+			case <-c.Request.Context().Done():
+				timer.Stop()
+				c.Abort()
+				return
+			}
+		}
+
+		done := trackInFlight()
+		defer func() {
+			done()
+			<-slots
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newConcurrencyRouter returns a router limited to max concurrent requests
+// serving GET /block, which signals started and waits for release, and
+// GET /fast
+func newConcurrencyRouter(max int, queueTimeout time.Duration, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(ConcurrencyLimit(max, queueTimeout))
+	router.GET("/block", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+// serveAsync serves a GET of path in the background, sending the response
+// on the returned channel
+func serveAsync(router http.Handler, path string) <-chan *httptest.ResponseRecorder {
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		done <- serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+	}()
+	return done
+}
+
+func TestConcurrencyLimitRejectsOverCapacity(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	const queueTimeout = 50 * time.Millisecond
+	router := newConcurrencyRouter(2, queueTimeout, started, release)
+
+	blocked := []<-chan *httptest.ResponseRecorder{serveAsync(router, "/block"), serveAsync(router, "/block")}
+	<-started
+	<-started
+
+	start := time.Now()
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503 at capacity", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want 1", got)
+	}
+	if elapsed := time.Since(start); elapsed < queueTimeout {
+		t.Errorf("rejected after %s, want to wait the %s queue timeout first", elapsed, queueTimeout)
+	}
+
+	close(release)
+	for _, done := range blocked {
+		if w := <-done; w.Code != http.StatusOK {
+			t.Errorf("blocked request = %d, want 200", w.Code)
+		}
+	}
+}
+
+func TestConcurrencyLimitQueuesUntilSlotFrees(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	router := newConcurrencyRouter(1, 5*time.Second, started, release)
+
+	first := serveAsync(router, "/block")
+	<-started
+
+	queued := serveAsync(router, "/fast")
+	select {
+	case w := <-queued:
+		t.Fatalf("queued request served with %d while the slot was taken", w.Code)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	if w := <-first; w.Code != http.StatusOK {
+		t.Errorf("first request = %d, want 200", w.Code)
+	}
+	select {
+	case w := <-queued:
+		if w.Code != http.StatusOK {
+			t.Errorf("queued request = %d, want 200 once the slot freed", w.Code)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued request not served after the slot freed")
+	}
+}
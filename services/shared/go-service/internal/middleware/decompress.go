@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/response"
+)
+
+// gzipBody decompresses the request body. The gzip header is only read on
+// the first Read, so a RouteMaxBodySize later in the chain still applies to
+// it. Close closes both the gzip reader and the underlying request body.
+type gzipBody struct {
+	body io.ReadCloser
+	gz   *gzip.Reader
+	err  error
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.gz == nil && b.err == nil {
+		b.gz, b.err = gzip.NewReader(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.gz.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	if b.gz != nil {
+		b.gz.Close()
+	}
+	return b.body.Close()
+}
+
+// DecompressRequest middleware transparently decompresses gzip-encoded
+// request bodies (Content-Encoding: gzip) so handlers can bind them as usual.
+// Reading more than maxBytes of decompressed data fails with
+// *http.MaxBytesError, so a small compressed body can't expand without bound.
+// A body that isn't valid gzip fails when it is read. Other encodings are
+// rejected with 415.
+func DecompressRequest(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		switch encoding {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			response.Error(c, apperror.UnsupportedMediaType("Unsupported Content-Encoding: "+encoding))
+			c.Abort()
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, &gzipBody{body: c.Request.Body}, maxBytes)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Del("Content-Length")
+		c.Request.ContentLength = -1
+
+		c.Next()
+	}
+}
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompressRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const maxBytes = 1 << 10
+	payload := []byte(`{"name":"alice"}`)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantCode int
+		wantBody string
+		wantErr  func(error) bool
+	}{
+		{"gzip", "gzip", gzipped(t, payload), http.StatusOK, string(payload), nil},
+		{"x-gzip", "X-Gzip", gzipped(t, payload), http.StatusOK, string(payload), nil},
+		{"identity", "identity", payload, http.StatusOK, string(payload), nil},
+		{"no encoding", "", payload, http.StatusOK, string(payload), nil},
+		{"invalid gzip", "gzip", payload, http.StatusOK, "", func(err error) bool {
+			return errors.Is(err, gzip.ErrHeader)
+		}},
+		{"decompresses past the limit", "gzip", gzipped(t, bytes.Repeat([]byte("a"), 64<<10)), http.StatusOK, "", func(err error) bool {
+			var tooLarge *http.MaxBytesError
+			return errors.As(err, &tooLarge)
+		}},
+		{"unsupported encoding", "br", payload, http.StatusUnsupportedMediaType, "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got     []byte
+				readErr error
+				header  string
+			)
+			router := gin.New()
+			router.Use(DecompressRequest(maxBytes))
+			router.POST("/", func(c *gin.Context) {
+				header = c.GetHeader("Content-Encoding")
+				got, readErr = io.ReadAll(c.Request.Body)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := serve(router, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.wantCode, w.Body)
+			}
+			if tt.wantCode != http.StatusOK {
+				if !strings.Contains(w.Body.String(), "unsupported_media_type") {
+					t.Errorf("body = %s, want the unsupported_media_type envelope", w.Body)
+				}
+				return
+			}
+			if tt.wantErr != nil {
+				if !tt.wantErr(readErr) {
+					t.Errorf("read error = %v", readErr)
+				}
+				return
+			}
+			if readErr != nil || string(got) != tt.wantBody {
+				t.Errorf("body = %q (%v), want %q", got, readErr, tt.wantBody)
+			}
+			if strings.Contains(strings.ToLower(tt.encoding), "gzip") && header != "" {
+				t.Errorf("Content-Encoding = %q after decompression, want it removed", header)
+			}
+		})
+	}
+}
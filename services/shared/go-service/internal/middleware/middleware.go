@@ -1,64 +1,86 @@
 package middleware
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/time/rate"
 
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/ctxcache"
 	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/reqctx"
+	"{{ module_name }}/internal/response"
 )
 
+// statusClientClosedRequest is the non-standard status (popularised by nginx)
+// used to label requests whose client went away before a response was sent.
+const statusClientClosedRequest = "499"
+
+// otherTenant is the metrics label for requests without a tenant or whose
+// tenant isn't one of the labeled tenants.
+const otherTenant = "other"
+
 var (
-	requestsTotal = promauto.NewCounterVec(
+	requestsTotal = metrics.Register(nil, prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "The total number of HTTP requests",
 		},
-		[]string{"method", "path", "status"},
-	)
+		[]string{"method", "path", "status", "tenant", "authenticated"},
+	))
 
-	requestDuration = promauto.NewHistogramVec(
+	deprecatedRequestsTotal = metrics.Register(nil, prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_deprecated_requests_total",
+			Help: "The total number of requests to deprecated API routes",
+		},
+		[]string{"method", "path"},
+	))
+
+	requestDuration = metrics.Register(nil, prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "http_request_duration_seconds",
 			Help:    "The HTTP request latencies in seconds",
 			Buckets: prometheus.DefBuckets,
 		},
 		[]string{"method", "path"},
-	)
-)
+	))
 
-// Logger middleware
-func Logger(log logger.Logger) gin.HandlerFunc {
-	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
-		log.WithFields(map[string]interface{}{
-			"client_ip":  param.ClientIP,
-			"timestamp":  param.TimeStamp.Format(time.RFC3339),
-			"method":     param.Method,
-			"path":       param.Path,
-			"protocol":   param.Request.Proto,
-			"status":     param.StatusCode,
-			"latency":    param.Latency,
-			"user_agent": param.Request.UserAgent(),
-			"error":      param.ErrorMessage,
-		}).Info("HTTP Request")
-		return ""
-	})
-}
+	// Sizes are the body bytes written to the connection, so behind a
+	// response compression middleware they are compressed sizes
+	responseSize = metrics.Register(nil, prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_response_size_bytes",
+			Help:    "The HTTP response body sizes in bytes, as sent",
+			Buckets: prometheus.ExponentialBuckets(100, 10, 7),
+		},
+		[]string{"method", "path"},
+	))
+)
 
 // CORS middleware
-func CORS(origins []string) gin.HandlerFunc {
+func CORS(runtime *config.Runtime) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 
-		// Check if origin is allowed
+		// Check if origin is allowed. Requests without an Origin header
+		// aren't cross-origin, and an empty list denies every origin.
 		allowed := false
-		for _, allowedOrigin := range origins {
-			if allowedOrigin == "*" || allowedOrigin == origin {
+		for _, allowedOrigin := range runtime.Load().CORSOrigins {
+			if origin != "" && (allowedOrigin == "*" || allowedOrigin == origin) {
 				allowed = true
 				break
 			}
@@ -67,10 +89,14 @@ func CORS(origins []string) gin.HandlerFunc {
 		if allowed {
 			c.Header("Access-Control-Allow-Origin", origin)
 		}
+		// The response depends on the request origin, so caches must not
+		// serve it to other origins
+		c.Header("Vary", "Origin")
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Request-ID, X-Tenant-ID")
 		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Expose-Headers", "RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -81,15 +107,120 @@ func CORS(origins []string) gin.HandlerFunc {
 	}
 }
 
-// Rate limiter middleware
-func RateLimit(requestsPerMinute int) gin.HandlerFunc {
-	limiter := rate.NewLimiter(rate.Limit(requestsPerMinute)/60, requestsPerMinute)
+// KeyFunc returns the key requests are rate limited by; each key gets its
+// own budget
+type KeyFunc func(c *gin.Context) string
+
+// KeyByIP limits each client IP separately. The IP is gin's ClientIP, which
+// only honors X-Forwarded-For from TRUSTED_PROXIES (see newRouter), so
+// clients can't pick their own key by spoofing the header.
+func KeyByIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// KeyByHeader limits by the value of header, e.g. an API key, falling back
+// to the client IP for requests without it
+func KeyByHeader(header string) KeyFunc {
+	return func(c *gin.Context) string {
+		if value := c.GetHeader(header); value != "" {
+			return "header:" + value
+		}
+		return KeyByIP(c)
+	}
+}
+
+// KeyByUserID limits by the authenticated user, falling back to the client
+// IP for anonymous requests. It must run after the auth middleware.
+func KeyByUserID(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return KeyByIP(c)
+}
+
+// CombineKeys limits by the combination of keys, e.g. user and endpoint
+func CombineKeys(keyFuncs ...KeyFunc) KeyFunc {
+	return func(c *gin.Context) string {
+		parts := make([]string, len(keyFuncs))
+		for i, keyFunc := range keyFuncs {
+			parts[i] = keyFunc(c)
+		}
+		return strings.Join(parts, "|")
+	}
+}
+
+// rateLimiterIdleTTL is how long a key's limiter is kept after its last
+// request; by then its bucket has refilled so dropping it loses nothing
+const rateLimiterIdleTTL = 10 * time.Minute
+
+type keyedLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// Rate limiter middleware. Each key returned by keyFunc (the client IP when
+// nil) gets its own budget. The limit is read from the runtime settings on
+// every request so it can be changed without a restart. A non-positive limit
+// disables rate limiting instead of rejecting every request.
+func RateLimit(runtime *config.Runtime, log logger.Logger, keyFunc KeyFunc) gin.HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = KeyByIP
+	}
+
+	var (
+		mu        sync.Mutex
+		current   = -1
+		limiters  = make(map[string]*keyedLimiter)
+		lastSweep time.Time
+	)
+
+	limiterFor := func(key string, requestsPerMinute int, now time.Time) *rate.Limiter {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if requestsPerMinute != current {
+			current = requestsPerMinute
+			clear(limiters)
+			if requestsPerMinute <= 0 {
+				log.Warnf("Rate limiting disabled (RATE_LIMIT=%d)", requestsPerMinute)
+			}
+		}
+		if requestsPerMinute <= 0 {
+			return nil
+		}
+
+		// Drop idle keys so memory doesn't grow with the number of clients
+		if now.Sub(lastSweep) > rateLimiterIdleTTL {
+			for k, l := range limiters {
+				if now.Sub(l.lastSeen) > rateLimiterIdleTTL {
+					delete(limiters, k)
+				}
+			}
+			lastSweep = now
+		}
+
+		l, ok := limiters[key]
+		if !ok {
+			l = &keyedLimiter{limiter: rate.NewLimiter(rate.Limit(requestsPerMinute)/60, requestsPerMinute)}
+			limiters[key] = l
+		}
+		l.lastSeen = now
+		return l.limiter
+	}
 
 	return func(c *gin.Context) {
-		if !limiter.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded",
-			})
+		now := time.Now()
+		limiter := limiterFor(keyFunc(c), runtime.Load().RateLimit, now)
+		if limiter == nil {
+			c.Next()
+			return
+		}
+
+		allowed := limiter.AllowN(now, 1)
+		setRateLimitHeaders(c, limiter, now, allowed)
+
+		if !allowed {
+			response.Error(c, apperror.RateLimited("Rate limit exceeded"))
 			c.Abort()
 			return
 		}
@@ -97,14 +228,137 @@ func RateLimit(requestsPerMinute int) gin.HandlerFunc {
 	}
 }
 
-// Security headers middleware
-func Security() gin.HandlerFunc {
+// setRateLimitHeaders sets the IETF draft RateLimit-* headers describing the
+// token bucket, plus Retry-After when the request was rejected. Reset is the
+// number of seconds until the bucket is full again.
+func setRateLimitHeaders(c *gin.Context, limiter *rate.Limiter, now time.Time, allowed bool) {
+	perSecond := float64(limiter.Limit())
+	burst := limiter.Burst()
+	tokens := math.Max(limiter.TokensAt(now), 0)
+
+	c.Header("RateLimit-Limit", strconv.Itoa(burst))
+	c.Header("RateLimit-Remaining", strconv.Itoa(int(tokens)))
+	c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil((float64(burst)-tokens)/perSecond))))
+
+	if !allowed {
+		// Seconds until a single token is available
+		c.Header("Retry-After", strconv.Itoa(int(math.Max(math.Ceil((1-tokens)/perSecond), 1))))
+	}
+}
+
+// HSTSOptions configures the Strict-Transport-Security header
+type HSTSOptions struct {
+	Enabled           bool
+	MaxAge            int // seconds
+	IncludeSubDomains bool
+	Preload           bool
+
+	// TrustForwardedProto treats requests with "X-Forwarded-Proto: https" as
+	// secure. Only enable it behind a TLS-terminating proxy that sets the
+	// header itself.
+	TrustForwardedProto bool
+}
+
+func (o HSTSOptions) header() string {
+	value := "max-age=" + strconv.Itoa(o.MaxAge)
+	if o.IncludeSubDomains {
+		value += "; includeSubDomains"
+	}
+	if o.Preload {
+		value += "; preload"
+	}
+	return value
+}
+
+// SecurityOptions configures the Security middleware. Empty policies are
+// not sent.
+type SecurityOptions struct {
+	HSTS                  HSTSOptions
+	ContentSecurityPolicy string
+	PermissionsPolicy     string
+}
+
+// Security headers middleware. Strict-Transport-Security is only sent when
+// enabled and the request arrived over HTTPS, as browsers ignore it over
+// plain HTTP.
+func Security(opts SecurityOptions) gin.HandlerFunc {
+	hstsHeader := opts.HSTS.header()
+
 	return func(c *gin.Context) {
 		c.Header("X-Frame-Options", "DENY")
 		c.Header("X-Content-Type-Options", "nosniff")
 		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+		if opts.HSTS.Enabled && isSecure(c, opts.HSTS.TrustForwardedProto) {
+			c.Header("Strict-Transport-Security", hstsHeader)
+		}
 		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
+		if opts.ContentSecurityPolicy != "" {
+			c.Header("Content-Security-Policy", opts.ContentSecurityPolicy)
+		}
+		if opts.PermissionsPolicy != "" {
+			c.Header("Permissions-Policy", opts.PermissionsPolicy)
+		}
+		c.Next()
+	}
+}
+
+func isSecure(c *gin.Context, trustForwardedProto bool) bool {
+	if c.Request.TLS != nil {
+		return true
+	}
+	return trustForwardedProto && strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+// DeprecationOptions configures the Deprecation middleware
+type DeprecationOptions struct {
+	// Since is when the routes were deprecated. When zero, the day the
+	// middleware is created is used.
+	Since time.Time
+	// Sunset is when the routes will be removed
+	Sunset time.Time
+	// Link, when set, points clients at the deprecation notice or the
+	// migration guide
+	Link string
+}
+
+// Deprecation middleware marks routes as deprecated. It sets the Deprecation
+// (RFC 9745), Sunset (RFC 8594), Link and Warning headers and logs and counts
+// each call so remaining callers can be tracked down before the sunset date.
+func Deprecation(opts DeprecationOptions, log logger.Logger) gin.HandlerFunc {
+	since := opts.Since
+	if since.IsZero() {
+		since = time.Now().UTC().Truncate(24 * time.Hour)
+	}
+	deprecationHeader := "@" + strconv.FormatInt(since.Unix(), 10)
+	sunsetHeader := opts.Sunset.UTC().Format(http.TimeFormat)
+	warning := fmt.Sprintf(`299 - "Deprecated API: this endpoint will be removed after %s"`, opts.Sunset.Format(time.DateOnly))
+	var linkHeader string
+	if opts.Link != "" {
+		linkHeader = fmt.Sprintf(`<%s>; rel="deprecation"; type="text/html"`, opts.Link)
+	}
+
+	return func(c *gin.Context) {
+		c.Header("Deprecation", deprecationHeader)
+		c.Header("Sunset", sunsetHeader)
+		c.Header("Warning", warning)
+		if linkHeader != "" {
+			c.Writer.Header().Add("Link", linkHeader)
+		}
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unknown"
+		}
+		deprecatedRequestsTotal.WithLabelValues(c.Request.Method, path).Inc()
+
+		log.WithFields(map[string]interface{}{
+			"method":     c.Request.Method,
+			"path":       path,
+			"client_ip":  c.ClientIP(),
+			"user_agent": c.Request.UserAgent(),
+			"sunset":     sunsetHeader,
+		}).Warn("Deprecated API called")
+
 		c.Next()
 	}
 }
@@ -118,14 +372,125 @@ func RequestID() gin.HandlerFunc {
 		}
 		c.Header("X-Request-ID", requestID)
 		c.Set("request_id", requestID)
+		c.Request = c.Request.WithContext(reqctx.WithRequestID(c.Request.Context(), requestID))
 		c.Next()
 	}
 }
 
-// Metrics middleware
-func Metrics() gin.HandlerFunc {
+// traceHeaders are the W3C trace context headers forwarded on outbound calls
+var traceHeaders = []string{"traceparent", "tracestate", "baggage"}
+
+// TraceContext middleware stores the incoming trace context headers in the
+// request context so the httpclient can forward them downstream
+func TraceContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var headers http.Header
+		for _, name := range traceHeaders {
+			if values := c.Request.Header.Values(name); len(values) > 0 {
+				if headers == nil {
+					headers = make(http.Header, len(traceHeaders))
+				}
+				headers[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+			}
+		}
+		if headers != nil {
+			c.Request = c.Request.WithContext(reqctx.WithTraceHeaders(c.Request.Context(), headers))
+		}
+		c.Next()
+	}
+}
+
+// Tenant middleware extracts the tenant identifier from the given header
+func Tenant(header string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if tenantID := c.GetHeader(header); tenantID != "" {
+			c.Set("tenant_id", tenantID)
+			c.Request = c.Request.WithContext(reqctx.WithTenantID(c.Request.Context(), tenantID))
+		}
+		c.Next()
+	}
+}
+
+// RequestLogger middleware stores log, tagged with the request ID and
+// tenant, in the request context, so code that only receives a context (such
+// as GORM's logger) can log under the request's fields via
+// logger.FromContext. It must run after RequestID and Tenant.
+func RequestLogger(log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		fields := map[string]interface{}{logger.FieldRequestID: reqctx.RequestID(ctx)}
+		if tenantID := reqctx.TenantID(ctx); tenantID != "" {
+			fields["tenant_id"] = tenantID
+		}
+
+		c.Request = c.Request.WithContext(logger.NewContext(ctx, log.WithFields(fields)))
+		c.Next()
+	}
+}
+
+// RequestCache middleware attaches a request-scoped cache to the request
+// context so repeated lookups within a request are deduplicated
+func RequestCache() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(ctxcache.New(c.Request.Context()))
+		c.Next()
+	}
+}
+
+// StrictJSON middleware makes JSON body binding reject unknown fields for
+// the routes it is applied to, regardless of the global setting
+func StrictJSON() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("strict_json", true)
+		c.Next()
+	}
+}
+
+// isAuthenticated reports whether auth middleware identified a user, via a
+// JWT or trusted gateway headers
+func isAuthenticated(c *gin.Context) bool {
+	userID, ok := c.Get("user_id")
+	return ok && userID != nil && userID != ""
+}
+
+// tenantLabels limits tenant label values to a configured set. The tenant
+// header is set by clients, so labeling whatever tenants show up first would
+// let any client use up the labels with junk values.
+type tenantLabels map[string]struct{}
+
+func newTenantLabels(tenants []string) tenantLabels {
+	labels := make(tenantLabels, len(tenants))
+	for _, tenant := range tenants {
+		labels[tenant] = struct{}{}
+	}
+	return labels
+}
+
+func (t tenantLabels) label(tenantID string) string {
+	if len(t) == 0 {
+		return ""
+	}
+	if _, ok := t[tenantID]; ok {
+		return tenantID
+	}
+	return otherTenant
+}
+
+// Metrics middleware. When tenants are given, request counts are labeled
+// with the tenant from the Tenant middleware if it is one of them, and
+// "other" otherwise.
+// Request counts are also labeled authenticated="true" or "false" depending
+// on whether auth middleware identified a user for the request. Response
+// sizes are the body bytes actually written, measured after any response
+// compression, since compressing middleware writes through gin's writer.
+func Metrics(tenants []string) gin.HandlerFunc {
+	labels := newTenantLabels(tenants)
+
 	return func(c *gin.Context) {
 		start := time.Now()
+		// Checked rather than c.Request's context after the handlers, which
+		// other middleware may have replaced with one they cancelled
+		ctx := c.Request.Context()
 
 		c.Next()
 
@@ -135,7 +500,18 @@ func Metrics() gin.HandlerFunc {
 			path = "unknown"
 		}
 
-		requestsTotal.WithLabelValues(c.Request.Method, path, string(rune(c.Writer.Status()))).Inc()
+		tenant := labels.label(c.GetString("tenant_id"))
+		authenticated := strconv.FormatBool(isAuthenticated(c))
+
+		// Client-cancelled requests get a distinct status and are kept out
+		// of the latency histogram so they don't skew it.
+		if errors.Is(ctx.Err(), context.Canceled) {
+			requestsTotal.WithLabelValues(c.Request.Method, path, statusClientClosedRequest, tenant, authenticated).Inc()
+			return
+		}
+
+		requestsTotal.WithLabelValues(c.Request.Method, path, strconv.Itoa(c.Writer.Status()), tenant, authenticated).Inc()
 		requestDuration.WithLabelValues(c.Request.Method, path).Observe(duration)
+		responseSize.WithLabelValues(c.Request.Method, path).Observe(float64(max(c.Writer.Size(), 0)))
 	}
 }
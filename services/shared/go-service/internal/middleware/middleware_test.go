@@ -0,0 +1,517 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+)
+
+// serve sends req through router and returns the response
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMetricsClientClosedRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics(nil))
+	router.GET("/metrics-test/cancelled", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	closed := requestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/cancelled", "499", "", "false")
+	ok := requestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/cancelled", "200", "", "false")
+	closedBefore, okBefore := testutil.ToFloat64(closed), testutil.ToFloat64(ok)
+	durations := testutil.CollectAndCount(requestDuration)
+
+	serve(router, httptest.NewRequest(http.MethodGet, "/metrics-test/cancelled", nil).WithContext(ctx))
+
+	if got := testutil.ToFloat64(closed) - closedBefore; got != 1 {
+		t.Errorf("499 requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(ok) - okBefore; got != 0 {
+		t.Errorf("200 requests = %v, want the cancelled request counted as 499 only", got)
+	}
+	if got := testutil.CollectAndCount(requestDuration); got != durations {
+		t.Errorf("latency series = %d, want %d: cancelled requests must not be observed", got, durations)
+	}
+}
+
+func TestMetricsWithRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics(nil))
+	router.Use(RequestTimeout(time.Minute))
+	router.GET("/metrics-test/timeout", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	ok := requestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/timeout", "200", "", "false")
+	closed := requestsTotal.WithLabelValues(http.MethodGet, "/metrics-test/timeout", "499", "", "false")
+	okBefore, closedBefore := testutil.ToFloat64(ok), testutil.ToFloat64(closed)
+
+	w := serve(router, httptest.NewRequest(http.MethodGet, "/metrics-test/timeout", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+
+	if got := testutil.ToFloat64(ok) - okBefore; got != 1 {
+		t.Errorf("200 requests = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(closed) - closedBefore; got != 0 {
+		t.Errorf("499 requests = %v, want the cancelled budget not taken for a disconnect", got)
+	}
+}
+
+// responseSizes returns the sample count and sum of the response size
+// histogram for path
+func responseSizes(t *testing.T, path string) (count uint64, sum float64) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "http_response_size_bytes" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "path" && label.GetValue() == path {
+					return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+				}
+			}
+		}
+	}
+	return 0, 0
+}
+
+func TestMetricsResponseSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Metrics(nil))
+	router.GET("/metrics-test/size/:n", func(c *gin.Context) {
+		n, _ := strconv.Atoi(c.Param("n"))
+		if n == 0 {
+			c.Status(http.StatusNoContent)
+			return
+		}
+		c.String(http.StatusOK, strings.Repeat("x", n))
+	})
+
+	const path = "/metrics-test/size/:n"
+	countBefore, sumBefore := responseSizes(t, path)
+
+	for _, n := range []int{1500, 0, 250} {
+		serve(router, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/metrics-test/size/%d", n), nil))
+	}
+
+	count, sum := responseSizes(t, path)
+	if got := count - countBefore; got != 3 {
+		t.Errorf("observed sizes = %d, want 3", got)
+	}
+	if got := sum - sumBefore; got != 1750 {
+		t.Errorf("observed bytes = %v, want 1750, with no body counted as 0", got)
+	}
+}
+
+func TestMetricsTenantLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		tenants []string
+		tenant  string
+		want    string
+	}{
+		{"configured tenant", []string{"acme", "globex"}, "acme", "acme"},
+		{"unknown tenant", []string{"acme", "globex"}, "initech", "other"},
+		{"no tenant", []string{"acme", "globex"}, "", "other"},
+		{"tenants not configured", nil, "acme", ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/metrics-test/tenant-%d", i)
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				if tt.tenant != "" {
+					c.Set("tenant_id", tt.tenant)
+				}
+			})
+			router.Use(Metrics(tt.tenants))
+			router.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+			counter := requestsTotal.WithLabelValues(http.MethodGet, path, "200", tt.want, "false")
+			before := testutil.ToFloat64(counter)
+
+			serve(router, httptest.NewRequest(http.MethodGet, path, nil))
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests labeled tenant=%q = %v, want 1", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMetricsAuthenticatedLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	noUser := func(claims jwt.MapClaims) { delete(claims, "user_id") }
+
+	tests := []struct {
+		name       string
+		token      func(t *testing.T) string
+		gatewayID  string
+		wantStatus string
+		want       string
+	}{
+		{"valid JWT", func(t *testing.T) string { return signToken(t, testJWTSecret, nil) }, "", "200", "true"},
+		{"gateway headers", nil, "user-1", "200", "true"},
+		{"JWT without a user", func(t *testing.T) string { return signToken(t, testJWTSecret, noUser) }, "", "200", "false"},
+		{"invalid JWT", func(t *testing.T) string { return signToken(t, "wrong-secret-at-least-32-bytes-long", nil) }, "", "401", "false"},
+		{"anonymous", nil, "", "401", "false"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf("/metrics-test/authenticated-%d", i)
+			router := gin.New()
+			router.Use(Metrics(nil))
+			router.GET(path, AuthMiddleware(AuthOptions{
+				Secret: testJWTSecret,
+				Gateway: GatewayOptions{
+					TrustHeaders:   true,
+					TrustedProxies: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")},
+					UserIDHeader:   "X-User-ID",
+				},
+			}), func(c *gin.Context) { c.Status(http.StatusOK) })
+			counter := requestsTotal.WithLabelValues(http.MethodGet, path, tt.wantStatus, "", tt.want)
+			before := testutil.ToFloat64(counter)
+
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if tt.token != nil {
+				req.Header.Set("Authorization", "Bearer "+tt.token(t))
+			}
+			if tt.gatewayID != "" {
+				req.Header.Set("X-User-ID", tt.gatewayID)
+			}
+			serve(router, req)
+
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("requests labeled status=%s authenticated=%s = %v, want 1", tt.wantStatus, tt.want, got)
+			}
+		})
+	}
+}
+
+// newRateLimitRouter returns a router limited to limit requests a minute
+// per key, serving GET /
+func newRateLimitRouter(limit int, keyFunc KeyFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	router := gin.New()
+	router.Use(RateLimit(config.NewRuntime(&config.Config{RateLimit: limit}), log, keyFunc))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	router := newRateLimitRouter(0, nil)
+
+	for i := 0; i < 50; i++ {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want every request allowed with RATE_LIMIT=0", i, w.Code)
+		}
+		if w.Header().Get("RateLimit-Limit") != "" {
+			t.Fatal("RateLimit-Limit sent with rate limiting disabled")
+		}
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	// 3 requests a minute: one token every 20 seconds
+	router := newRateLimitRouter(3, nil)
+
+	tests := []struct {
+		wantCode       int
+		wantRemaining  string
+		wantReset      string
+		wantRetryAfter string
+	}{
+		{http.StatusOK, "2", "20", ""},
+		{http.StatusOK, "1", "40", ""},
+		{http.StatusOK, "0", "60", ""},
+		{http.StatusTooManyRequests, "0", "60", "20"},
+		{http.StatusTooManyRequests, "0", "60", "20"},
+	}
+	for i, tt := range tests {
+		w := serve(router, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != tt.wantCode {
+			t.Fatalf("request %d = %d, want %d", i+1, w.Code, tt.wantCode)
+		}
+		got := []string{
+			w.Header().Get("RateLimit-Limit"),
+			w.Header().Get("RateLimit-Remaining"),
+			w.Header().Get("RateLimit-Reset"),
+			w.Header().Get("Retry-After"),
+		}
+		want := []string{"3", tt.wantRemaining, tt.wantReset, tt.wantRetryAfter}
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("request %d: Limit, Remaining, Reset, Retry-After = %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	// Each request is identified by its API key, user and client IP; a
+	// budget of one request a minute per key makes a key's second request 429
+	type request struct {
+		apiKey, user, ip string
+		want             int
+	}
+	tests := []struct {
+		name     string
+		keyFunc  KeyFunc
+		requests []request
+	}{
+		{
+			name:    "by header",
+			keyFunc: KeyByHeader("X-API-Key"),
+			requests: []request{
+				{"key-a", "", "192.0.2.1", http.StatusOK},
+				{"key-a", "", "192.0.2.2", http.StatusTooManyRequests},
+				{"key-b", "", "192.0.2.1", http.StatusOK},
+				{"", "", "192.0.2.1", http.StatusOK},
+				{"", "", "192.0.2.1", http.StatusTooManyRequests},
+				{"", "", "192.0.2.2", http.StatusOK},
+			},
+		},
+		{
+			name:    "by user",
+			keyFunc: KeyByUserID,
+			requests: []request{
+				{"", "alice", "192.0.2.1", http.StatusOK},
+				{"", "alice", "192.0.2.2", http.StatusTooManyRequests},
+				{"", "bob", "192.0.2.1", http.StatusOK},
+				{"", "", "192.0.2.1", http.StatusOK},
+				{"", "", "192.0.2.1", http.StatusTooManyRequests},
+				{"", "", "192.0.2.2", http.StatusOK},
+			},
+		},
+		{
+			name:    "by user and header combined",
+			keyFunc: CombineKeys(KeyByUserID, KeyByHeader("X-API-Key")),
+			requests: []request{
+				{"key-a", "alice", "192.0.2.1", http.StatusOK},
+				{"key-b", "alice", "192.0.2.1", http.StatusOK},
+				{"key-a", "bob", "192.0.2.1", http.StatusOK},
+				{"key-a", "alice", "192.0.2.2", http.StatusTooManyRequests},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			// Stands in for the auth middleware, which KeyByUserID runs after
+			router.Use(func(c *gin.Context) {
+				if user := c.GetHeader("X-Test-User"); user != "" {
+					c.Set("user_id", user)
+				}
+			})
+			router.Use(RateLimit(config.NewRuntime(&config.Config{RateLimit: 1}), log, tt.keyFunc))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.RemoteAddr = r.ip + ":40000"
+				if r.apiKey != "" {
+					req.Header.Set("X-API-Key", r.apiKey)
+				}
+				if r.user != "" {
+					req.Header.Set("X-Test-User", r.user)
+				}
+				if w := serve(router, req); w.Code != r.want {
+					t.Errorf("request %d (%+v) = %d, want %d", i+1, r, w.Code, r.want)
+				}
+			}
+		})
+	}
+}
+
+func TestDeprecation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+
+	since := time.Date(2026, time.January, 15, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		opts            DeprecationOptions
+		path            string
+		wantDeprecation string
+		wantLink        string
+	}{
+		{
+			name:            "deprecation date and link",
+			opts:            DeprecationOptions{Since: since, Sunset: sunset, Link: "https://example.com/docs/v1-migration"},
+			path:            "/deprecation-test/link",
+			wantDeprecation: "@1768435200",
+			wantLink:        `<https://example.com/docs/v1-migration>; rel="deprecation"; type="text/html"`,
+		},
+		{
+			name:            "defaults to today without a link",
+			opts:            DeprecationOptions{Sunset: sunset},
+			path:            "/deprecation-test/default",
+			wantDeprecation: "@" + strconv.FormatInt(time.Now().UTC().Truncate(24*time.Hour).Unix(), 10),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(Deprecation(tt.opts, log))
+			router.GET(tt.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+			counter := deprecatedRequestsTotal.WithLabelValues(http.MethodGet, tt.path)
+			before := testutil.ToFloat64(counter)
+
+			w := serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := w.Header().Get("Deprecation"); got != tt.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tt.wantDeprecation)
+			}
+			if got := w.Header().Get("Sunset"); got != "Wed, 01 Jul 2026 00:00:00 GMT" {
+				t.Errorf("Sunset = %q, want Wed, 01 Jul 2026 00:00:00 GMT", got)
+			}
+			if got := w.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+			if got := w.Header().Get("Warning"); !strings.Contains(got, "2026-07-01") {
+				t.Errorf("Warning = %q, want it to name the sunset date", got)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("http_deprecated_requests_total = %v, want 1", got)
+			}
+		})
+	}
+}
+
+// newSecurityRouter returns a router serving GET / behind Security
+func newSecurityRouter(opts SecurityOptions) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Security(opts))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestSecurityHSTS(t *testing.T) {
+	hsts := HSTSOptions{Enabled: true, MaxAge: 31536000, IncludeSubDomains: true, Preload: true}
+	const want = "max-age=31536000; includeSubDomains; preload"
+
+	tests := []struct {
+		name    string
+		hsts    HSTSOptions
+		tls     bool
+		proto   string
+		trusted bool
+		want    string
+	}{
+		{"https", hsts, true, "", false, want},
+		{"plain http", hsts, false, "", false, ""},
+		{"disabled", HSTSOptions{MaxAge: 31536000}, true, "", false, ""},
+		{"forwarded https, trusted", hsts, false, "https", true, want},
+		{"forwarded https, untrusted", hsts, false, "https", false, ""},
+		{"forwarded http, trusted", hsts, false, "http", true, ""},
+		{"max-age only", HSTSOptions{Enabled: true, MaxAge: 300}, true, "", false, "max-age=300"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.hsts
+			opts.TrustForwardedProto = tt.trusted
+			router := newSecurityRouter(SecurityOptions{HSTS: opts})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req = httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+			}
+			if tt.proto != "" {
+				req.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			w := serve(router, req)
+			if got := w.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityPolicies(t *testing.T) {
+	const (
+		csp         = "default-src 'self'; frame-ancestors 'none'"
+		permissions = "camera=(), geolocation=(), microphone=()"
+	)
+
+	tests := []struct {
+		name            string
+		opts            SecurityOptions
+		wantCSP         string
+		wantPermissions string
+	}{
+		{"both set", SecurityOptions{ContentSecurityPolicy: csp, PermissionsPolicy: permissions}, csp, permissions},
+		{"CSP only", SecurityOptions{ContentSecurityPolicy: csp}, csp, ""},
+		{"empty policies not sent", SecurityOptions{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(newSecurityRouter(tt.opts), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := w.Header().Get("Content-Security-Policy"); got != tt.wantCSP {
+				t.Errorf("Content-Security-Policy = %q, want %q", got, tt.wantCSP)
+			}
+			if got := w.Header().Get("Permissions-Policy"); got != tt.wantPermissions {
+				t.Errorf("Permissions-Policy = %q, want %q", got, tt.wantPermissions)
+			}
+			if _, sent := w.Header()["Content-Security-Policy"]; sent != (tt.wantCSP != "") {
+				t.Errorf("Content-Security-Policy sent = %v, want %v", sent, tt.wantCSP != "")
+			}
+			for header, want := range map[string]string{
+				"X-Frame-Options":        "DENY",
+				"X-Content-Type-Options": "nosniff",
+				"Referrer-Policy":        "strict-origin-when-cross-origin",
+			} {
+				if got := w.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+		})
+	}
+}
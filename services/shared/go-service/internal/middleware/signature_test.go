@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryReplayGuard is an in-memory ReplayGuard
+type memoryReplayGuard struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func (g *memoryReplayGuard) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.seen[nonce] {
+		return false, nil
+	}
+	g.seen[nonce] = true
+	return true, nil
+}
+
+func sign(secret, method, uri, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + uri + "\n" + timestamp + "\n" + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/partner/orders", VerifySignature(
+		StaticKeys(map[string]string{"acme": "s3cret"}),
+		SignatureOptions{MaxSkew: time.Minute, Replay: &memoryReplayGuard{seen: map[string]bool{}}},
+	), func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString("partner_id"))
+	})
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-2*time.Minute).Unix(), 10)
+	body := `{"sku":"A-1","qty":1}`
+	valid := sign("s3cret", http.MethodPost, "/partner/orders", now, body)
+
+	tests := []struct {
+		name      string
+		keyID     string
+		timestamp string
+		signature string
+		body      string
+		want      int
+	}{
+		{"valid", "acme", now, valid, body, http.StatusOK},
+		{"replayed", "acme", now, valid, body, http.StatusUnauthorized},
+		{"tampered body", "acme", now, valid, `{"sku":"A-1","qty":100}`, http.StatusUnauthorized},
+		{"stale timestamp", "acme", stale, sign("s3cret", http.MethodPost, "/partner/orders", stale, body), body, http.StatusUnauthorized},
+		{"unknown key", "other", now, sign("s3cret", http.MethodPost, "/partner/orders", now, "{}"), "{}", http.StatusUnauthorized},
+		{"missing signature", "acme", now, "", body, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/partner/orders", strings.NewReader(tt.body))
+			req.Header.Set(HeaderKeyID, tt.keyID)
+			req.Header.Set(HeaderTimestamp, tt.timestamp)
+			req.Header.Set(HeaderSignature, tt.signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want == http.StatusOK && w.Body.String() != "acme" {
+				t.Errorf("partner_id = %q, want acme", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/logger"
+)
+
+// slowThresholdKey holds a per-route override set by SlowRequestThreshold
+const slowThresholdKey = "slow_request_threshold"
+
+// SlowRequests middleware logs a warning for every request that takes longer
+// than threshold, regardless of whether request logging is enabled. Routes
+// can raise or lower the threshold with SlowRequestThreshold. A non-positive
+// threshold disables the warning unless a route sets its own.
+func SlowRequests(threshold time.Duration, log logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		limit := threshold
+		if override, ok := c.Get(slowThresholdKey); ok {
+			limit = override.(time.Duration)
+		}
+		if limit <= 0 {
+			return
+		}
+
+		duration := time.Since(start)
+		if duration <= limit {
+			return
+		}
+
+		path := c.FullPath()
+		if path == "" {
+			path = "unknown"
+		}
+		log.WithFields(map[string]interface{}{
+			"method":       c.Request.Method,
+			"path":         path,
+			"status":       c.Writer.Status(),
+			"duration_ms":  duration.Milliseconds(),
+			"threshold_ms": limit.Milliseconds(),
+			"request_id":   c.GetString("request_id"),
+		}).Warn("Slow request")
+	}
+}
+
+// SlowRequestThreshold overrides the SlowRequests threshold for the routes
+// it is applied to, e.g. a higher one for report exports. Zero disables the
+// warning for those routes.
+func SlowRequestThreshold(threshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(slowThresholdKey, threshold)
+		c.Next()
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/logger"
+)
+
+func TestSlowRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	const delay = 50 * time.Millisecond
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		override  gin.HandlerFunc
+		wantWarn  bool
+	}{
+		{"slow request", 10 * time.Millisecond, nil, true},
+		{"fast request", time.Second, nil, false},
+		{"disabled", 0, nil, false},
+		{"route raises threshold", 10 * time.Millisecond, SlowRequestThreshold(time.Second), false},
+		{"route lowers threshold", time.Second, SlowRequestThreshold(10 * time.Millisecond), true},
+		{"route enables warning", 0, SlowRequestThreshold(10 * time.Millisecond), true},
+		{"route disables warning", 10 * time.Millisecond, SlowRequestThreshold(0), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&buf) })
+
+			router := gin.New()
+			router.Use(SlowRequests(tt.threshold, log))
+			handlers := []gin.HandlerFunc{func(c *gin.Context) {
+				time.Sleep(delay)
+				c.Status(http.StatusAccepted)
+			}}
+			if tt.override != nil {
+				handlers = append([]gin.HandlerFunc{tt.override}, handlers...)
+			}
+			router.GET("/slow/:id", handlers...)
+
+			serve(router, httptest.NewRequest(http.MethodGet, "/slow/42", nil))
+
+			if !tt.wantWarn {
+				if buf.Len() != 0 {
+					t.Errorf("logged %s, want no warning", buf.String())
+				}
+				return
+			}
+			var entry map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+				t.Fatalf("want one warning, got %q: %v", buf.String(), err)
+			}
+			if entry["level"] != "warning" || entry["msg"] != "Slow request" {
+				t.Errorf("entry = %v, want a Slow request warning", entry)
+			}
+			if entry["path"] != "/slow/:id" {
+				t.Errorf("path = %v, want the route template", entry["path"])
+			}
+			if entry["status"] != float64(http.StatusAccepted) {
+				t.Errorf("status = %v, want %d", entry["status"], http.StatusAccepted)
+			}
+			if ms, _ := entry["duration_ms"].(float64); ms < float64(delay.Milliseconds()) {
+				t.Errorf("duration_ms = %v, want at least %d", entry["duration_ms"], delay.Milliseconds())
+			}
+		})
+	}
+}
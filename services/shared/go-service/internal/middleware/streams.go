@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/response"
+)
+
+// newOpenStreamsGauge registers the open stream gauge. It is registered by
+// StreamLimit rather than at init, so it's only exported by services that
+// serve streams.
+func newOpenStreamsGauge() prometheus.Gauge {
+	return metrics.Register(nil, prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_open_streams",
+			Help: "The number of open streaming connections (SSE, WebSocket)",
+		},
+	))
+}
+
+// StreamLimit middleware caps the number of concurrent streaming connections
+// (SSE, WebSocket) so long-lived clients can't exhaust file descriptors.
+// Create it once and apply the same handler to every streaming route so
+// they share the cap:
+//
+//	streams := middleware.StreamLimit(1000)
+//	api.GET("/events", streams, handlers.Events())
+//
+// Connections beyond max are rejected immediately with 503; a max of 0
+// disables the limit. The open stream gauge is maintained either way.
+func StreamLimit(max int) gin.HandlerFunc {
+	openStreams := newOpenStreamsGauge()
+	if max <= 0 {
+		return func(c *gin.Context) {
+			openStreams.Inc()
+			defer openStreams.Dec()
+			c.Next()
+		}
+	}
+
+	slots := make(chan struct{}, max)
+
+	return func(c *gin.Context) {
+		select {
+		case slots <- struct{}{}:
+		default:
+			c.Header("Retry-After", "5")
+			response.Error(c, apperror.Unavailable("Too many open streams"))
+			c.Abort()
+			return
+		}
+
+		openStreams.Inc()
+		defer func() {
+			openStreams.Dec()
+			<-slots
+		}()
+
+		c.Next()
+	}
+}
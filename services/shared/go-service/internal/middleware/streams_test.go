@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	release := make(chan struct{})
+	opened := make(chan struct{})
+	router := gin.New()
+	router.GET("/events", StreamLimit(1), func(c *gin.Context) {
+		opened <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+		return w.Code
+	}
+
+	first := make(chan int, 1)
+	go func() { first <- serve() }()
+	<-opened
+
+	if code := serve(); code != http.StatusServiceUnavailable {
+		t.Errorf("second stream got %d, want 503 while the first is open", code)
+	}
+
+	release <- struct{}{}
+	if code := <-first; code != http.StatusOK {
+		t.Fatalf("first stream got %d, want 200", code)
+	}
+
+	// The closed stream's slot is free again
+	go func() {
+		<-opened
+		release <- struct{}{}
+	}()
+	if code := serve(); code != http.StatusOK {
+		t.Errorf("stream after the first closed got %d, want 200", code)
+	}
+}
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// RequestTimeout middleware gives each request an overall time budget by
+// setting a deadline on its context. Handlers aren't interrupted; the
+// database, Redis and HTTP client wrappers stop their operations at the
+// deadline, and each operation's own budget is clamped to the time the
+// request has left. A timeout of 0 disables the deadline.
+//
+// The original request is restored once the handlers return, before the
+// budget is cancelled, so middleware running after it, such as Metrics,
+// doesn't mistake the cancelled budget for a client disconnect.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := reqctx.WithBudget(c.Request.Context(), timeout)
+		defer cancel()
+
+		req := c.Request
+		c.Request = req.WithContext(ctx)
+		c.Next()
+		c.Request = req
+	}
+}
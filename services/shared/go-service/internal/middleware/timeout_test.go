@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name         string
+		timeout      time.Duration
+		wantDeadline bool
+	}{
+		{"budget set", 100 * time.Millisecond, true},
+		{"disabled", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var original, after *http.Request
+			var handlerCtx context.Context
+
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				original = c.Request
+				c.Next()
+				after = c.Request
+			})
+			router.Use(RequestTimeout(tt.timeout))
+			router.GET("/timeout", func(c *gin.Context) {
+				handlerCtx = c.Request.Context()
+				c.Status(http.StatusOK)
+			})
+
+			start := time.Now()
+			serve(router, httptest.NewRequest(http.MethodGet, "/timeout", nil))
+			end := time.Now()
+
+			deadline, ok := handlerCtx.Deadline()
+			if ok != tt.wantDeadline {
+				t.Fatalf("handler context has deadline = %v, want %v", ok, tt.wantDeadline)
+			}
+			if ok && (deadline.Before(start.Add(tt.timeout)) || deadline.After(end.Add(tt.timeout))) {
+				t.Errorf("deadline %v after the request started, want the %v budget", deadline.Sub(start), tt.timeout)
+			}
+
+			// Middleware running after the budget sees the original request,
+			// whose context isn't cancelled with the budget
+			if after != original {
+				t.Error("request not restored after the handlers returned")
+			}
+			if err := after.Context().Err(); err != nil {
+				t.Errorf("restored request context err = %v, want nil", err)
+			}
+			if tt.wantDeadline && handlerCtx.Err() == nil {
+				t.Error("budget context not cancelled once the request finished")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"{{ module_name }}/internal/metrics"
+)
+
+// Backlog is a snapshot of the events not yet published
+type Backlog struct {
+	Pending int
+	// Oldest is when the oldest pending event was written; zero when there
+	// are none
+	Oldest time.Time
+}
+
+// BacklogFunc reports the current backlog, typically by counting the
+// unpublished rows of the outbox table. It is called on every scrape.
+type BacklogFunc func(ctx context.Context) (Backlog, error)
+
+// Metrics records transactional outbox relay activity. The template ships no relay of its own; services that add one
+// report through Metrics so every service exposes the same series:
+//
+//   - outbox_pending_events: events waiting to be published
+//   - outbox_lag_seconds: age of the oldest unpublished event
+//   - outbox_events_published_total, outbox_events_failed_total
+//   - outbox_backlog_errors_total: scrapes where the backlog query failed
+//
+// The gauges are computed at scrape time, so the lag keeps growing while
+// the relay is stuck rather than freezing at its last reported value.
+type Metrics struct {
+	published prometheus.Counter
+	failed    prometheus.Counter
+}
+
+// NewMetrics registers the outbox metrics with reg (the default registry
+// when nil), querying backlog on each scrape bounded by timeout. Create it
+// once per registry.
+func NewMetrics(reg prometheus.Registerer, backlog BacklogFunc, timeout time.Duration) *Metrics {
+	metrics.Register(reg, &backlogCollector{
+		backlog: backlog,
+		timeout: timeout,
+		pending: prometheus.NewDesc(
+			"outbox_pending_events",
+			"The number of outbox events waiting to be published",
+			nil, nil,
+		),
+		lag: prometheus.NewDesc(
+			"outbox_lag_seconds",
+			"The age of the oldest unpublished outbox event in seconds",
+			nil, nil,
+		),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_backlog_errors_total",
+			Help: "The total number of failed outbox backlog queries",
+		}),
+	})
+
+	return &Metrics{
+		published: metrics.Register(reg, prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "The total number of outbox events published",
+		})),
+		failed: metrics.Register(reg, prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_events_failed_total",
+			Help: "The total number of failed outbox publish attempts",
+		})),
+	}
+}
+
+// Published records n events published by the relay
+func (m *Metrics) Published(n int) {
+	m.published.Add(float64(n))
+}
+
+// Failed records n failed publish attempts
+func (m *Metrics) Failed(n int) {
+	m.failed.Add(float64(n))
+}
+
+// backlogCollector turns BacklogFunc snapshots into the backlog gauges
+type backlogCollector struct {
+	backlog BacklogFunc
+	timeout time.Duration
+
+	pending *prometheus.Desc
+	lag     *prometheus.Desc
+	errors  prometheus.Counter
+}
+
+func (c *backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.pending
+	ch <- c.lag
+	c.errors.Describe(ch)
+}
+
+func (c *backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	// On failure the gauges are left out of the scrape rather than
+	// reported as an empty backlog
+	if backlog, err := c.backlog(ctx); err != nil {
+		c.errors.Inc()
+	} else {
+		lag := 0.0
+		if backlog.Pending > 0 && !backlog.Oldest.IsZero() {
+			lag = time.Since(backlog.Oldest).Seconds()
+		}
+		ch <- prometheus.MustNewConstMetric(c.pending, prometheus.GaugeValue, float64(backlog.Pending))
+		ch <- prometheus.MustNewConstMetric(c.lag, prometheus.GaugeValue, lag)
+	}
+
+	c.errors.Collect(ch)
+}
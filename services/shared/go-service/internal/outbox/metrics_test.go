@@ -0,0 +1,113 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsCounters(t *testing.T) {
+	m := NewMetrics(prometheus.NewRegistry(), func(context.Context) (Backlog, error) { return Backlog{}, nil }, time.Second)
+
+	m.Published(3)
+	m.Published(2)
+	m.Failed(1)
+
+	if got := testutil.ToFloat64(m.published); got != 5 {
+		t.Errorf("outbox_events_published_total = %v, want 5", got)
+	}
+	if got := testutil.ToFloat64(m.failed); got != 1 {
+		t.Errorf("outbox_events_failed_total = %v, want 1", got)
+	}
+}
+
+// newTestCollector registers a backlog collector reporting backlog on a
+// registry of its own
+func newTestCollector(t *testing.T, backlog BacklogFunc) *prometheus.Registry {
+	t.Helper()
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(&backlogCollector{
+		backlog: backlog,
+		timeout: time.Second,
+		pending: prometheus.NewDesc("outbox_pending_events", "pending", nil, nil),
+		lag:     prometheus.NewDesc("outbox_lag_seconds", "lag", nil, nil),
+		errors:  prometheus.NewCounter(prometheus.CounterOpts{Name: "outbox_backlog_errors_total", Help: "errors"}),
+	})
+	return reg
+}
+
+// gathered returns the value of each single-series metric in reg
+func gathered(t *testing.T, reg *prometheus.Registry) map[string]float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		metric := family.GetMetric()[0]
+		switch {
+		case metric.GetGauge() != nil:
+			values[family.GetName()] = metric.GetGauge().GetValue()
+		case metric.GetCounter() != nil:
+			values[family.GetName()] = metric.GetCounter().GetValue()
+		}
+	}
+	return values
+}
+
+func TestBacklogCollector(t *testing.T) {
+	t.Run("lag of the oldest pending event", func(t *testing.T) {
+		oldest := time.Now().Add(-90 * time.Second)
+		reg := newTestCollector(t, func(ctx context.Context) (Backlog, error) {
+			if _, ok := ctx.Deadline(); !ok {
+				t.Error("backlog query isn't bounded by the timeout")
+			}
+			return Backlog{Pending: 3, Oldest: oldest}, nil
+		})
+
+		values := gathered(t, reg)
+		if values["outbox_pending_events"] != 3 {
+			t.Errorf("outbox_pending_events = %v, want 3", values["outbox_pending_events"])
+		}
+		if lag := values["outbox_lag_seconds"]; lag < 90 || lag > 95 {
+			t.Errorf("outbox_lag_seconds = %v, want about 90", lag)
+		}
+
+		// The lag is computed at scrape time, so it grows while stuck
+		oldest = oldest.Add(-time.Minute)
+		if lag := gathered(t, reg)["outbox_lag_seconds"]; lag < 150 {
+			t.Errorf("outbox_lag_seconds = %v after a minute more, want about 150", lag)
+		}
+	})
+
+	t.Run("empty backlog", func(t *testing.T) {
+		reg := newTestCollector(t, func(context.Context) (Backlog, error) {
+			return Backlog{}, nil
+		})
+
+		values := gathered(t, reg)
+		if values["outbox_pending_events"] != 0 || values["outbox_lag_seconds"] != 0 {
+			t.Errorf("gauges = %v, want zero pending and zero lag", values)
+		}
+	})
+
+	t.Run("failed backlog query", func(t *testing.T) {
+		reg := newTestCollector(t, func(context.Context) (Backlog, error) {
+			return Backlog{}, errors.New("connection refused")
+		})
+
+		values := gathered(t, reg)
+		if _, ok := values["outbox_pending_events"]; ok {
+			t.Errorf("gauges reported despite the failed query: %v", values)
+		}
+		if values["outbox_backlog_errors_total"] != 1 {
+			t.Errorf("outbox_backlog_errors_total = %v, want 1", values["outbox_backlog_errors_total"])
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package password
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HIBP checks passwords against the Have I Been Pwned Pwned Passwords API
+// using k-anonymity: only the first 5 hex characters of the password's
+// SHA-1 hash are sent, and the match is made locally against the returned
+// suffixes, so neither the password nor its full hash leave the service.
+type HIBP struct {
+	Client *http.Client
+	// URL is the range endpoint the hash prefix is appended to
+	URL string
+	// Timeout bounds each check; zero leaves it to ctx
+	Timeout time.Duration
+}
+
+// Breached reports whether password appears in the breach corpus
+func (h *HIBP) Breached(ctx context.Context, password string) (bool, error) {
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.URL+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create breach check request: %w", err)
+	}
+	// Padding hides the real number of matches from network observers
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to query breach API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach API returned status %d", resp.StatusCode)
+	}
+
+	// Each line is SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && candidate == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breach API response: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,17 @@
+package password
+
+// CommonPasswords are among the most frequently used passwords, rejected by
+// default. Services wanting a larger list can load one into Policy.Banned.
+var CommonPasswords = []string{
+	"123456", "123456789", "12345678", "1234567890", "12345", "1234567",
+	"123123", "111111", "000000", "654321", "666666", "121212", "112233",
+	"123321", "987654321", "1q2w3e4r", "1q2w3e4r5t", "1qaz2wsx", "qwerty",
+	"qwerty123", "qwertyuiop", "asdfghjkl", "zxcvbnm", "password",
+	"password1", "password123", "passw0rd", "p@ssw0rd", "abc123", "abcd1234",
+	"iloveyou", "admin", "admin123", "administrator", "welcome", "welcome1",
+	"welcome123", "letmein", "monkey", "dragon", "football", "baseball",
+	"sunshine", "princess", "master", "shadow", "superman", "michael",
+	"trustno1", "starwars", "whatever", "changeme", "secret", "login",
+	"qazwsx", "zaq12wsx", "access", "hello123", "freedom", "computer",
+	"internet", "default", "guest", "test", "test123", "root", "toor",
+}
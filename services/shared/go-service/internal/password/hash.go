@@ -0,0 +1,32 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxBytes is the longest password bcrypt hashes in full
+const MaxBytes = 72
+
+// ErrTooLong is returned by Hash for passwords over MaxBytes, which bcrypt
+// would otherwise silently truncate or reject depending on its version
+var ErrTooLong = errors.New("password too long")
+
+// Hash returns the bcrypt hash of password for storage
+func Hash(password string) (string, error) {
+	if len(password) > MaxBytes {
+		return "", fmt.Errorf("%w: over %d bytes", ErrTooLong, MaxBytes)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Verify reports whether password matches hash, as returned by Hash
+func Verify(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,111 @@
+package password
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Violation is a rule a password fails
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+// BreachChecker reports whether a password appears in known data breaches
+type BreachChecker interface {
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// Policy is the password strength policy applied when users choose a
+// password. The template's Register handler applies it; handlers that let
+// users change their password must apply it too.
+type Policy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// Required character classes
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// Banned are rejected regardless of the other rules, compared case
+	// insensitively; defaults to CommonPasswords
+	Banned []string
+	// Breaches, when set, rejects passwords found in data breaches. The
+	// check fails open: if it errors the password is accepted.
+	Breaches BreachChecker
+	// OnBreachCheckError is called when the breach check fails, for logging
+	OnBreachCheckError func(err error)
+
+	banned map[string]struct{}
+}
+
+// NewPolicy prepares p for use
+func NewPolicy(p Policy) *Policy {
+	if p.Banned == nil {
+		p.Banned = CommonPasswords
+	}
+	p.banned = make(map[string]struct{}, len(p.Banned))
+	for _, banned := range p.Banned {
+		p.banned[strings.ToLower(banned)] = struct{}{}
+	}
+	return &p
+}
+
+// Validate returns every rule password violates, or none if it is
+// acceptable. The breach check only runs once the local rules pass.
+func (p *Policy) Validate(ctx context.Context, password string) []Violation {
+	var violations []Violation
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, Violation{"min_length", fmt.Sprintf("must be at least %d characters long", p.MinLength)})
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		violations = append(violations, Violation{"uppercase", "must contain an uppercase letter"})
+	}
+	if p.RequireLower && !lower {
+		violations = append(violations, Violation{"lowercase", "must contain a lowercase letter"})
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, Violation{"digit", "must contain a digit"})
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, Violation{"symbol", "must contain a symbol"})
+	}
+
+	if _, ok := p.banned[strings.ToLower(password)]; ok {
+		violations = append(violations, Violation{"common", "is too common"})
+	}
+
+	if len(violations) > 0 || p.Breaches == nil {
+		return violations
+	}
+
+	breached, err := p.Breaches.Breached(ctx, password)
+	if err != nil {
+		if p.OnBreachCheckError != nil {
+			p.OnBreachCheckError(err)
+		}
+		return nil
+	}
+	if breached {
+		violations = append(violations, Violation{"breached", "has appeared in a data breach; choose a different password"})
+	}
+	return violations
+}
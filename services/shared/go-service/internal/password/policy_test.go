@@ -0,0 +1,86 @@
+package password
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeBreaches reports the passwords in breached, or fails with err
+type fakeBreaches struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (f *fakeBreaches) Breached(ctx context.Context, password string) (bool, error) {
+	f.calls++
+	return f.breached[password], f.err
+}
+
+func rules(violations []Violation) []string {
+	names := make([]string, 0, len(violations))
+	for _, v := range violations {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestPolicyValidate(t *testing.T) {
+	breaches := &fakeBreaches{breached: map[string]bool{"Tr0ub4dor&3": true}}
+	policy := NewPolicy(Policy{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		Breaches:      breaches,
+	})
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"acceptable", "correct-Horse-7-battery", []string{}},
+		{"every rule", "abc", []string{"min_length", "uppercase", "digit", "symbol"}},
+		{"multibyte characters count once", "Ünïcödé-1é", []string{}},
+		{"common, case insensitively", "PASSWORD123", []string{"lowercase", "symbol", "common"}},
+		{"breached", "Tr0ub4dor&3", []string{"breached"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rules(policy.Validate(context.Background(), tt.password)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidateBreachCheck(t *testing.T) {
+	breaches := &fakeBreaches{err: errors.New("breach API unavailable")}
+	var reported error
+	policy := NewPolicy(Policy{
+		MinLength:          8,
+		Breaches:           breaches,
+		OnBreachCheckError: func(err error) { reported = err },
+	})
+
+	// Passwords failing local rules aren't sent to the breach check
+	if violations := policy.Validate(context.Background(), "short"); len(violations) == 0 {
+		t.Fatal("short password accepted")
+	}
+	if breaches.calls != 0 {
+		t.Errorf("breach check ran %d times for a password failing local rules", breaches.calls)
+	}
+
+	// The check fails open
+	if violations := policy.Validate(context.Background(), "long enough passphrase"); len(violations) != 0 {
+		t.Errorf("Validate = %v when the breach check failed, want the password accepted", violations)
+	}
+	if reported == nil {
+		t.Error("breach check failure wasn't reported")
+	}
+}
@@ -0,0 +1,126 @@
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Optional is a PATCH request field that tells apart a field the client left
+// out, one it explicitly set to null, and one it set to a value. Decoding
+// JSON only touches fields present in the body, so a zero Optional means
+// absent.
+type Optional[T any] struct {
+	present bool
+	null    bool
+	value   T
+}
+
+// Some returns an Optional set to value
+func Some[T any](value T) Optional[T] {
+	return Optional[T]{present: true, value: value}
+}
+
+// Null returns an Optional explicitly set to null
+func Null[T any]() Optional[T] {
+	return Optional[T]{present: true, null: true}
+}
+
+// Present reports whether the field was in the request, null or not
+func (o Optional[T]) Present() bool {
+	return o.present
+}
+
+// IsNull reports whether the field was explicitly set to null
+func (o Optional[T]) IsNull() bool {
+	return o.present && o.null
+}
+
+// Value returns the field's value and whether it was set to one, i.e.
+// present and not null
+func (o Optional[T]) Value() (T, bool) {
+	return o.value, o.present && !o.null
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.present = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.null = true
+		var zero T
+		o.value = zero
+		return nil
+	}
+	o.null = false
+	return json.Unmarshal(data, &o.value)
+}
+
+// MarshalJSON implements json.Marshaler. Absent and null both encode as null.
+func (o Optional[T]) MarshalJSON() ([]byte, error) {
+	if !o.present || o.null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(o.value)
+}
+
+// field lets Updates inspect an Optional without knowing T
+type field interface {
+	Present() bool
+	IsNull() bool
+	update() interface{}
+}
+
+func (o Optional[T]) update() interface{} {
+	if o.null {
+		return nil
+	}
+	return o.value
+}
+
+// Updates converts a request struct's Optional fields into a map for GORM's
+// Updates: absent fields are left out, null fields set the column to NULL
+// and other fields set it to their value. Keys are the gorm column tag if
+// set, otherwise the json name; non-Optional fields are ignored.
+//
+//	db.Model(&user).Updates(patch.Updates(req))
+func Updates(req interface{}) map[string]interface{} {
+	v := reflect.Indirect(reflect.ValueOf(req))
+	t := v.Type()
+
+	updates := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		f, ok := v.Field(i).Interface().(field)
+		if !ok || !f.Present() {
+			continue
+		}
+		updates[columnName(sf)] = f.update()
+	}
+	return updates
+}
+
+// ValidationValue is a validator CustomTypeFunc that validates Optional
+// fields by their value. Absent and null fields validate as nil. It must be
+// registered for each Optional[T] instantiation to validate.
+func ValidationValue(v reflect.Value) interface{} {
+	if f, ok := v.Interface().(field); ok && f.Present() {
+		return f.update()
+	}
+	return nil
+}
+
+func columnName(sf reflect.StructField) string {
+	for _, option := range strings.Split(sf.Tag.Get("gorm"), ";") {
+		if column, ok := strings.CutPrefix(option, "column:"); ok {
+			return column
+		}
+	}
+	if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return sf.Name
+}
@@ -0,0 +1,94 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+type updateUser struct {
+	Name     Optional[string] `json:"name"`
+	Nickname Optional[string] `json:"nickname" gorm:"column:display_name"`
+	Age      Optional[int]    `json:"age"`
+	Email    string           `json:"email"`
+}
+
+func TestOptionalUnmarshal(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantPresent bool
+		wantNull    bool
+		wantValue   string
+		wantSet     bool
+	}{
+		{"missing", `{}`, false, false, "", false},
+		{"null", `{"name":null}`, true, true, "", false},
+		{"set", `{"name":"Alice"}`, true, false, "Alice", true},
+		{"set to empty", `{"name":""}`, true, false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req updateUser
+			if err := json.Unmarshal([]byte(tt.body), &req); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			if req.Name.Present() != tt.wantPresent {
+				t.Errorf("Present = %t, want %t", req.Name.Present(), tt.wantPresent)
+			}
+			if req.Name.IsNull() != tt.wantNull {
+				t.Errorf("IsNull = %t, want %t", req.Name.IsNull(), tt.wantNull)
+			}
+			if value, ok := req.Name.Value(); value != tt.wantValue || ok != tt.wantSet {
+				t.Errorf("Value = %q, %t; want %q, %t", value, ok, tt.wantValue, tt.wantSet)
+			}
+		})
+	}
+}
+
+func TestOptionalUnmarshalInvalid(t *testing.T) {
+	var req updateUser
+	if err := json.Unmarshal([]byte(`{"age":"old"}`), &req); err == nil {
+		t.Error("Unmarshal accepted a string for an Optional[int]")
+	}
+}
+
+func TestOptionalMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Optional[int]
+		want string
+	}{
+		{"missing", Optional[int]{}, "null"},
+		{"null", Null[int](), "null"},
+		{"set", Some(42), "42"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.opt)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	var req updateUser
+	body := `{"name":"Alice","nickname":null,"email":"alice@example.com"}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	// Age is missing and Email isn't Optional, so neither is updated
+	want := map[string]interface{}{"name": "Alice", "display_name": nil}
+	if got := Updates(&req); !reflect.DeepEqual(got, want) {
+		t.Errorf("Updates = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnknownField is returned for query parameters that aren't allowlisted
+	ErrUnknownField = errors.New("unknown filter field")
+	// ErrUnsupportedOperator is returned for operators a field doesn't allow
+	ErrUnsupportedOperator = errors.New("unsupported filter operator")
+	// ErrInvalidValue is returned when a value can't be parsed as the field type
+	ErrInvalidValue = errors.New("invalid filter value")
+)
+
+// FieldType is the type a filter value is parsed as
+type FieldType int
+
+const (
+	String FieldType = iota
+	Int
+	Bool
+	Time
+)
+
+// Operator is a comparison applied by a filter condition
+type Operator string
+
+const (
+	Eq   Operator = "eq"
+	Gt   Operator = "gt"
+	Lt   Operator = "lt"
+	Like Operator = "like"
+)
+
+// reservedParams are query parameters handled outside of filtering
+var reservedParams = map[string]bool{
+	"page":      true,
+	"page_size": true,
+	"sort":      true,
+}
+
+// Field describes an allowlisted filter parameter. Operators lists the
+// operators the field accepts; the first one is used when the parameter is
+// given without an explicit operator. An empty list allows only Eq.
+type Field struct {
+	Column    string
+	Type      FieldType
+	Operators []Operator
+}
+
+// Condition is a single parsed filter condition
+type Condition struct {
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+// Filter maps allowlisted query parameters to database conditions. Columns
+// only ever come from the allowlist, never from the request. The package
+// doesn't depend on an ORM; database.FilterScope and database.ListScope
+// apply the results to GORM queries.
+type Filter struct {
+	fields map[string]Field
+}
+
+// NewFilter creates a filter from parameter name to field definition.
+// Parameters are matched as `name=value` for the default operator or
+// `name[op]=value` for an explicit one, e.g. `created_at[gt]=2024-01-01`.
+func NewFilter(fields map[string]Field) *Filter {
+	return &Filter{fields: fields}
+}
+
+// Parse converts query parameters into conditions, rejecting any parameter
+// that isn't allowlisted.
+func (f *Filter) Parse(values url.Values) ([]Condition, error) {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	for _, key := range keys {
+		if reservedParams[key] {
+			continue
+		}
+
+		name, op := key, Operator("")
+		if open := strings.IndexByte(key, '['); open > 0 && strings.HasSuffix(key, "]") {
+			name, op = key[:open], Operator(key[open+1:len(key)-1])
+		}
+
+		field, ok := f.fields[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownField, name)
+		}
+
+		allowed := field.Operators
+		if len(allowed) == 0 {
+			allowed = []Operator{Eq}
+		}
+		if op == "" {
+			op = allowed[0]
+		}
+		if !slices.Contains(allowed, op) {
+			return nil, fmt.Errorf("%w: %s on %s", ErrUnsupportedOperator, op, name)
+		}
+
+		for _, raw := range values[key] {
+			value, err := parseValue(field.Type, raw)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %v", ErrInvalidValue, name, err)
+			}
+			if op == Like {
+				if field.Type != String {
+					return nil, fmt.Errorf("%w: like on non-string field %s", ErrUnsupportedOperator, name)
+				}
+				value = "%" + escapeLike(raw) + "%"
+			}
+			conditions = append(conditions, Condition{Column: field.Column, Operator: op, Value: value})
+		}
+	}
+
+	return conditions, nil
+}
+
+// ListParams combines filter conditions and pagination for list endpoints
+type ListParams struct {
+	Conditions []Condition
+	Pagination Pagination
+}
+
+// ParseList parses both filters and pagination from the query string.
+// sortable is the endpoint's sort allowlist (see ParsePagination).
+func ParseList(values url.Values, filter *Filter, sortable map[string]string) (ListParams, error) {
+	conditions, err := filter.Parse(values)
+	if err != nil {
+		return ListParams{}, err
+	}
+
+	pagination, err := ParsePagination(values, sortable)
+	if err != nil {
+		return ListParams{}, err
+	}
+
+	return ListParams{Conditions: conditions, Pagination: pagination}, nil
+}
+
+func parseValue(fieldType FieldType, raw string) (interface{}, error) {
+	switch fieldType {
+	case Int:
+		return strconv.ParseInt(raw, 10, 64)
+	case Bool:
+		return strconv.ParseBool(raw)
+	case Time:
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return t, nil
+		}
+		return time.Parse(time.DateOnly, raw)
+	default:
+		return raw, nil
+	}
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
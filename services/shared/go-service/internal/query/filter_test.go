@@ -0,0 +1,86 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func cond(column string, op Operator, value interface{}) Condition {
+	return Condition{Column: column, Operator: op, Value: value}
+}
+
+func TestFilterParse(t *testing.T) {
+	filter := NewFilter(map[string]Field{
+		"name":       {Column: "display_name", Type: String, Operators: []Operator{Eq, Like}},
+		"age":        {Column: "age", Type: Int, Operators: []Operator{Eq, Gt, Lt}},
+		"active":     {Column: "is_active", Type: Bool},
+		"created_at": {Column: "created_at", Type: Time, Operators: []Operator{Gt, Lt}},
+		"count":      {Column: "count", Type: Int, Operators: []Operator{Like}},
+	})
+
+	tests := []struct {
+		name    string
+		query   string
+		want    []Condition
+		wantErr error
+	}{
+		{"string equality", "name=alice", []Condition{cond("display_name", Eq, "alice")}, nil},
+		{"like is escaped", "name[like]=50%25_off", []Condition{cond("display_name", Like, `%50\%\_off%`)}, nil},
+		{"int equality", "age=42", []Condition{cond("age", Eq, int64(42))}, nil},
+		{"greater than", "age[gt]=18", []Condition{cond("age", Gt, int64(18))}, nil},
+		{"less than", "age[lt]=65", []Condition{cond("age", Lt, int64(65))}, nil},
+		{"bool", "active=true", []Condition{cond("is_active", Eq, true)}, nil},
+		{"RFC 3339 time", "created_at[gt]=2024-01-02T03:04:05Z", []Condition{cond("created_at", Gt, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))}, nil},
+		{"date", "created_at[lt]=2024-01-02", []Condition{cond("created_at", Lt, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))}, nil},
+		{"default operator is the first allowed", "created_at=2024-01-02", []Condition{cond("created_at", Gt, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))}, nil},
+		{"repeated parameter", "age=1&age=2", []Condition{cond("age", Eq, int64(1)), cond("age", Eq, int64(2))}, nil},
+		{"pagination is not a filter", "page=2&page_size=10&sort=-age", nil, nil},
+		{"unknown field", "password_hash=x", nil, ErrUnknownField},
+		{"column name instead of parameter", "display_name=alice", nil, ErrUnknownField},
+		{"unknown field with operator", "role[eq]=admin", nil, ErrUnknownField},
+		{"operator not allowed", "name[gt]=a", nil, ErrUnsupportedOperator},
+		{"only eq without operators", "active[like]=t", nil, ErrUnsupportedOperator},
+		{"like on non-string field", "count[like]=1", nil, ErrUnsupportedOperator},
+		{"invalid int", "age=old", nil, ErrInvalidValue},
+		{"invalid bool", "active=maybe", nil, ErrInvalidValue},
+		{"invalid time", "created_at[gt]=yesterday", nil, ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			got, err := filter.Parse(values)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("conditions = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseList(t *testing.T) {
+	filter := NewFilter(map[string]Field{"age": {Column: "age", Type: Int}})
+	sortable := map[string]string{"age": "age"}
+
+	values, _ := url.ParseQuery("age=42&page=3&page_size=10&sort=-age")
+	params, err := ParseList(values, filter, sortable)
+	if err != nil {
+		t.Fatalf("ParseList: %v", err)
+	}
+	if len(params.Conditions) != 1 || params.Pagination.Offset() != 20 {
+		t.Errorf("params = %+v, want one condition and offset 20", params)
+	}
+	if column, desc := params.Pagination.OrderBy(); column != "age" || !desc {
+		t.Errorf("OrderBy = %q, %t; want age, descending", column, desc)
+	}
+
+	values, _ = url.ParseQuery("unknown=1&page=3")
+	if _, err := ParseList(values, filter, sortable); !errors.Is(err, ErrUnknownField) {
+		t.Errorf("err = %v, want ErrUnknownField", err)
+	}
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// DefaultPageSize is used when the request doesn't specify page_size
+	DefaultPageSize = 20
+	// MaxPageSize caps page_size to keep list queries bounded
+	MaxPageSize = 100
+)
+
+// ErrOffsetTooLarge is returned for pages beyond the configured maximum
+// offset, which would make the database skip over that many rows
+var ErrOffsetTooLarge = errors.New("pagination offset too large")
+
+// PaginationOptions is the service-wide pagination policy
+type PaginationOptions struct {
+	// DefaultPageSize is used when the request doesn't specify page_size
+	DefaultPageSize int
+	// MaxPageSize caps page_size; larger requests are clamped to it
+	MaxPageSize int
+	// DefaultSort is used when the request doesn't specify sort, e.g.
+	// "-created_at", on endpoints that allow sorting by it. Empty leaves
+	// the order to the database.
+	DefaultSort string
+	// MaxOffset rejects pages starting beyond this many rows; 0 allows any
+	// offset. Deep pages are slow since the skipped rows are still scanned.
+	MaxOffset int
+}
+
+var paginationOptions atomic.Pointer[PaginationOptions]
+
+// ConfigurePagination sets the pagination policy used by ParsePagination.
+// Non-positive sizes fall back to DefaultPageSize and MaxPageSize.
+func ConfigurePagination(opts PaginationOptions) {
+	if opts.DefaultPageSize <= 0 {
+		opts.DefaultPageSize = DefaultPageSize
+	}
+	if opts.MaxPageSize <= 0 {
+		opts.MaxPageSize = MaxPageSize
+	}
+	opts.DefaultPageSize = min(opts.DefaultPageSize, opts.MaxPageSize)
+	paginationOptions.Store(&opts)
+}
+
+func currentPagination() PaginationOptions {
+	if opts := paginationOptions.Load(); opts != nil {
+		return *opts
+	}
+	return PaginationOptions{DefaultPageSize: DefaultPageSize, MaxPageSize: MaxPageSize}
+}
+
+// Pagination holds page parameters parsed from the query string
+type Pagination struct {
+	Page     int    `json:"page"`
+	PageSize int    `json:"page_size"`
+	Sort     string `json:"sort,omitempty"`
+
+	// column is the allowlisted column Sort maps to
+	column string
+}
+
+// ParsePagination reads page, page_size and sort from the query string,
+// applying the configured defaults and clamping page_size to the configured
+// maximum. Non-positive values are rejected, and so are pages beyond the
+// configured maximum offset, with ErrOffsetTooLarge.
+//
+// sortable maps the sort keys the endpoint accepts to their columns, like
+// the field map of a Filter, e.g. {"created_at": "created_at", "name":
+// "display_name"}. sort is a key, optionally prefixed with "-" for
+// descending order; any other key is rejected with ErrInvalidValue, so
+// clients can't order by columns they can't see. A nil map disallows sorting.
+func ParsePagination(values url.Values, sortable map[string]string) (Pagination, error) {
+	opts := currentPagination()
+	p := Pagination{Page: 1, PageSize: opts.DefaultPageSize}
+	if column, ok := sortable[strings.TrimPrefix(opts.DefaultSort, "-")]; ok {
+		p.Sort, p.column = opts.DefaultSort, column
+	}
+
+	if raw := values.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return Pagination{}, fmt.Errorf("%w: page must be a positive integer", ErrInvalidValue)
+		}
+		p.Page = page
+	}
+
+	if raw := values.Get("page_size"); raw != "" {
+		size, err := strconv.Atoi(raw)
+		if err != nil || size < 1 {
+			return Pagination{}, fmt.Errorf("%w: page_size must be a positive integer", ErrInvalidValue)
+		}
+		p.PageSize = min(size, opts.MaxPageSize)
+	}
+
+	// Compared by page so huge page numbers can't overflow the offset
+	if opts.MaxOffset > 0 && p.Page-1 > opts.MaxOffset/p.PageSize {
+		return Pagination{}, fmt.Errorf("%w: offsets beyond %d rows are not supported, use keyset pagination (filter past the last item seen) instead",
+			ErrOffsetTooLarge, opts.MaxOffset)
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		column, ok := sortable[strings.TrimPrefix(raw, "-")]
+		if !ok {
+			return Pagination{}, fmt.Errorf("%w: cannot sort by %s", ErrInvalidValue, strings.TrimPrefix(raw, "-"))
+		}
+		p.Sort, p.column = raw, column
+	}
+
+	return p, nil
+}
+
+// Offset returns the number of rows to skip for the current page
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// OrderBy returns the allowlisted column to sort by and whether the order
+// is descending; column is empty when the request isn't sorted
+func (p Pagination) OrderBy() (column string, desc bool) {
+	return p.column, strings.HasPrefix(p.Sort, "-")
+}
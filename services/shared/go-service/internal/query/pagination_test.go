@@ -0,0 +1,76 @@
+package query
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestParsePaginationSort(t *testing.T) {
+	ConfigurePagination(PaginationOptions{DefaultSort: "-created_at"})
+	defer ConfigurePagination(PaginationOptions{})
+
+	sortable := map[string]string{"created_at": "created_at", "name": "display_name"}
+
+	tests := []struct {
+		name       string
+		sortable   map[string]string
+		query      string
+		wantSort   string
+		wantColumn string
+		wantErr    error
+	}{
+		{"default", sortable, "", "-created_at", "created_at", nil},
+		{"allowlisted", sortable, "sort=name", "name", "display_name", nil},
+		{"descending", sortable, "sort=-name", "-name", "display_name", nil},
+		{"not allowlisted", sortable, "sort=password_hash", "", "", ErrInvalidValue},
+		{"column instead of key", sortable, "sort=display_name", "", "", ErrInvalidValue},
+		{"default not allowed", map[string]string{"name": "name"}, "", "", "", nil},
+		{"sorting disallowed", nil, "sort=name", "", "", ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			p, err := ParsePagination(values, tt.sortable)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if p.Sort != tt.wantSort || p.column != tt.wantColumn {
+				t.Errorf("sort = %q (column %q), want %q (column %q)", p.Sort, p.column, tt.wantSort, tt.wantColumn)
+			}
+		})
+	}
+}
+
+func TestParsePaginationMaxOffset(t *testing.T) {
+	ConfigurePagination(PaginationOptions{DefaultPageSize: 20, MaxOffset: 1000})
+	defer ConfigurePagination(PaginationOptions{})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr error
+	}{
+		{"first page", "", nil},
+		{"offset at the limit", "page=51", nil},
+		{"offset past the limit", "page=52", ErrOffsetTooLarge},
+		{"limit with another page size", "page=34&page_size=30", nil},
+		{"past the limit with another page size", "page=35&page_size=30", ErrOffsetTooLarge},
+		{"page that would overflow the offset", "page=9223372036854775807", ErrOffsetTooLarge},
+		{"zero page", "page=0", ErrInvalidValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			p, err := ParsePagination(values, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && p.Offset() > 1000 {
+				t.Errorf("offset %d beyond the maximum", p.Offset())
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ginKey is the gin context key the client is stored under
+const ginKey = "redis"
+
+// Bind middleware makes client available to handlers through For
+func Bind(client *Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(ginKey, client)
+		c.Next()
+	}
+}
+
+// For returns the client bound to the request's context, so operations are
+// cancelled with the request and inherit its deadline without handlers
+// passing c.Request.Context() around. It panics if Bind hasn't run.
+//
+//	value, err := redis.For(c).Get("product:" + id)
+func For(c *gin.Context) *Scoped {
+	return &Scoped{
+		client: c.MustGet(ginKey).(*Client),
+		ctx:    c.Request.Context(),
+	}
+}
+
+// Scoped is a Client whose operations all use one context
+type Scoped struct {
+	client *Client
+	ctx    context.Context
+}
+
+// State returns the scoped view of Client.State, for sessions, locks and
+// other state that must not be evicted
+//
+//	session, found, err := redis.For(c).State().GetOptional("session:" + id)
+func (s *Scoped) State() *Scoped {
+	return &Scoped{client: s.client.State(), ctx: s.ctx}
+}
+
+// Set stores a key-value pair with expiration, jittered by REDIS_TTL_JITTER
+func (s *Scoped) Set(key string, value interface{}, expiration time.Duration) error {
+	return s.client.Set(s.ctx, key, value, expiration)
+}
+
+// Get retrieves a value by key
+func (s *Scoped) Get(key string) (string, error) {
+	return s.client.Get(s.ctx, key)
+}
+
+// GetOptional retrieves a value by key, reporting a miss as found=false
+func (s *Scoped) GetOptional(key string) (string, bool, error) {
+	return s.client.GetOptional(s.ctx, key)
+}
+
+// Del deletes keys
+func (s *Scoped) Del(keys ...string) error {
+	return s.client.Del(s.ctx, keys...)
+}
+
+// Exists checks if keys exist
+func (s *Scoped) Exists(keys ...string) (int64, error) {
+	return s.client.Exists(s.ctx, keys...)
+}
+
+// Expire sets expiration for a key
+func (s *Scoped) Expire(key string, expiration time.Duration) error {
+	return s.client.Expire(s.ctx, key, expiration)
+}
+
+// SetJSON stores value as JSON, compressed above REDIS_COMPRESSION_THRESHOLD
+func (s *Scoped) SetJSON(key string, value interface{}, expiration time.Duration) error {
+	return s.client.SetJSON(s.ctx, key, value, expiration)
+}
+
+// GetJSON retrieves the value stored by SetJSON and unmarshals it into dest
+func (s *Scoped) GetJSON(key string, dest interface{}) error {
+	return s.client.GetJSON(s.ctx, key, dest)
+}
+
+// GetJSONOptional is GetJSON reporting a miss as found=false
+func (s *Scoped) GetJSONOptional(key string, dest interface{}) (bool, error) {
+	return s.client.GetJSONOptional(s.ctx, key, dest)
+}
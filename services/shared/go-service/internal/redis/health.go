@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/health"
+)
+
+// HealthCheck pings Redis. In cluster mode every node is pinged and reported
+// individually in the details; the check only fails when a master is down,
+// since reads of its slots can still be served by replicas but writes can't.
+// Failed replicas are reported without failing the check.
+func (c *Client) HealthCheck(ctx context.Context) (health.CheckResult, error) {
+	cluster, ok := c.client.(*redis.ClusterClient)
+	if !ok {
+		return health.CheckResult{Details: map[string]interface{}{"mode": "standalone"}}, c.PingContext(ctx)
+	}
+
+	var (
+		mu    sync.Mutex
+		nodes = make(map[string]interface{})
+	)
+	ping := func(role string) func(ctx context.Context, node *redis.Client) error {
+		return func(ctx context.Context, node *redis.Client) error {
+			status := map[string]interface{}{"role": role, "status": health.StatusHealthy}
+			err := node.Ping(ctx).Err()
+			if err != nil {
+				status["status"] = health.StatusUnhealthy
+				status["error"] = err.Error()
+			}
+
+			mu.Lock()
+			nodes[node.Options().Addr] = status
+			mu.Unlock()
+			return err
+		}
+	}
+
+	mastersErr := cluster.ForEachMaster(ctx, ping("master"))
+	// Replica failures are only reported
+	_ = cluster.ForEachSlave(ctx, ping("replica"))
+
+	result := health.CheckResult{Details: map[string]interface{}{
+		"mode":  "cluster",
+		"nodes": nodes,
+	}}
+	if mastersErr != nil {
+		return result, fmt.Errorf("redis cluster master unavailable: %w", mastersErr)
+	}
+	return result, nil
+}
@@ -0,0 +1,112 @@
+package redis
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/health"
+)
+
+// deadAddr returns an address nothing listens on
+func deadAddr(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	return addr
+}
+
+// newClusterClient returns a Client for a cluster of two shards, each a
+// master and a replica, with the topology given rather than discovered
+func newClusterClient(t *testing.T, shards [2][2]string) *Client {
+	t.Helper()
+	cluster := redis.NewClusterClient(&redis.ClusterOptions{
+		ClusterSlots: func(ctx context.Context) ([]redis.ClusterSlot, error) {
+			return []redis.ClusterSlot{
+				{Start: 0, End: 8191, Nodes: []redis.ClusterNode{{Addr: shards[0][0]}, {Addr: shards[0][1]}}},
+				{Start: 8192, End: 16383, Nodes: []redis.ClusterNode{{Addr: shards[1][0]}, {Addr: shards[1][1]}}},
+			}, nil
+		},
+		MaxRetries: -1,
+	})
+	t.Cleanup(func() { cluster.Close() })
+	return &Client{client: cluster}
+}
+
+func TestClusterHealthCheck(t *testing.T) {
+	master1, replica1 := miniredis.RunT(t).Addr(), miniredis.RunT(t).Addr()
+	master2, replica2 := miniredis.RunT(t).Addr(), miniredis.RunT(t).Addr()
+	down := deadAddr(t)
+
+	tests := []struct {
+		name     string
+		shards   [2][2]string
+		down     string
+		downRole string
+		wantErr  bool
+	}{
+		{"all nodes up", [2][2]string{{master1, replica1}, {master2, replica2}}, "", "", false},
+		{"replica down", [2][2]string{{master1, replica1}, {master2, down}}, down, "replica", false},
+		{"master down", [2][2]string{{master1, replica1}, {down, replica2}}, down, "master", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClusterClient(t, tt.shards)
+
+			result, err := client.HealthCheck(context.Background())
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "redis cluster master unavailable") {
+					t.Errorf("HealthCheck = %v, want a master unavailable error", err)
+				}
+			} else if err != nil {
+				t.Errorf("HealthCheck: %v", err)
+			}
+
+			if result.Details["mode"] != "cluster" {
+				t.Errorf("mode = %v, want cluster", result.Details["mode"])
+			}
+			nodes, _ := result.Details["nodes"].(map[string]interface{})
+			if len(nodes) != 4 {
+				t.Fatalf("nodes = %v, want all four reported", nodes)
+			}
+			for addr, node := range nodes {
+				status := node.(map[string]interface{})
+				want := health.StatusHealthy
+				if addr == tt.down {
+					want = health.StatusUnhealthy
+					if status["role"] != tt.downRole || status["error"] == nil {
+						t.Errorf("down node %s = %v, want a %s with its error", addr, status, tt.downRole)
+					}
+				}
+				if status["status"] != want {
+					t.Errorf("node %s status = %v, want %s", addr, status["status"], want)
+				}
+			}
+		})
+	}
+}
+
+func TestStandaloneHealthCheck(t *testing.T) {
+	client, server := newTestClient(t, nil)
+
+	result, err := client.HealthCheck(context.Background())
+	if err != nil {
+		t.Fatalf("HealthCheck: %v", err)
+	}
+	if result.Details["mode"] != "standalone" {
+		t.Errorf("mode = %v, want standalone", result.Details["mode"])
+	}
+
+	server.Close()
+	if _, err := client.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck passed with the server down")
+	}
+}
@@ -0,0 +1,110 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanBatchSize is the COUNT hint passed to SCAN and the number of keys
+// deleted per DEL
+const scanBatchSize = 500
+
+// globEscaper escapes the characters SCAN's MATCH treats as glob syntax, so
+// a key prefix such as "app[1]:" only matches itself
+var globEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// DelByPattern deletes every key in the client's namespace matching the glob
+// pattern and returns how many were deleted. Keys are found with SCAN rather
+// than KEYS so Redis is never blocked on a large keyspace, and deleted in
+// batches as they are found. In cluster mode every master is scanned.
+func (c *Client) DelByPattern(ctx context.Context, pattern string) (int64, error) {
+	cluster, ok := c.client.(*redis.ClusterClient)
+	if !ok {
+		return c.delByPattern(ctx, c.client, pattern, false)
+	}
+
+	var deleted atomic.Int64
+	err := cluster.ForEachMaster(ctx, func(ctx context.Context, node *redis.Client) error {
+		n, err := c.delByPattern(ctx, node, pattern, true)
+		deleted.Add(n)
+		return err
+	})
+	return deleted.Load(), err
+}
+
+// delByPattern scans and deletes the matching keys of a single node. Cluster
+// nodes reject multi-key commands spanning hash slots, so with perKey each
+// key gets its own UNLINK, pipelined per batch.
+//
+// SCAN only promises to return keys present for the whole scan, and a
+// server whose cursor is an offset skips keys once earlier ones are
+// deleted, so passes are repeated until one finds nothing to delete.
+func (c *Client) delByPattern(ctx context.Context, node redis.Cmdable, pattern string, perKey bool) (int64, error) {
+	match := globEscaper.Replace(c.prefix) + pattern
+
+	var deleted int64
+	for {
+		n, err := scanAndUnlink(ctx, node, match, perKey)
+		deleted += n
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete keys matching %q: %w", pattern, err)
+		}
+		if n == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// scanAndUnlink makes one full SCAN pass over node, deleting the keys
+// matching match batch by batch as they are found
+func scanAndUnlink(ctx context.Context, node redis.Cmdable, match string, perKey bool) (int64, error) {
+	var (
+		cursor  uint64
+		deleted int64
+	)
+
+	for {
+		keys, next, err := node.Scan(ctx, cursor, match, scanBatchSize).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("scan: %w", err)
+		}
+
+		if len(keys) > 0 {
+			n, err := unlink(ctx, node, keys, perKey)
+			deleted += n
+			if err != nil {
+				return deleted, fmt.Errorf("unlink: %w", err)
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// unlink deletes keys with UNLINK, which reclaims memory asynchronously on
+// the server
+func unlink(ctx context.Context, node redis.Cmdable, keys []string, perKey bool) (int64, error) {
+	if !perKey {
+		return node.Unlink(ctx, keys...).Result()
+	}
+
+	cmds, err := node.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Unlink(ctx, key)
+		}
+		return nil
+	})
+
+	var deleted int64
+	for _, cmd := range cmds {
+		deleted += cmd.(*redis.IntCmd).Val()
+	}
+	return deleted, err
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"{{ module_name }}/internal/config"
+)
+
+func TestDelByPattern(t *testing.T) {
+	client, server := newTestClient(t, func(cfg *config.Config) { cfg.RedisKeyPrefix = "app[1]:" })
+	ctx := context.Background()
+
+	// More matching keys than a single SCAN batch
+	const matching = scanBatchSize + 100
+	for i := 0; i < matching; i++ {
+		if err := client.Set(ctx, fmt.Sprintf("cache:user:%d", i), "v", time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	// Outside the pattern, and outside the prefix; "app1:" would match the
+	// prefix if its brackets were taken as a character class
+	if err := client.Set(ctx, "session:1", "v", time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for _, key := range []string{"app1:cache:user:1", "other:cache:user:1"} {
+		if err := server.Set(key, "v"); err != nil {
+			t.Fatalf("Set %s: %v", key, err)
+		}
+	}
+
+	deleted, err := client.DelByPattern(ctx, "cache:user:*")
+	if err != nil {
+		t.Fatalf("DelByPattern: %v", err)
+	}
+	if deleted != matching {
+		t.Errorf("deleted = %d, want %d", deleted, matching)
+	}
+
+	for _, key := range []string{"app[1]:session:1", "app1:cache:user:1", "other:cache:user:1"} {
+		if !server.Exists(key) {
+			t.Errorf("%s deleted, want it kept", key)
+		}
+	}
+	if n := len(server.Keys()); n != 3 {
+		t.Errorf("%d keys left, want 3: %v", n, server.Keys())
+	}
+}
@@ -0,0 +1,101 @@
+package redis
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Every value written by SetJSON starts with a marker byte recording how the
+// payload is encoded, so GetJSON knows whether to decompress.
+const (
+	encodingRaw  byte = 0x00
+	encodingGzip byte = 0x01
+)
+
+// SetJSON stores value as JSON with expiration, jittered by REDIS_TTL_JITTER.
+// When compression is enabled payloads of at least
+// REDIS_COMPRESSION_THRESHOLD bytes are gzip-compressed.
+func (c *Client) SetJSON(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value for key %s: %w", key, err)
+	}
+
+	encoded, err := c.encode(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for key %s: %w", key, err)
+	}
+
+	return c.client.Set(ctx, c.key(key), encoded, c.jitter(expiration)).Err()
+}
+
+// GetJSON retrieves the value stored by SetJSON and unmarshals it into dest
+func (c *Client) GetJSON(ctx context.Context, key string, dest interface{}) error {
+	raw, err := c.client.Get(ctx, c.key(key)).Bytes()
+	if err != nil {
+		return err
+	}
+
+	data, err := decode(raw)
+	if err != nil {
+		return fmt.Errorf("failed to decode value for key %s: %w", key, err)
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+// GetJSONOptional is GetJSON reporting a missing key as found=false instead
+// of an error; dest is left untouched on a miss
+func (c *Client) GetJSONOptional(ctx context.Context, key string, dest interface{}) (found bool, err error) {
+	err = c.GetJSON(ctx, key, dest)
+	if IsMiss(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *Client) encode(data []byte) ([]byte, error) {
+	if c.compressThreshold <= 0 || len(data) < c.compressThreshold {
+		return append([]byte{encodingRaw}, data...), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte(encodingGzip)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decode(raw []byte) ([]byte, error) {
+	if len(raw) == 0 {
+		return raw, nil
+	}
+
+	switch raw[0] {
+	case encodingRaw:
+		return raw[1:], nil
+	case encodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(raw[1:]))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+	default:
+		// Plain JSON written before markers were introduced
+		return raw, nil
+	}
+}
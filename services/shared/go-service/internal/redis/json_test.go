@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"{{ module_name }}/internal/config"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	// {"name":"<n x's>"} is 11 bytes plus the name
+	const threshold = 64
+	atThreshold := strings.Repeat("x", threshold-11)
+
+	tests := []struct {
+		name      string
+		threshold int
+		value     string
+		marker    byte
+	}{
+		{"below the threshold", threshold, atThreshold[1:], encodingRaw},
+		{"at the threshold", threshold, atThreshold, encodingGzip},
+		{"above the threshold", threshold, strings.Repeat("x", 1000), encodingGzip},
+		{"compression disabled", 0, strings.Repeat("x", 1000), encodingRaw},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t, func(cfg *config.Config) {
+				cfg.RedisKeyPrefix = "svc:"
+				cfg.RedisCompressionThreshold = tt.threshold
+			})
+			ctx := context.Background()
+
+			type user struct {
+				Name string `json:"name"`
+			}
+			if err := client.SetJSON(ctx, "user", user{Name: tt.value}, 0); err != nil {
+				t.Fatalf("SetJSON: %v", err)
+			}
+
+			raw, err := server.Get("svc:user")
+			if err != nil {
+				t.Fatalf("stored value: %v", err)
+			}
+			if raw[0] != tt.marker {
+				t.Errorf("marker = %#x, want %#x", raw[0], tt.marker)
+			}
+
+			var got user
+			if err := client.GetJSON(ctx, "user", &got); err != nil {
+				t.Fatalf("GetJSON: %v", err)
+			}
+			if got.Name != tt.value {
+				t.Errorf("GetJSON name = %d bytes, want %d", len(got.Name), len(tt.value))
+			}
+		})
+	}
+}
+
+func TestGetJSONLegacyValue(t *testing.T) {
+	client, server := newTestClient(t, nil)
+
+	// Values written before the encoding marker are plain JSON
+	server.Set("legacy", `{"name":"Alice"}`)
+
+	var got struct{ Name string }
+	if err := client.GetJSON(context.Background(), "legacy", &got); err != nil || got.Name != "Alice" {
+		t.Errorf("GetJSON = %+v, %v; want Alice", got, err)
+	}
+}
+
+func TestGetJSONOptional(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, client *Client, server *miniredis.Miniredis)
+		want      user
+		wantFound bool
+		wantErr   bool
+	}{
+		{
+			name: "hit",
+			setup: func(t *testing.T, client *Client, _ *miniredis.Miniredis) {
+				if err := client.SetJSON(context.Background(), "user", user{Name: "Alice"}, 0); err != nil {
+					t.Fatalf("SetJSON: %v", err)
+				}
+			},
+			want:      user{Name: "Alice"},
+			wantFound: true,
+		},
+		{
+			name:  "miss leaves dest untouched",
+			setup: func(*testing.T, *Client, *miniredis.Miniredis) {},
+			want:  user{Name: "unchanged"},
+		},
+		{
+			name:    "undecodable value",
+			setup:   func(_ *testing.T, _ *Client, s *miniredis.Miniredis) { s.Set("user", "not json") },
+			want:    user{Name: "unchanged"},
+			wantErr: true,
+		},
+		{
+			name: "server error",
+			setup: func(_ *testing.T, _ *Client, s *miniredis.Miniredis) {
+				s.SetError("LOADING Redis is loading the dataset")
+			},
+			want:    user{Name: "unchanged"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t, nil)
+			tt.setup(t, client, server)
+
+			getters := map[string]func(dest interface{}) (bool, error){
+				"client": func(dest interface{}) (bool, error) {
+					return client.GetJSONOptional(context.Background(), "user", dest)
+				},
+				"scoped": func(dest interface{}) (bool, error) {
+					return (&Scoped{client: client, ctx: context.Background()}).GetJSONOptional("user", dest)
+				},
+			}
+			for via, get := range getters {
+				got := user{Name: "unchanged"}
+				found, err := get(&got)
+				if (err != nil) != tt.wantErr || IsMiss(err) {
+					t.Errorf("%s: err = %v, want error %v and never redis.Nil", via, err, tt.wantErr)
+				}
+				if found != tt.wantFound || got != tt.want {
+					t.Errorf("%s: GetJSONOptional = %+v, %v; want %+v, %v", via, got, found, tt.want, tt.wantFound)
+				}
+			}
+		})
+	}
+}
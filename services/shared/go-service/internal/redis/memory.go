@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/health"
+)
+
+// memoryInfo is the part of INFO memory relevant to eviction
+type memoryInfo struct {
+	used   int64
+	max    int64
+	policy string
+}
+
+// usage is the fraction of maxmemory in use, 0 without a limit
+func (m memoryInfo) usage() float64 {
+	if m.max <= 0 {
+		return 0
+	}
+	return float64(m.used) / float64(m.max)
+}
+
+// evictsState reports whether the eviction policy may evict keys without
+// a TTL, i.e. keys stored through State
+func (m memoryInfo) evictsState() bool {
+	return m.max > 0 && strings.HasPrefix(m.policy, "allkeys-")
+}
+
+func parseMemoryInfo(info string) (memoryInfo, error) {
+	var (
+		m   memoryInfo
+		err error
+	)
+	for _, line := range strings.Split(info, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "used_memory":
+			m.used, err = strconv.ParseInt(value, 10, 64)
+		case "maxmemory":
+			m.max, err = strconv.ParseInt(value, 10, 64)
+		case "maxmemory_policy":
+			m.policy = value
+		}
+		if err != nil {
+			return m, fmt.Errorf("invalid %s in INFO memory: %w", key, err)
+		}
+	}
+	return m, nil
+}
+
+// MemoryCheck returns a health check reporting memory use against
+// maxmemory. It fails, degrading the service when registered as
+// non-critical, once usage reaches warnRatio of maxmemory, since Redis then
+// starts evicting keys or rejecting writes. It also reports when the
+// eviction policy can evict keys without a TTL, which puts State keys at
+// risk. Servers without maxmemory always pass. In cluster mode every master
+// is checked.
+func (c *Client) MemoryCheck(warnRatio float64) health.CheckFunc {
+	return func(ctx context.Context) (health.CheckResult, error) {
+		var (
+			mu    sync.Mutex
+			nodes = make(map[string]memoryInfo)
+		)
+		inspect := func(ctx context.Context, node *redis.Client) error {
+			info, err := node.Info(ctx, "memory").Result()
+			if err != nil {
+				return err
+			}
+			memory, err := parseMemoryInfo(info)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			nodes[node.Options().Addr] = memory
+			mu.Unlock()
+			return nil
+		}
+
+		var err error
+		switch client := c.client.(type) {
+		case *redis.ClusterClient:
+			err = client.ForEachMaster(ctx, inspect)
+		case *redis.Client:
+			err = inspect(ctx, client)
+		default:
+			err = fmt.Errorf("unsupported Redis client %T", client)
+		}
+		if err != nil {
+			return health.CheckResult{}, fmt.Errorf("failed to read Redis memory info: %w", err)
+		}
+
+		details := make(map[string]interface{}, len(nodes))
+		var pressured []string
+		for addr, memory := range nodes {
+			node := map[string]interface{}{
+				"used_memory":      memory.used,
+				"maxmemory":        memory.max,
+				"maxmemory_policy": memory.policy,
+				"usage":            memory.usage(),
+			}
+			if memory.evictsState() {
+				node["warning"] = "eviction policy " + memory.policy + " can evict keys without a TTL; use a volatile-* policy to protect state"
+			}
+			details[addr] = node
+			if memory.usage() >= warnRatio {
+				pressured = append(pressured, addr)
+			}
+		}
+
+		result := health.CheckResult{Details: details}
+		if len(pressured) > 0 {
+			return result, fmt.Errorf("redis memory above %.0f%% of maxmemory on %s", warnRatio*100, strings.Join(pressured, ", "))
+		}
+		return result, nil
+	}
+}
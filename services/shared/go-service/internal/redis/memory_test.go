@@ -0,0 +1,50 @@
+package redis
+
+import "testing"
+
+func TestParseMemoryInfo(t *testing.T) {
+	info := "# Memory\r\nused_memory:943718400\r\nused_memory_human:900.00M\r\nmaxmemory:1073741824\r\nmaxmemory_human:1.00G\r\nmaxmemory_policy:allkeys-lru\r\n"
+
+	memory, err := parseMemoryInfo(info)
+	if err != nil {
+		t.Fatalf("parseMemoryInfo: %v", err)
+	}
+	if memory.used != 943718400 || memory.max != 1073741824 || memory.policy != "allkeys-lru" {
+		t.Errorf("parseMemoryInfo = %+v", memory)
+	}
+	if usage := memory.usage(); usage < 0.87 || usage > 0.88 {
+		t.Errorf("usage = %f, want about 0.879", usage)
+	}
+	if !memory.evictsState() {
+		t.Error("allkeys-lru should be reported as evicting state")
+	}
+
+	if _, err := parseMemoryInfo("used_memory:lots\r\n"); err == nil {
+		t.Error("parseMemoryInfo accepted a non-numeric used_memory")
+	}
+}
+
+func TestMemoryInfoPolicies(t *testing.T) {
+	tests := []struct {
+		name        string
+		memory      memoryInfo
+		wantUsage   float64
+		evictsState bool
+	}{
+		{"no maxmemory", memoryInfo{used: 100, policy: "allkeys-lru"}, 0, false},
+		{"volatile policy", memoryInfo{used: 50, max: 100, policy: "volatile-lru"}, 0.5, false},
+		{"noeviction", memoryInfo{used: 100, max: 100, policy: "noeviction"}, 1, false},
+		{"allkeys policy", memoryInfo{used: 95, max: 100, policy: "allkeys-lfu"}, 0.95, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if usage := tt.memory.usage(); usage != tt.wantUsage {
+				t.Errorf("usage = %f, want %f", usage, tt.wantUsage)
+			}
+			if evicts := tt.memory.evictsState(); evicts != tt.evictsState {
+				t.Errorf("evictsState = %t, want %t", evicts, tt.evictsState)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nonceKeyPrefix namespaces nonces within the client's key prefix
+const nonceKeyPrefix = "nonce:"
+
+// consumeNonce deletes the nonce and reports whether it existed, in one step
+// so two concurrent consumers can't both succeed
+var consumeNonce = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	redis.call("DEL", KEYS[1])
+	return 1
+end
+return 0
+`)
+
+// NonceStore issues one-time nonces for replay protection (signed requests,
+// MFA challenges). A nonce can be consumed once, within its TTL. Nonces are
+// state kept through Client.State; since they expire, they are evictable
+// under any policy but noeviction, and an evicted claim lets a replay
+// through, so run the instance holding them with noeviction.
+type NonceStore struct {
+	client *Client
+	ttl    time.Duration
+}
+
+// NewNonceStore creates a nonce store whose nonces expire after ttl
+func NewNonceStore(client *Client, ttl time.Duration) *NonceStore {
+	return &NonceStore{client: client.State(), ttl: ttl}
+}
+
+// Issue generates a random nonce and stores it for the store's TTL
+func (s *NonceStore) Issue(ctx context.Context) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+
+	// The TTL is exact, not jittered: it is a security bound, not a cache hint
+	ok, err := s.client.client.SetNX(ctx, s.key(nonce), 1, s.ttl).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to store nonce: %w", err)
+	}
+	if !ok {
+		return "", fmt.Errorf("failed to store nonce: already exists")
+	}
+	return nonce, nil
+}
+
+// Consume reports whether nonce was issued, hasn't expired and hasn't been
+// consumed before, and marks it consumed
+func (s *NonceStore) Consume(ctx context.Context, nonce string) (bool, error) {
+	if nonce == "" {
+		return false, nil
+	}
+
+	consumed, err := consumeNonce.Run(ctx, s.client.client, []string{s.key(nonce)}).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume nonce: %w", err)
+	}
+	return consumed == 1, nil
+}
+
+// Claim records a nonce chosen by the client, such as a request signature,
+// for ttl and reports whether it was new. A second claim of the same nonce
+// within ttl returns false, so a captured request can't be replayed.
+func (s *NonceStore) Claim(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	ok, err := s.client.client.SetNX(ctx, s.key("claimed:"+nonce), 1, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim nonce: %w", err)
+	}
+	return ok, nil
+}
+
+func (s *NonceStore) key(nonce string) string {
+	return s.client.key(nonceKeyPrefix + nonce)
+}
@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNonceStoreConsume(t *testing.T) {
+	client, server := newTestClient(t, nil)
+	const ttl = time.Minute
+	store := NewNonceStore(client, ttl)
+	ctx := context.Background()
+
+	consume := func(nonce string) bool {
+		t.Helper()
+		ok, err := store.Consume(ctx, nonce)
+		if err != nil {
+			t.Fatalf("Consume: %v", err)
+		}
+		return ok
+	}
+
+	nonce, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if got := server.TTL(store.key(nonce)); got != ttl {
+		t.Errorf("nonce TTL = %v, want exactly %v", got, ttl)
+	}
+	if !consume(nonce) {
+		t.Fatal("first Consume = false, want true")
+	}
+	if consume(nonce) {
+		t.Error("second Consume = true, want false")
+	}
+
+	expired, err := store.Issue(ctx)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	server.FastForward(ttl)
+	if consume(expired) {
+		t.Error("Consume after the TTL = true, want false")
+	}
+
+	if consume("never-issued") || consume("") {
+		t.Error("Consume of an unknown nonce = true, want false")
+	}
+}
+
+func TestNonceStoreClaim(t *testing.T) {
+	client, server := newTestClient(t, nil)
+	store := NewNonceStore(client, time.Minute)
+	ctx := context.Background()
+
+	claim := func(nonce string) bool {
+		t.Helper()
+		ok, err := store.Claim(ctx, nonce, 30*time.Second)
+		if err != nil {
+			t.Fatalf("Claim: %v", err)
+		}
+		return ok
+	}
+
+	if !claim("signature-1") {
+		t.Fatal("first Claim = false, want true")
+	}
+	if claim("signature-1") {
+		t.Error("repeated Claim = true, want false")
+	}
+	if !claim("signature-2") {
+		t.Error("Claim of another nonce = false, want true")
+	}
+
+	server.FastForward(30 * time.Second)
+	if !claim("signature-1") {
+		t.Error("Claim after the TTL = false, want true")
+	}
+}
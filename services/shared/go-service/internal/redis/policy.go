@@ -0,0 +1,80 @@
+package redis
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/metrics"
+)
+
+var degradedOperationsTotal = metrics.Register(nil, prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "redis_degraded_operations_total",
+		Help: "The total number of Redis failures handled by a failure policy",
+	},
+	[]string{"component", "policy"},
+))
+
+// IsMiss reports whether err only means the key doesn't exist, which is a
+// cache miss rather than a Redis failure
+func IsMiss(err error) bool {
+	return errors.Is(err, redis.Nil)
+}
+
+// FailurePolicy decides what Redis-dependent middleware (idempotency keys,
+// response caching, distributed rate limiting, ...) does when Redis fails.
+// Components fail open by default: the request is served as if Redis had
+// nothing for it, skipping the cache or limit. Components the policy was
+// created with reject the request with 503 instead, for features where
+// proceeding without Redis is unsafe, such as idempotency of payments.
+type FailurePolicy struct {
+	failClosed map[string]bool
+	allClosed  bool
+}
+
+// NewFailurePolicy creates a policy failing the named components closed;
+// "*" fails every component closed
+func NewFailurePolicy(failClosed []string) *FailurePolicy {
+	p := &FailurePolicy{failClosed: make(map[string]bool, len(failClosed))}
+	for _, component := range failClosed {
+		if component == "*" {
+			p.allClosed = true
+		}
+		p.failClosed[component] = true
+	}
+	return p
+}
+
+// FailsOpen reports whether component carries on when Redis fails
+func (p *FailurePolicy) FailsOpen(component string) bool {
+	return !p.allClosed && !p.failClosed[component]
+}
+
+// Handle applies the policy to err from a Redis operation of component and
+// counts the degraded operation. It returns nil when component fails open,
+// so the caller proceeds without Redis, and otherwise a 503 error to reject
+// the request with. Misses should be handled before calling Handle.
+//
+//	if err != nil && !redis.IsMiss(err) {
+//		if err := policy.Handle("idempotency", err); err != nil {
+//			response.Error(c, err)
+//			c.Abort()
+//			return
+//		}
+//	}
+func (p *FailurePolicy) Handle(component string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if p.FailsOpen(component) {
+		degradedOperationsTotal.WithLabelValues(component, "open").Inc()
+		return nil
+	}
+
+	degradedOperationsTotal.WithLabelValues(component, "closed").Inc()
+	return apperror.Unavailable("Service temporarily unavailable").WithCause(err)
+}
@@ -0,0 +1,31 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFailurePolicyHandle(t *testing.T) {
+	redisDown := errors.New("dial tcp: connection refused")
+
+	tests := []struct {
+		name       string
+		failClosed []string
+		component  string
+		wantErr    bool
+	}{
+		{"fails open by default", nil, "response_cache", false},
+		{"listed component fails closed", []string{"idempotency"}, "idempotency", true},
+		{"unlisted component fails open", []string{"idempotency"}, "response_cache", false},
+		{"wildcard fails all closed", []string{"*"}, "response_cache", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewFailurePolicy(tt.failClosed).Handle(tt.component, redisDown)
+			if gotErr := err != nil; gotErr != tt.wantErr {
+				t.Errorf("Handle = %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/logger"
+)
+
+// newTestClient returns a client connected to an in-memory Redis server,
+// with the configuration adjusted by configure if set
+func newTestClient(t *testing.T, configure func(*config.Config)) (*Client, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	cfg.RedisURL = ""
+	cfg.RedisClusterAddrs = nil
+	cfg.RedisHost = server.Host()
+	cfg.RedisPort = server.Port()
+	if configure != nil {
+		configure(cfg)
+	}
+
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+	client, err := NewClient(context.Background(), cfg, log)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client, server
+}
+
+// TestNewClientACLAuth checks the client authenticates as REDIS_USERNAME, or
+// as the user in REDIS_URL, against a server requiring ACL users
+func TestNewClientACLAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config, server *miniredis.Miniredis)
+		wantErr   bool
+	}{
+		{
+			name: "username and password",
+			configure: func(cfg *config.Config, _ *miniredis.Miniredis) {
+				cfg.RedisUsername = "svc"
+				cfg.RedisPassword = "s3cret"
+			},
+		},
+		{
+			name: "credentials in the URL",
+			configure: func(cfg *config.Config, server *miniredis.Miniredis) {
+				cfg.RedisURL = "redis://svc:s3cret@" + server.Addr()
+				cfg.RedisUsername = "ignored"
+				cfg.RedisPassword = "ignored"
+			},
+		},
+		{
+			name: "URL without credentials",
+			configure: func(cfg *config.Config, server *miniredis.Miniredis) {
+				cfg.RedisURL = "redis://" + server.Addr()
+				cfg.RedisUsername = "svc"
+				cfg.RedisPassword = "s3cret"
+			},
+		},
+		{
+			name: "wrong password",
+			configure: func(cfg *config.Config, _ *miniredis.Miniredis) {
+				cfg.RedisUsername = "svc"
+				cfg.RedisPassword = "wrong"
+			},
+			wantErr: true,
+		},
+		{
+			name: "password only",
+			configure: func(cfg *config.Config, _ *miniredis.Miniredis) {
+				cfg.RedisPassword = "s3cret"
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			server.RequireUserAuth("svc", "s3cret")
+
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatalf("config.Load: %v", err)
+			}
+			cfg.RedisURL = ""
+			cfg.RedisClusterAddrs = nil
+			cfg.RedisHost = server.Host()
+			cfg.RedisPort = server.Port()
+			tt.configure(cfg, server)
+
+			log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+			client, err := NewClient(context.Background(), cfg, log)
+			if tt.wantErr {
+				if err == nil {
+					client.Close()
+					t.Fatal("NewClient succeeded, want an authentication error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewClient: %v", err)
+			}
+			defer client.Close()
+
+			if err := client.Client().Set(context.Background(), "k", "v", 0).Err(); err != nil {
+				t.Fatalf("SET as svc: %v", err)
+			}
+		})
+	}
+}
+
+func TestGetOptional(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(server *miniredis.Miniredis)
+		wantValue string
+		wantFound bool
+		wantErr   bool
+	}{
+		{"hit", func(s *miniredis.Miniredis) { s.Set("svc:greeting", "hello") }, "hello", true, false},
+		{"hit on empty value", func(s *miniredis.Miniredis) { s.Set("svc:greeting", "") }, "", true, false},
+		{"miss", func(s *miniredis.Miniredis) {}, "", false, false},
+		{"wrong type", func(s *miniredis.Miniredis) { s.Lpush("svc:greeting", "hello") }, "", false, true},
+		{"server error", func(s *miniredis.Miniredis) { s.SetError("LOADING Redis is loading the dataset") }, "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t, func(cfg *config.Config) { cfg.RedisKeyPrefix = "svc:" })
+			tt.setup(server)
+
+			getters := map[string]func() (string, bool, error){
+				"client": func() (string, bool, error) { return client.GetOptional(context.Background(), "greeting") },
+				"scoped": func() (string, bool, error) {
+					return (&Scoped{client: client, ctx: context.Background()}).GetOptional("greeting")
+				},
+			}
+			for via, get := range getters {
+				value, found, err := get()
+				if (err != nil) != tt.wantErr || IsMiss(err) {
+					t.Errorf("%s: err = %v, want error %v and never redis.Nil", via, err, tt.wantErr)
+				}
+				if value != tt.wantValue || found != tt.wantFound {
+					t.Errorf("%s: GetOptional = %q, %v; want %q, %v", via, value, found, tt.wantValue, tt.wantFound)
+				}
+			}
+		})
+	}
+}
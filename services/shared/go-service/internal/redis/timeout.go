@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/reqctx"
+)
+
+// budgetHook bounds every command, or pipeline, to REDIS_OPERATION_TIMEOUT,
+// clamped to the time left before the request's deadline. The budget
+// includes waiting for a pooled connection and retries, unlike
+// REDIS_READ_TIMEOUT, which bounds a single socket read. Blocking commands
+// such as BLPOP are cut off at the budget too.
+type budgetHook struct {
+	timeout time.Duration
+}
+
+func (h budgetHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h budgetHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		ctx, cancel := reqctx.WithBudget(ctx, h.timeout)
+		defer cancel()
+		return next(ctx, cmd)
+	}
+}
+
+func (h budgetHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		ctx, cancel := reqctx.WithBudget(ctx, h.timeout)
+		defer cancel()
+		return next(ctx, cmds)
+	}
+}
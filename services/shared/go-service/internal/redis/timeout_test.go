@@ -0,0 +1,221 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+
+	"{{ module_name }}/internal/config"
+	"{{ module_name }}/internal/middleware"
+)
+
+func TestBudgetHook(t *testing.T) {
+	hook := budgetHook{timeout: time.Minute}
+
+	tests := []struct {
+		name   string
+		parent time.Duration // zero for no request deadline
+		want   time.Duration
+	}{
+		{"clamped to the request deadline", 2 * time.Second, 2 * time.Second},
+		{"operation timeout without a request deadline", 0, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.parent > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.parent)
+				defer cancel()
+			}
+
+			check := func(ctx context.Context) {
+				deadline, ok := ctx.Deadline()
+				if remaining := time.Until(deadline); !ok || remaining > tt.want || remaining < tt.want-time.Second {
+					t.Errorf("command budget = %s, want %s", remaining, tt.want)
+				}
+			}
+
+			process := hook.ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+				check(ctx)
+				return nil
+			})
+			_ = process(ctx, redis.NewStringCmd(ctx, "get", "key"))
+
+			pipeline := hook.ProcessPipelineHook(func(ctx context.Context, cmds []redis.Cmder) error {
+				check(ctx)
+				return nil
+			})
+			_ = pipeline(ctx, nil)
+		})
+	}
+}
+
+func TestClientTimeoutsFromConfig(t *testing.T) {
+	setTimeouts := func(cfg *config.Config) {
+		cfg.RedisDialTimeout = 1100 * time.Millisecond
+		cfg.RedisReadTimeout = 1200 * time.Millisecond
+		cfg.RedisWriteTimeout = 1300 * time.Millisecond
+	}
+
+	tests := []struct {
+		name      string
+		url       func(server *miniredis.Miniredis) string
+		wantRead  time.Duration
+		wantWrite time.Duration
+	}{
+		{"host and port", nil, 1200 * time.Millisecond, 1300 * time.Millisecond},
+		{"URL", func(s *miniredis.Miniredis) string { return "redis://" + s.Addr() }, 1200 * time.Millisecond, 1300 * time.Millisecond},
+		{"URL timeouts win", func(s *miniredis.Miniredis) string {
+			return "redis://" + s.Addr() + "?read_timeout=2s&write_timeout=3s"
+		}, 2 * time.Second, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := miniredis.RunT(t)
+			client, _ := newTestClient(t, func(cfg *config.Config) {
+				setTimeouts(cfg)
+				cfg.RedisHost = server.Host()
+				cfg.RedisPort = server.Port()
+				if tt.url != nil {
+					cfg.RedisURL = tt.url(server)
+				}
+			})
+
+			opts := client.Client().(*redis.Client).Options()
+			if opts.DialTimeout != 1100*time.Millisecond || opts.ReadTimeout != tt.wantRead || opts.WriteTimeout != tt.wantWrite {
+				t.Errorf("dial, read, write timeouts = %v, %v, %v, want 1.1s, %v, %v",
+					opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout, tt.wantRead, tt.wantWrite)
+			}
+			if !opts.ContextTimeoutEnabled {
+				t.Error("ContextTimeoutEnabled = false, want socket deadlines to follow the context")
+			}
+		})
+	}
+}
+
+// slowProxy forwards connections to addr, delaying every reply by the
+// current delay
+type slowProxy struct {
+	addr  string
+	delay atomic.Int64
+}
+
+func startSlowProxy(t *testing.T, addr string) (*slowProxy, string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	p := &slowProxy{addr: addr}
+	go func() {
+		for {
+			client, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(client)
+		}
+	}()
+	return p, listener.Addr().String()
+}
+
+func (p *slowProxy) serve(client net.Conn) {
+	defer client.Close()
+	server, err := net.Dial("tcp", p.addr)
+	if err != nil {
+		return
+	}
+	defer server.Close()
+
+	go func() { _, _ = io.Copy(server, client) }()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := server.Read(buf)
+		if err != nil {
+			return
+		}
+		time.Sleep(time.Duration(p.delay.Load()))
+		if _, err := client.Write(buf[:n]); err != nil {
+			return
+		}
+	}
+}
+
+func TestReadTimeoutAgainstSlowServer(t *testing.T) {
+	server := miniredis.RunT(t)
+	proxy, addr := startSlowProxy(t, server.Addr())
+	host, port, _ := net.SplitHostPort(addr)
+
+	const readTimeout = 100 * time.Millisecond
+	client, _ := newTestClient(t, func(cfg *config.Config) {
+		cfg.RedisHost = host
+		cfg.RedisPort = port
+		cfg.RedisReadTimeout = readTimeout
+		cfg.RedisOperationTimeout = 10 * time.Second
+	})
+
+	proxy.delay.Store(int64(2 * time.Second))
+	start := time.Now()
+	_, err := client.Get(context.Background(), "key")
+	elapsed := time.Since(start)
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Fatalf("Get = %v, want a read timeout", err)
+	}
+	// Retries may add a few read timeouts, but not the server's delay
+	if elapsed >= time.Second {
+		t.Errorf("Get returned after %v, want it cut off by the %v read timeout", elapsed, readTimeout)
+	}
+}
+
+func TestRequestTimeoutCancelsCommand(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := miniredis.RunT(t)
+	proxy, addr := startSlowProxy(t, server.Addr())
+	host, port, _ := net.SplitHostPort(addr)
+	client, _ := newTestClient(t, func(cfg *config.Config) {
+		cfg.RedisHost = host
+		cfg.RedisPort = port
+		cfg.RedisReadTimeout = 10 * time.Second
+		cfg.RedisOperationTimeout = 10 * time.Second
+	})
+	proxy.delay.Store(int64(2 * time.Second))
+
+	const budget = 100 * time.Millisecond
+	var getErr error
+	router := gin.New()
+	router.Use(Bind(client), middleware.RequestTimeout(budget))
+	router.GET("/slow", func(c *gin.Context) {
+		_, getErr = For(c).Get("key")
+		c.Status(http.StatusOK)
+	})
+
+	start := time.Now()
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	elapsed := time.Since(start)
+
+	if !errors.Is(getErr, context.DeadlineExceeded) {
+		var netErr net.Error
+		if !errors.As(getErr, &netErr) || !netErr.Timeout() {
+			t.Errorf("Get = %v, want it stopped by the request deadline", getErr)
+		}
+	}
+	if elapsed >= time.Second {
+		t.Errorf("request took %v, want the command stopped at the %v budget", elapsed, budget)
+	}
+}
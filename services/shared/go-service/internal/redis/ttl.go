@@ -0,0 +1,24 @@
+package redis
+
+import (
+	"math/rand"
+	"time"
+)
+
+// jitter randomly spreads expiration by up to ±ttlJitter of its length, so
+// entries written together don't all expire (and get recomputed) at once.
+// Expirations of zero or less, meaning no expiry or keep TTL, are unchanged.
+func (c *Client) jitter(expiration time.Duration) time.Duration {
+	if c.ttlJitter <= 0 || expiration <= 0 {
+		return expiration
+	}
+
+	spread := float64(expiration) * c.ttlJitter
+	jittered := time.Duration(float64(expiration) + (rand.Float64()*2-1)*spread)
+
+	// Redis expirations have millisecond precision
+	if jittered < time.Millisecond {
+		return time.Millisecond
+	}
+	return jittered
+}
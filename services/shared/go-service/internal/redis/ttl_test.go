@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"{{ module_name }}/internal/config"
+)
+
+func TestJitterWithinBounds(t *testing.T) {
+	c := &Client{ttlJitter: 0.1}
+	expiration := time.Hour
+	low, high := 54*time.Minute, 66*time.Minute
+
+	var spread bool
+	for i := 0; i < 1000; i++ {
+		got := c.jitter(expiration)
+		if got < low || got > high {
+			t.Fatalf("jitter(%v) = %v, want within [%v, %v]", expiration, got, low, high)
+		}
+		if got != expiration {
+			spread = true
+		}
+	}
+	if !spread {
+		t.Errorf("jitter(%v) never changed the expiration", expiration)
+	}
+}
+
+func TestJitterPassesThrough(t *testing.T) {
+	tests := []struct {
+		name       string
+		ttlJitter  float64
+		expiration time.Duration
+	}{
+		{"no expiry", 0.5, 0},
+		{"keep TTL", 0.5, -1},
+		{"negative", 0.5, -time.Second},
+		{"jitter disabled", 0, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Client{ttlJitter: tt.ttlJitter}
+			if got := c.jitter(tt.expiration); got != tt.expiration {
+				t.Errorf("jitter(%v) = %v, want it unchanged", tt.expiration, got)
+			}
+		})
+	}
+}
+
+func TestSetAppliesJitter(t *testing.T) {
+	client, server := newTestClient(t, func(cfg *config.Config) { cfg.RedisTTLJitter = 0.2 })
+	ctx := context.Background()
+
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		if err := client.Set(ctx, key, "v", 100*time.Second); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+		if err := client.SetJSON(ctx, key+"-json", map[string]string{"k": "v"}, 100*time.Second); err != nil {
+			t.Fatalf("SetJSON: %v", err)
+		}
+		for _, k := range []string{key, key + "-json"} {
+			ttl := server.TTL(client.key(k))
+			if ttl < 80*time.Second || ttl > 120*time.Second {
+				t.Errorf("TTL of %q = %v, want within 100s ±20%%", k, ttl)
+			}
+		}
+	}
+}
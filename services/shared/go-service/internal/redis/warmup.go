@@ -0,0 +1,33 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/errgroup"
+)
+
+// WarmUp opens about n pool connections up front, to every master in
+// cluster mode, by sending n concurrent pings, so the first requests don't
+// wait for connections to be established
+func (c *Client) WarmUp(ctx context.Context, n int) error {
+	warm := func(ctx context.Context, node *redis.Client) error {
+		group, ctx := errgroup.WithContext(ctx)
+		for i := 0; i < n; i++ {
+			group.Go(func() error {
+				return node.Ping(ctx).Err()
+			})
+		}
+		return group.Wait()
+	}
+
+	switch client := c.client.(type) {
+	case *redis.ClusterClient:
+		return client.ForEachMaster(ctx, warm)
+	case *redis.Client:
+		return warm(ctx, client)
+	default:
+		return fmt.Errorf("unsupported Redis client %T", client)
+	}
+}
@@ -0,0 +1,33 @@
+package reqctx
+
+import (
+	"context"
+	"time"
+)
+
+// WithBudget derives the context for one operation within a request, such as
+// a query or an outbound call. It expires after budget or at ctx's deadline,
+// whichever comes first, so an operation never outlives the request that
+// started it. A budget <= 0 only inherits ctx's deadline.
+//
+//	ctx, cancel := reqctx.WithBudget(c.Request.Context(), 5*time.Second)
+//	defer cancel()
+func WithBudget(ctx context.Context, budget time.Duration) (context.Context, context.CancelFunc) {
+	if budget <= 0 {
+		return context.WithCancel(ctx)
+	}
+	if remaining, ok := Remaining(ctx); ok && remaining < budget {
+		budget = remaining
+	}
+	return context.WithTimeout(ctx, budget)
+}
+
+// Remaining returns the time left before ctx's deadline, and false if ctx
+// has none
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
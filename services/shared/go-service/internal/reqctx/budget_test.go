@@ -0,0 +1,71 @@
+package reqctx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// deadlineWithin reports whether ctx's deadline is within tolerance of
+// want from now
+func deadlineWithin(ctx context.Context, want time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false
+	}
+	got := time.Until(deadline)
+	return got <= want && got > want-time.Second
+}
+
+func TestWithBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent time.Duration // zero for no parent deadline
+		budget time.Duration
+		want   time.Duration // zero for no deadline
+	}{
+		{"clamped to the parent deadline", 2 * time.Second, time.Minute, 2 * time.Second},
+		{"shorter than the parent deadline", time.Minute, 2 * time.Second, 2 * time.Second},
+		{"no parent deadline", 0, 2 * time.Second, 2 * time.Second},
+		{"no budget inherits the parent deadline", 2 * time.Second, 0, 2 * time.Second},
+		{"neither", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := context.Background()
+			if tt.parent > 0 {
+				var cancel context.CancelFunc
+				parent, cancel = context.WithTimeout(parent, tt.parent)
+				defer cancel()
+			}
+
+			ctx, cancel := WithBudget(parent, tt.budget)
+			defer cancel()
+
+			if tt.want == 0 {
+				if _, ok := ctx.Deadline(); ok {
+					t.Error("budget has a deadline, want none")
+				}
+				return
+			}
+			if !deadlineWithin(ctx, tt.want) {
+				deadline, _ := ctx.Deadline()
+				t.Errorf("budget expires in %s, want %s", time.Until(deadline), tt.want)
+			}
+		})
+	}
+}
+
+func TestWithBudgetCancelledWithParent(t *testing.T) {
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithBudget(parent, time.Minute)
+	defer cancel()
+
+	cancelParent()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Error("budget outlived its cancelled parent")
+	}
+}
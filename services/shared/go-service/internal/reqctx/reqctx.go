@@ -0,0 +1,60 @@
+package reqctx
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	debugSQLKey
+	tenantIDKey
+	traceHeadersKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request ID stored in ctx, or "" if there is none
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+// WithDebugSQL returns a copy of ctx flagged for verbose SQL logging
+func WithDebugSQL(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugSQLKey, true)
+}
+
+// DebugSQL reports whether ctx is flagged for verbose SQL logging
+func DebugSQL(ctx context.Context) bool {
+	debug, _ := ctx.Value(debugSQLKey).(bool)
+	return debug
+}
+
+// WithTenantID returns a copy of ctx carrying the tenant ID
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// TenantID returns the tenant ID stored in ctx, or "" if there is none
+func TenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDKey).(string)
+	return tenantID
+}
+
+// WithTraceHeaders returns a copy of ctx carrying the incoming trace context
+// headers, to be forwarded on outbound calls
+func WithTraceHeaders(ctx context.Context, headers http.Header) context.Context {
+	return context.WithValue(ctx, traceHeadersKey, headers)
+}
+
+// TraceHeaders returns the trace context headers stored in ctx, or nil
+func TraceHeaders(ctx context.Context) http.Header {
+	headers, _ := ctx.Value(traceHeadersKey).(http.Header)
+	return headers
+}
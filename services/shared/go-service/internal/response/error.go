@@ -0,0 +1,40 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/reqctx"
+)
+
+// Error writes err using the standard error envelope, including the request
+// ID for correlation in the body and the X-Request-ID header, and the tenant
+// of the request if any. Errors that aren't an *apperror.Error are reported
+// as internal errors without leaking details.
+func Error(c *gin.Context, err error) {
+	appErr := apperror.From(err)
+
+	body := gin.H{
+		"error": appErr.Message,
+		"code":  appErr.Code,
+	}
+	if appErr.Details != nil {
+		body["details"] = appErr.Details
+	}
+	if requestID := requestID(c); requestID != "" {
+		body["request_id"] = requestID
+		c.Header("X-Request-ID", requestID)
+	}
+	if tenantID := reqctx.TenantID(c.Request.Context()); tenantID != "" {
+		body["tenant_id"] = tenantID
+	}
+
+	JSON(c, appErr.Status, body)
+}
+
+func requestID(c *gin.Context) string {
+	if requestID := c.GetString("request_id"); requestID != "" {
+		return requestID
+	}
+	return reqctx.RequestID(c.Request.Context())
+}
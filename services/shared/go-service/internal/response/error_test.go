@@ -0,0 +1,87 @@
+package response
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"{{ module_name }}/internal/apperror"
+	"{{ module_name }}/internal/reqctx"
+)
+
+func TestError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		err       func(ctx context.Context) error
+		status    int
+		want      map[string]string
+		requestID string
+	}{
+		{
+			name: "wrapped application error",
+			ctx:  reqctx.WithTenantID(reqctx.WithRequestID(context.Background(), "req-1"), "acme"),
+			err: func(ctx context.Context) error {
+				return apperror.Wrap(ctx, "handlers.Register", apperror.Conflict("Email already registered"))
+			},
+			status:    http.StatusConflict,
+			want:      map[string]string{"error": "Email already registered", "code": "conflict", "request_id": "req-1", "tenant_id": "acme"},
+			requestID: "req-1",
+		},
+		{
+			name: "unknown error",
+			ctx:  reqctx.WithRequestID(context.Background(), "req-2"),
+			err: func(ctx context.Context) error {
+				return errors.New("pq: connection reset")
+			},
+			status:    http.StatusInternalServerError,
+			want:      map[string]string{"error": "Internal server error", "code": "internal", "request_id": "req-2"},
+			requestID: "req-2",
+		},
+		{
+			name: "no request ID or tenant",
+			ctx:  context.Background(),
+			err: func(ctx context.Context) error {
+				return apperror.NotFound("Route not found")
+			},
+			status: http.StatusNotFound,
+			want:   map[string]string{"error": "Route not found", "code": "not_found"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/", nil).WithContext(tt.ctx)
+
+			Error(c, tt.err(tt.ctx))
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			var body map[string]string
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("body %s: %v", w.Body, err)
+			}
+			if len(body) != len(tt.want) {
+				t.Errorf("body = %v, want %v", body, tt.want)
+			}
+			for key, want := range tt.want {
+				if body[key] != want {
+					t.Errorf("%s = %q, want %q", key, body[key], want)
+				}
+			}
+			if got := w.Header().Get("X-Request-ID"); got != tt.requestID {
+				t.Errorf("X-Request-ID = %q, want %q", got, tt.requestID)
+			}
+		})
+	}
+}
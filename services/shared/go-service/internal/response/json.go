@@ -0,0 +1,297 @@
+package response
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Options controls how response bodies are serialized.
+type Options struct {
+	// SnakeCase converts the names of struct fields without a json tag to
+	// snake_case. Fields with an explicit json tag keep the tagged name.
+	SnakeCase bool
+	// OmitEmpty omits zero-valued struct fields without a json tag, as if
+	// they were tagged with omitempty. Tagged fields keep their own options.
+	OmitEmpty bool
+	// TimeFormat controls how time.Time values are written: "rfc3339" (the
+	// default), "unix" for epoch seconds, "unix_ms" for epoch milliseconds,
+	// or a Go time layout such as time.RFC1123.
+	TimeFormat string
+}
+
+var current atomic.Pointer[Options]
+
+// Configure sets the global serialization options used by JSON.
+func Configure(opts Options) {
+	current.Store(&opts)
+}
+
+func options() Options {
+	if opts := current.Load(); opts != nil {
+		return *opts
+	}
+	return Options{}
+}
+
+// JSON writes obj as the response body using the global serialization
+// options. With no options enabled it behaves exactly like c.JSON.
+func JSON(c *gin.Context, code int, obj interface{}) {
+	opts := options()
+	if !opts.SnakeCase && !opts.OmitEmpty && !opts.customTime() {
+		c.JSON(code, obj)
+		return
+	}
+	c.Render(code, jsonRender{data: obj, opts: opts})
+}
+
+// Marshal encodes v as JSON applying opts.
+func Marshal(v interface{}, opts Options) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, reflect.ValueOf(v), opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type jsonRender struct {
+	data interface{}
+	opts Options
+}
+
+func (r jsonRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := Marshal(r.data, r.opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (r jsonRender) WriteContentType(w http.ResponseWriter) {
+	header := w.Header()
+	if val := header["Content-Type"]; len(val) == 0 {
+		header["Content-Type"] = []string{"application/json; charset=utf-8"}
+	}
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+func encode(buf *bytes.Buffer, v reflect.Value, opts Options) error {
+	if !v.IsValid() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	if opts.customTime() {
+		if v.Kind() == reflect.Pointer && v.Type().Elem() == timeType {
+			if v.IsNil() {
+				buf.WriteString("null")
+				return nil
+			}
+			v = v.Elem()
+		}
+		if v.Type() == timeType {
+			return encodeTime(buf, v.Interface().(time.Time), opts.TimeFormat)
+		}
+	}
+
+	// Types with their own encoding (time.Time, json.RawMessage, ...) are
+	// delegated to encoding/json untouched.
+	if v.Type().Implements(jsonMarshalerType) || v.Type().Implements(textMarshalerType) {
+		return encodeStd(buf, v)
+	}
+
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		return encode(buf, v.Elem(), opts)
+	case reflect.Struct:
+		return encodeStruct(buf, v, opts)
+	case reflect.Map:
+		return encodeMap(buf, v, opts)
+	case reflect.Slice:
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return encodeStd(buf, v)
+		}
+		fallthrough
+	case reflect.Array:
+		buf.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := encode(buf, v.Index(i), opts); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	default:
+		return encodeStd(buf, v)
+	}
+}
+
+func encodeStd(buf *bytes.Buffer, v reflect.Value) error {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
+
+func encodeStruct(buf *bytes.Buffer, v reflect.Value, opts Options) error {
+	buf.WriteByte('{')
+	first := true
+	if err := encodeFields(buf, v, opts, &first); err != nil {
+		return err
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func encodeFields(buf *bytes.Buffer, v reflect.Value, opts Options, first *bool) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag, hasTag := field.Tag.Lookup("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, tagOpts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+
+		// Untagged embedded structs are flattened like encoding/json does.
+		if field.Anonymous && name == "" {
+			ev := fv
+			if ev.Kind() == reflect.Pointer {
+				if ev.IsNil() {
+					continue
+				}
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct {
+				if err := encodeFields(buf, ev, opts, first); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		omitEmpty := strings.Contains(tagOpts, "omitempty")
+		if !hasTag {
+			omitEmpty = opts.OmitEmpty
+		}
+		if omitEmpty && fv.IsZero() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+			if opts.SnakeCase {
+				name = SnakeCase(name)
+			}
+		}
+
+		if !*first {
+			buf.WriteByte(',')
+		}
+		*first = false
+
+		key, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		if strings.Contains(tagOpts, "string") {
+			if err := encodeQuoted(buf, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := encode(buf, fv, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeQuoted encodes a field tagged with the ",string" option as a JSON
+// string, following pointers like encoding/json does. A nil pointer is null.
+func encodeQuoted(buf *bytes.Buffer, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			buf.WriteString("null")
+			return nil
+		}
+		v = v.Elem()
+	}
+	return encodeStd(buf, reflect.ValueOf(fmt.Sprint(v.Interface())))
+}
+
+func encodeMap(buf *bytes.Buffer, v reflect.Value, opts Options) error {
+	if v.IsNil() {
+		buf.WriteString("null")
+		return nil
+	}
+
+	// Encode values individually and let encoding/json order the keys.
+	entries := make(map[string]json.RawMessage, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		var elem bytes.Buffer
+		if err := encode(&elem, iter.Value(), opts); err != nil {
+			return err
+		}
+		entries[fmt.Sprint(iter.Key().Interface())] = elem.Bytes()
+	}
+	return encodeStd(buf, reflect.ValueOf(entries))
+}
+
+// SnakeCase converts a Go identifier such as "UserID" to "user_id".
+func SnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				prev := runes[i-1]
+				nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+				if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+					b.WriteByte('_')
+				}
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,22 @@
+package response
+
+import "testing"
+
+func TestMarshalStringOption(t *testing.T) {
+	id := int64(42)
+	type payload struct {
+		ID    int64  `json:"id,string"`
+		Ref   *int64 `json:"ref,string"`
+		Empty *int64 `json:"empty,string"`
+	}
+
+	got, err := Marshal(payload{ID: 7, Ref: &id}, Options{SnakeCase: true})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	want := `{"id":"7","ref":"42","empty":null}`
+	if string(got) != want {
+		t.Errorf("Marshal = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,69 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Time formats accepted by Options.TimeFormat besides a Go time layout
+const (
+	TimeFormatRFC3339 = "rfc3339"
+	TimeFormatUnix    = "unix"
+	TimeFormatUnixMs  = "unix_ms"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// customTime reports whether times need encoding other than encoding/json's
+// default RFC 3339
+func (o Options) customTime() bool {
+	return o.TimeFormat != "" && o.TimeFormat != TimeFormatRFC3339
+}
+
+// FormatTime converts t to its JSON value in format: a number of seconds or
+// milliseconds since the epoch, or a string otherwise.
+func FormatTime(t time.Time, format string) interface{} {
+	switch format {
+	case "", TimeFormatRFC3339:
+		return t.Format(time.RFC3339Nano)
+	case TimeFormatUnix:
+		return t.Unix()
+	case TimeFormatUnixMs:
+		return t.UnixMilli()
+	default:
+		return t.Format(format)
+	}
+}
+
+// ParseTime parses a timestamp sent by a client in format, so values
+// returned in responses can be sent back unchanged.
+func ParseTime(value string, format string) (time.Time, error) {
+	switch format {
+	case "", TimeFormatRFC3339:
+		return time.Parse(time.RFC3339Nano, value)
+	case TimeFormatUnix, TimeFormatUnixMs:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s timestamp %q: %w", format, value, err)
+		}
+		if format == TimeFormatUnix {
+			return time.Unix(n, 0), nil
+		}
+		return time.UnixMilli(n), nil
+	default:
+		return time.Parse(format, value)
+	}
+}
+
+func encodeTime(buf *bytes.Buffer, t time.Time, format string) error {
+	b, err := json.Marshal(FormatTime(t, format))
+	if err != nil {
+		return err
+	}
+	buf.Write(b)
+	return nil
+}
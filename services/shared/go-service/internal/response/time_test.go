@@ -0,0 +1,76 @@
+package response
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeFormatRoundTrip(t *testing.T) {
+	at := time.Date(2026, time.October, 16, 12, 34, 56, 789123456, time.FixedZone("CEST", 2*60*60))
+
+	tests := []struct {
+		format  string
+		encoded string
+		want    time.Time
+	}{
+		{"", `"2026-10-16T12:34:56.789123456+02:00"`, at},
+		{TimeFormatRFC3339, `"2026-10-16T12:34:56.789123456+02:00"`, at},
+		{TimeFormatUnix, `1792146896`, at.Truncate(time.Second)},
+		{TimeFormatUnixMs, `1792146896789`, at.Truncate(time.Millisecond)},
+		{time.RFC1123Z, `"Fri, 16 Oct 2026 12:34:56 +0200"`, at.Truncate(time.Second)},
+	}
+
+	for _, tt := range tests {
+		name := tt.format
+		if name == "" {
+			name = "default"
+		}
+		t.Run(name, func(t *testing.T) {
+			body, err := Marshal(struct {
+				At time.Time `json:"at"`
+			}{at}, Options{TimeFormat: tt.format})
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			var decoded struct {
+				At json.RawMessage `json:"at"`
+			}
+			if err := json.Unmarshal(body, &decoded); err != nil {
+				t.Fatalf("Unmarshal %s: %v", body, err)
+			}
+			if string(decoded.At) != tt.encoded {
+				t.Errorf("encoded = %s, want %s", decoded.At, tt.encoded)
+			}
+
+			// Clients send the value back as it appeared in the response
+			value := strings.Trim(string(decoded.At), `"`)
+			parsed, err := ParseTime(value, tt.format)
+			if err != nil {
+				t.Fatalf("ParseTime(%q): %v", value, err)
+			}
+			if !parsed.Equal(tt.want) {
+				t.Errorf("ParseTime(%q) = %v, want %v", value, parsed, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeInvalid(t *testing.T) {
+	tests := []struct {
+		value  string
+		format string
+	}{
+		{"yesterday", TimeFormatRFC3339},
+		{"1792146896.5", TimeFormatUnix},
+		{"", TimeFormatUnixMs},
+		{"2026-10-16", time.RFC1123},
+	}
+	for _, tt := range tests {
+		if got, err := ParseTime(tt.value, tt.format); err == nil {
+			t.Errorf("ParseTime(%q, %q) = %v, want an error", tt.value, tt.format, got)
+		}
+	}
+}
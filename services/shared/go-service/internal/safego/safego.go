@@ -0,0 +1,109 @@
+package safego
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"{{ module_name }}/internal/logger"
+)
+
+const (
+	initialRestartDelay = time.Second
+	maxRestartDelay     = time.Minute
+)
+
+// Reporter receives recovered panics, e.g. to forward them to an error tracker
+type Reporter func(name string, recovered interface{}, stack []byte)
+
+var (
+	mu       sync.RWMutex
+	reporter Reporter
+)
+
+// SetReporter sets the function every recovered panic is reported to
+func SetReporter(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	reporter = r
+}
+
+// PanicError is returned by Run when fn panics
+type PanicError struct {
+	Name      string
+	Recovered interface{}
+	Stack     []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%s panicked: %v", e.Name, e.Recovered)
+}
+
+// Go runs fn in a new goroutine. A panic in fn is recovered, logged with its
+// stack and reported instead of crashing the process.
+func Go(log logger.Logger, name string, fn func()) {
+	go func() {
+		_ = Run(log, name, func() error {
+			fn()
+			return nil
+		})
+	}()
+}
+
+// Run calls fn, turning a panic into a *PanicError after logging and
+// reporting it
+func Run(log logger.Logger, name string, fn func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			panicErr := &PanicError{Name: name, Recovered: recovered, Stack: debug.Stack()}
+			log.WithField("stack", string(panicErr.Stack)).Errorf("Recovered panic in %s: %v", name, recovered)
+			report(panicErr)
+			err = panicErr
+		}
+	}()
+	return fn()
+}
+
+// Loop runs a long-lived fn in a new goroutine, restarting it with
+// exponential backoff (1s up to 1m) whenever it panics, until ctx is
+// cancelled. Loop stops once fn returns normally.
+func Loop(ctx context.Context, log logger.Logger, name string, fn func(ctx context.Context)) {
+	go func() {
+		delay := initialRestartDelay
+		for {
+			start := time.Now()
+			err := Run(log, name, func() error {
+				fn(ctx)
+				return nil
+			})
+			if err == nil || ctx.Err() != nil {
+				return
+			}
+
+			// A loop that ran healthily for a while starts over with a short delay
+			if time.Since(start) > maxRestartDelay {
+				delay = initialRestartDelay
+			}
+
+			log.Warnf("Restarting %s in %s", name, delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+			delay = min(delay*2, maxRestartDelay)
+		}
+	}()
+}
+
+func report(err *PanicError) {
+	mu.RLock()
+	r := reporter
+	mu.RUnlock()
+
+	if r != nil {
+		r(err.Name, err.Recovered, err.Stack)
+	}
+}
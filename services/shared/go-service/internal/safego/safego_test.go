@@ -0,0 +1,82 @@
+package safego
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/logger"
+)
+
+// syncBuffer is a bytes.Buffer safe for the logger and the test to share
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestGoRecoversPanic(t *testing.T) {
+	var logs syncBuffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&logs) })
+
+	reported := make(chan *PanicError, 1)
+	SetReporter(func(name string, recovered interface{}, stack []byte) {
+		reported <- &PanicError{Name: name, Recovered: recovered, Stack: stack}
+	})
+	defer SetReporter(nil)
+
+	Go(log, "worker", func() {
+		panic("boom")
+	})
+
+	select {
+	case err := <-reported:
+		if err.Name != "worker" || err.Recovered != "boom" {
+			t.Errorf("reported %s: %v, want worker: boom", err.Name, err.Recovered)
+		}
+		if !bytes.Contains(err.Stack, []byte("safego_test.go")) {
+			t.Errorf("stack doesn't point at the panic:\n%s", err.Stack)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("panic wasn't reported")
+	}
+
+	out := logs.String()
+	for _, want := range []string{"Recovered panic in worker: boom", "stack"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log doesn't contain %q: %s", want, out)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	var logs syncBuffer
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(&logs) })
+
+	err := Run(log, "task", func() error { panic("boom") })
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Error() != "task panicked: boom" {
+		t.Errorf("err = %v, want a *PanicError", err)
+	}
+
+	failed := errors.New("failed")
+	if err := Run(log, "task", func() error { return failed }); err != failed {
+		t.Errorf("err = %v, want fn's error returned as is", err)
+	}
+}
@@ -0,0 +1,82 @@
+package webhooks
+
+import (
+	"sync"
+	"time"
+)
+
+// Attempt records a single delivery attempt of an event to an endpoint
+type Attempt struct {
+	EndpointID string        `json:"endpoint_id"`
+	EventID    string        `json:"event_id"`
+	Number     int           `json:"number"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// DeadLetter is an event that could not be delivered after all attempts
+type DeadLetter struct {
+	EndpointID string    `json:"endpoint_id"`
+	Event      Event     `json:"event"`
+	Attempts   int       `json:"attempts"`
+	LastError  string    `json:"last_error"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// Store persists delivery attempts and dead-lettered events
+type Store interface {
+	RecordAttempt(attempt Attempt)
+	RecordDeadLetter(dead DeadLetter)
+}
+
+// MemoryStore keeps the most recent attempts and dead letters in memory
+type MemoryStore struct {
+	mu          sync.RWMutex
+	limit       int
+	attempts    []Attempt
+	deadLetters []DeadLetter
+}
+
+// NewMemoryStore creates a store retaining at most limit entries of each kind
+func NewMemoryStore(limit int) *MemoryStore {
+	if limit <= 0 {
+		limit = 1000
+	}
+	return &MemoryStore{limit: limit}
+}
+
+func (s *MemoryStore) RecordAttempt(attempt Attempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts = appendBounded(s.attempts, attempt, s.limit)
+}
+
+func (s *MemoryStore) RecordDeadLetter(dead DeadLetter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deadLetters = appendBounded(s.deadLetters, dead, s.limit)
+}
+
+// Attempts returns the recorded attempts, oldest first
+func (s *MemoryStore) Attempts() []Attempt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Attempt(nil), s.attempts...)
+}
+
+// DeadLetters returns the dead-lettered events, oldest first
+func (s *MemoryStore) DeadLetters() []DeadLetter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]DeadLetter(nil), s.deadLetters...)
+}
+
+func appendBounded[T any](items []T, item T, limit int) []T {
+	items = append(items, item)
+	if len(items) > limit {
+		items = items[len(items)-limit:]
+	}
+	return items
+}
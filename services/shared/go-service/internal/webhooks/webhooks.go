@@ -0,0 +1,339 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"{{ module_name }}/internal/logger"
+	"{{ module_name }}/internal/metrics"
+	"{{ module_name }}/internal/safego"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEventID   = "X-Webhook-ID"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var deliveryAttemptsTotal = metrics.Register(nil, prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "webhook_delivery_attempts_total",
+		Help: "The total number of webhook delivery attempts",
+	},
+	[]string{"endpoint", "result"},
+))
+
+// ErrUnknownEndpoint is returned when delivering to an unregistered endpoint
+var ErrUnknownEndpoint = errors.New("unknown webhook endpoint")
+
+// ErrClosed is returned by Dispatch after Close
+var ErrClosed = errors.New("webhook dispatcher closed")
+
+// Endpoint is a registered webhook receiver
+type Endpoint struct {
+	ID     string
+	URL    string
+	Secret string
+	// MaxConcurrency limits in-flight deliveries to this endpoint. Zero uses
+	// Options.MaxConcurrency.
+	MaxConcurrency int
+}
+
+// Event is a payload delivered to every registered endpoint
+type Event struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Payload   interface{} `json:"payload"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Options configures delivery behaviour
+type Options struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Timeout        time.Duration
+	MaxConcurrency int
+}
+
+type endpointState struct {
+	Endpoint
+	slots chan struct{}
+}
+
+// Dispatcher delivers events to registered endpoints with HMAC signatures,
+// exponential-backoff retries and dead-lettering.
+type Dispatcher struct {
+	opts   Options
+	client *http.Client
+	store  Store
+	logger logger.Logger
+
+	mu        sync.RWMutex
+	endpoints map[string]*endpointState
+	// closed is set by Close, under mu, so no delivery is added to wg
+	// while Close waits on it
+	closed bool
+	wg     sync.WaitGroup
+	// closing is closed by Close to stop deliveries waiting to retry
+	closing chan struct{}
+}
+
+// NewDispatcher creates a dispatcher recording attempts in store
+func NewDispatcher(opts Options, store Store, log logger.Logger) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = time.Second
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxConcurrency <= 0 {
+		opts.MaxConcurrency = 4
+	}
+
+	return &Dispatcher{
+		opts:      opts,
+		client:    &http.Client{Timeout: opts.Timeout},
+		store:     store,
+		logger:    log,
+		endpoints: make(map[string]*endpointState),
+		closing:   make(chan struct{}),
+	}
+}
+
+// Register adds or replaces an endpoint
+func (d *Dispatcher) Register(endpoint Endpoint) {
+	concurrency := endpoint.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = d.opts.MaxConcurrency
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.endpoints[endpoint.ID] = &endpointState{
+		Endpoint: endpoint,
+		slots:    make(chan struct{}, concurrency),
+	}
+}
+
+// Unregister removes an endpoint
+func (d *Dispatcher) Unregister(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.endpoints, id)
+}
+
+// Dispatch delivers event to every registered endpoint in the background.
+// Deliveries outlive ctx cancellation (e.g. the originating request ending)
+// but keep its values. After Close it returns ErrClosed.
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) error {
+	ctx = context.WithoutCancel(ctx)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+
+	for id := range d.endpoints {
+		id := id
+		d.wg.Add(1)
+		safego.Go(d.logger, "webhook delivery to "+id, func() {
+			defer d.wg.Done()
+			if err := d.Deliver(ctx, id, event); err != nil {
+				d.logger.Warnf("Webhook delivery of event %s to %s failed: %v", event.ID, id, err)
+			}
+		})
+	}
+	return nil
+}
+
+// Deliver sends event to a single endpoint, retrying with exponential backoff
+// until it succeeds or MaxAttempts is exhausted, at which point the event is
+// dead-lettered. A delivery still waiting for a concurrency slot or to retry
+// when the dispatcher is closed is dead-lettered too, with ErrClosed.
+func (d *Dispatcher) Deliver(ctx context.Context, endpointID string, event Event) error {
+	d.mu.RLock()
+	endpoint, ok := d.endpoints[endpointID]
+	d.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEndpoint, endpointID)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", event.ID, err)
+	}
+
+	// Respect the per-endpoint concurrency limit
+	select {
+	case endpoint.slots <- struct{}{}:
+		defer func() { <-endpoint.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-d.closing:
+		return d.deadLetter(endpoint.ID, event, 0, ErrClosed)
+	}
+
+	var (
+		lastErr  error
+		attempts int
+	)
+	for attempts < d.opts.MaxAttempts {
+		attempts++
+
+		retryable, err := d.attempt(ctx, endpoint.Endpoint, event, body, attempts)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable || attempts == d.opts.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(d.backoff(attempts)):
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-d.closing:
+			return d.deadLetter(endpoint.ID, event, attempts, fmt.Errorf("%w before retrying: %w", ErrClosed, lastErr))
+		}
+	}
+
+	return d.deadLetter(endpoint.ID, event, attempts, lastErr)
+}
+
+// deadLetter records event as undeliverable to the endpoint after attempts
+func (d *Dispatcher) deadLetter(endpointID string, event Event, attempts int, lastErr error) error {
+	d.store.RecordDeadLetter(DeadLetter{
+		EndpointID: endpointID,
+		Event:      event,
+		Attempts:   attempts,
+		LastError:  lastErr.Error(),
+		Timestamp:  time.Now(),
+	})
+	deliveryAttemptsTotal.WithLabelValues(endpointID, "dead_letter").Inc()
+	d.logger.Errorf("Webhook event %s dead-lettered for endpoint %s: %v", event.ID, endpointID, lastErr)
+
+	return fmt.Errorf("webhook event %s dead-lettered: %w", event.ID, lastErr)
+}
+
+// attempt performs one delivery and reports whether a failure is retryable
+func (d *Dispatcher) attempt(ctx context.Context, endpoint Endpoint, event Event, body []byte, number int) (bool, error) {
+	start := time.Now()
+	record := Attempt{
+		EndpointID: endpoint.ID,
+		EventID:    event.ID,
+		Number:     number,
+		Timestamp:  start,
+	}
+	defer func() {
+		record.Duration = time.Since(start)
+		d.store.RecordAttempt(record)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		record.Error = err.Error()
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEventID, event.ID)
+	req.Header.Set(HeaderEventType, event.Type)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		record.Error = err.Error()
+		deliveryAttemptsTotal.WithLabelValues(endpoint.ID, "error").Inc()
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	record.StatusCode = resp.StatusCode
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		deliveryAttemptsTotal.WithLabelValues(endpoint.ID, "success").Inc()
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		err = fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+		record.Error = err.Error()
+		deliveryAttemptsTotal.WithLabelValues(endpoint.ID, "retryable").Inc()
+		return true, err
+	default:
+		err = fmt.Errorf("endpoint rejected delivery with status %d", resp.StatusCode)
+		record.Error = err.Error()
+		deliveryAttemptsTotal.WithLabelValues(endpoint.ID, "rejected").Inc()
+		return false, err
+	}
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.opts.InitialBackoff << (attempt - 1)
+	if delay <= 0 || delay > d.opts.MaxBackoff {
+		return d.opts.MaxBackoff
+	}
+	return delay
+}
+
+// Close stops accepting dispatches and waits for in-flight background
+// deliveries to finish or ctx to expire. Deliveries waiting for a
+// concurrency slot or to retry stop waiting and are dead-lettered, so Close
+// only waits on attempts in progress.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.closing)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sign computes the signature header value for a delivery. Receivers verify
+// it by recomputing HMAC-SHA256 over "<timestamp>.<body>" with the shared
+// secret and comparing in constant time.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,230 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"{{ module_name }}/internal/logger"
+)
+
+// newTestDispatcher returns a dispatcher retrying without noticeable backoff,
+// and its store
+func newTestDispatcher(opts Options) (*Dispatcher, *MemoryStore) {
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = time.Millisecond
+	}
+	store := NewMemoryStore(0)
+	log := logger.NewLogger("info", func(l *logrus.Logger) { l.SetOutput(io.Discard) })
+	return NewDispatcher(opts, store, log), store
+}
+
+var testEvent = Event{ID: "evt-1", Type: "user.created", Payload: map[string]string{"id": "u1"}}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDeliverSigned(t *testing.T) {
+	const secret = "s3cret"
+
+	var verified atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		want := Sign(secret, r.Header.Get(HeaderTimestamp), body)
+		if hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(want)) &&
+			r.Header.Get(HeaderEventID) == testEvent.ID && r.Header.Get(HeaderEventType) == testEvent.Type {
+			verified.Store(true)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	d, _ := newTestDispatcher(Options{})
+	d.Register(Endpoint{ID: "receiver", URL: server.URL, Secret: secret})
+
+	if err := d.Deliver(context.Background(), "receiver", testEvent); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	if !verified.Load() {
+		t.Error("receiver couldn't verify the signature")
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	signature := Sign("s3cret", "1700000000", body)
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("signature = %q, want the sha256= prefix", signature)
+	}
+	if Sign("s3cret", "1700000000", body) != signature {
+		t.Error("signature isn't deterministic")
+	}
+	for name, other := range map[string]string{
+		"other secret":    Sign("other", "1700000000", body),
+		"other timestamp": Sign("s3cret", "1700000001", body),
+		"other body":      Sign("s3cret", "1700000000", []byte(`{"id":"evt-2"}`)),
+	} {
+		if other == signature {
+			t.Errorf("%s gives the same signature", name)
+		}
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+		wantDead     bool
+	}{
+		{"success", []int{http.StatusOK}, 1, false},
+		{"recovers after server errors", []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}, 3, false},
+		{"retries rate limiting", []int{http.StatusTooManyRequests, http.StatusOK}, 2, false},
+		{"dead-lettered after max attempts", []int{http.StatusInternalServerError}, 3, true},
+		{"no retry on client errors", []int{http.StatusBadRequest}, 1, true},
+		{"no retry on gone", []int{http.StatusGone}, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Respond with each status in turn, repeating the last
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(calls.Add(1)) - 1
+				w.WriteHeader(tt.statuses[min(n, len(tt.statuses)-1)])
+			}))
+			defer server.Close()
+
+			d, store := newTestDispatcher(Options{MaxAttempts: 3})
+			d.Register(Endpoint{ID: "receiver", URL: server.URL, Secret: "s"})
+
+			err := d.Deliver(context.Background(), "receiver", testEvent)
+			if gotErr := err != nil; gotErr != tt.wantDead {
+				t.Errorf("err = %v, want error %t", err, tt.wantDead)
+			}
+			if got := int(calls.Load()); got != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", got, tt.wantAttempts)
+			}
+			if got := len(store.Attempts()); got != tt.wantAttempts {
+				t.Errorf("recorded attempts = %d, want %d", got, tt.wantAttempts)
+			}
+
+			dead := store.DeadLetters()
+			if !tt.wantDead {
+				if len(dead) != 0 {
+					t.Errorf("dead letters = %+v, want none", dead)
+				}
+				return
+			}
+			if len(dead) != 1 || dead[0].Attempts != tt.wantAttempts || dead[0].Event.ID != testEvent.ID {
+				t.Errorf("dead letters = %+v, want the event after %d attempts", dead, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestDeliverUnknownEndpoint(t *testing.T) {
+	d, _ := newTestDispatcher(Options{})
+	if err := d.Deliver(context.Background(), "missing", testEvent); !errors.Is(err, ErrUnknownEndpoint) {
+		t.Errorf("err = %v, want ErrUnknownEndpoint", err)
+	}
+}
+
+func TestDeliverConcurrencyCap(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+	}))
+	defer server.Close()
+
+	d, _ := newTestDispatcher(Options{MaxConcurrency: 10})
+	d.Register(Endpoint{ID: "receiver", URL: server.URL, Secret: "s", MaxConcurrency: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.Deliver(context.Background(), "receiver", testEvent); err != nil {
+				t.Errorf("Deliver: %v", err)
+			}
+		}()
+	}
+
+	waitFor(t, "two deliveries in flight", func() bool { return inFlight.Load() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+}
+
+func TestClose(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	// Long enough that Close would time out waiting for the retry
+	d, store := newTestDispatcher(Options{InitialBackoff: time.Hour})
+	d.Register(Endpoint{ID: "receiver", URL: server.URL, Secret: "s"})
+
+	if err := d.Dispatch(context.Background(), testEvent); err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	waitFor(t, "the first attempt", func() bool { return len(store.Attempts()) == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dead := store.DeadLetters()
+	if len(dead) != 1 || !strings.Contains(dead[0].LastError, ErrClosed.Error()) {
+		t.Errorf("dead letters = %+v, want the event cut short by Close", dead)
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("attempts = %d, want no retry after Close", got)
+	}
+
+	if err := d.Dispatch(context.Background(), testEvent); !errors.Is(err, ErrClosed) {
+		t.Errorf("Dispatch after Close = %v, want ErrClosed", err)
+	}
+	if err := d.Close(ctx); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}